@@ -0,0 +1,85 @@
+// Command faketmdb runs a fake TMDB API server for offline development and testing.
+//
+// Point tmdb-mcp at it with:
+//
+//	TMDB_BASE_URL=http://127.0.0.1:8787/3 TMDB_IMAGE_BASE_URL=http://127.0.0.1:8787/t/p/ TMDB_API_KEY=fake tmdb-mcp
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/XDwanj/tmdb-mcp/internal/faketmdb"
+)
+
+// ruleFlags collects repeated -fail flags
+type ruleFlags []faketmdb.Rule
+
+func (f *ruleFlags) String() string {
+	specs := make([]string, len(*f))
+	for i, r := range *f {
+		specs[i] = r.String()
+	}
+	return strings.Join(specs, " ")
+}
+
+func (f *ruleFlags) Set(spec string) error {
+	r, err := faketmdb.ParseRule(spec)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, r)
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8787", "Listen address")
+	apiKey := flag.String("api-key", "", "Require this api_key or Bearer token (empty accepts any key)")
+	quiet := flag.Bool("quiet", false, "Do not log requests")
+	var rules ruleFlags
+	flag.Var(&rules, "fail", "Failure rule PATH=STATUS[,retry_after=N][,delay=D][,times=N], e.g. '/movie/*=429,retry_after=2,times=1' (repeatable)")
+	flag.Parse()
+
+	server := faketmdb.New(*apiKey)
+	server.Script(rules...)
+
+	var handler http.Handler = server
+	if !*quiet {
+		handler = logRequests(server)
+	}
+
+	log.Printf("faketmdb listening on http://%s (base URL http://%s/3)", *addr, *addr)
+	for _, r := range rules {
+		log.Printf("rule: %s", r)
+	}
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Fatal(httpServer.ListenAndServe())
+}
+
+// statusRecorder captures the response status for request logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs method, path, status and duration of every request
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/XDwanj/tmdb-mcp/internal/cache"
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// cacheUsage describes the cache subcommands
+const cacheUsage = `Usage: tmdb-mcp cache <command> [flags]
+
+Commands:
+  inspect [--keys]     Show disk cache location, entry count, size and age
+  purge [--expired]    Remove all entries (or only expired ones)
+
+Flags:
+  --path DIR           Cache directory (default: tmdb.cache.disk.path from configuration)
+`
+
+// runCacheCommand implements the "tmdb-mcp cache" subcommands and returns the exit code
+func runCacheCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, cacheUsage)
+		return 2
+	}
+
+	command := args[0]
+	fs := flag.NewFlagSet("cache "+command, flag.ContinueOnError)
+	path := fs.String("path", "", "Cache directory (overrides tmdb.cache.disk.path)")
+	showKeys := fs.Bool("keys", false, "List cached keys (inspect)")
+	expiredOnly := fs.Bool("expired", false, "Only remove expired entries (purge)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	dir := *path
+	if dir == "" {
+		// 只读取配置：不生成 SSE token、不写配置文件，也不要求 TMDB 凭据
+		cfg, err := config.Read()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+			return 1
+		}
+		dir = cfg.TMDB.Cache.Disk.Path
+	}
+
+	// max size 为 0：CLI 不触发淘汰
+	store, err := cache.OpenDiskStore(dir, 0, diskCacheCLITimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening cache (is a server using it?): %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	switch command {
+	case "inspect":
+		return inspectCache(store, *showKeys)
+	case "purge":
+		removed, err := store.Purge(*expiredOnly)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error purging cache: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Removed %d entries from %s\n", removed, store.Path())
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown cache command: %s\n\n%s", command, cacheUsage)
+		return 2
+	}
+}
+
+// diskCacheCLITimeout bounds the wait for the cache file lock held by a running server
+const diskCacheCLITimeout = 2 * time.Second
+
+// inspectCache prints a summary of the disk cache
+func inspectCache(store *cache.DiskStore, showKeys bool) int {
+	stats, err := store.Stats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading cache: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Path:    %s\n", stats.Path)
+	fmt.Printf("Entries: %d (%d expired)\n", stats.Entries, stats.Expired)
+	fmt.Printf("Size:    %.1f KB\n", float64(stats.Size)/1024)
+	if stats.Entries > 0 {
+		fmt.Printf("Oldest:  %s\n", stats.Oldest.Format(time.RFC3339))
+		fmt.Printf("Newest:  %s\n", stats.Newest.Format(time.RFC3339))
+	}
+
+	if showKeys {
+		keys, err := store.Keys()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing keys: %v\n", err)
+			return 1
+		}
+		for _, key := range keys {
+			fmt.Println(key)
+		}
+	}
+	return 0
+}
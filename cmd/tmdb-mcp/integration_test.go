@@ -61,7 +61,7 @@ func setupTestEnvironment(t *testing.T) *testEnvironment {
 	tmdbClient := tmdb.NewClient(cfg.TMDB, logger)
 
 	// Create MCP Server
-	mcpServer := mcp.NewServer(tmdbClient, logger)
+	mcpServer := mcp.NewServer(tmdbClient, &cfg, logger)
 
 	return &testEnvironment{
 		config:     cfg,
@@ -447,7 +447,7 @@ func BenchmarkSearchTool(b *testing.B) {
 
 	logger := zaptest.NewLogger(b)
 	tmdbClient := tmdb.NewClient(cfg.TMDB, logger)
-	mcpServer := mcp.NewServer(tmdbClient, logger)
+	mcpServer := mcp.NewServer(tmdbClient, &cfg, logger)
 
 	clientTransport, serverTransport := mcpsdk.NewInMemoryTransports()
 
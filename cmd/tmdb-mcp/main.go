@@ -2,54 +2,110 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 
+	"github.com/XDwanj/tmdb-mcp/internal/cassette"
 	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/lifecycle"
 	"github.com/XDwanj/tmdb-mcp/internal/logger"
 	"github.com/XDwanj/tmdb-mcp/internal/mcp"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
 	"github.com/XDwanj/tmdb-mcp/internal/server/middleware"
+	"github.com/XDwanj/tmdb-mcp/internal/server/oauth"
+	"github.com/XDwanj/tmdb-mcp/internal/server/tlsconfig"
+	"github.com/XDwanj/tmdb-mcp/internal/server/tokens"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/XDwanj/tmdb-mcp/internal/tracing"
 	"github.com/XDwanj/tmdb-mcp/pkg/version"
 )
 
+// 退出码
+const (
+	exitOK           = 0
+	exitError        = 1 // 启动失败或服务运行出错
+	exitUsage        = 2 // 命令行参数错误
+	exitDrainTimeout = 3 // 关闭超时，进行中的调用被中止
+)
+
 // healthHandler returns server health status
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"status":"ok"}`))
+// During shutdown it answers 503 so that load balancers stop routing new sessions here
+func healthHandler(lc *lifecycle.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if lc.Draining() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"draining"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}
 }
 
 func main() {
+	// 子命令：tmdb-mcp cache inspect|purge
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		os.Exit(runCacheCommand(os.Args[2:]))
+	}
+	// 子命令：tmdb-mcp token new|hash
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		os.Exit(runTokenCommand(os.Args[2:]))
+	}
+
 	// 命令行参数（作为最高优先级）
 	tmdbAPIKey := flag.String("tmdb-api-key", "", "TMDB API Key (overrides TMDB_API_KEY env)")
+	tmdbAccessToken := flag.String("tmdb-access-token", "", "TMDB v4 API Read Access Token (overrides TMDB_ACCESS_TOKEN env)")
 	tmdbLang := flag.String("tmdb-language", "", "TMDB API language, e.g., en-US (overrides TMDB_LANGUAGE env)")
 	tmdbRate := flag.Int("tmdb-rate-limit", 0, "TMDB rate limit per 10s (overrides TMDB_RATE_LIMIT env)")
+	tmdbBaseURL := flag.String("tmdb-base-url", "", "TMDB API base URL, e.g. a faketmdb server (overrides TMDB_BASE_URL env)")
 
 	serverMode := flag.String("server-mode", "", "Server mode: stdio|sse|both (overrides SERVER_MODE env)")
 	sseHost := flag.String("sse-host", "", "SSE host (overrides SERVER_SSE_HOST env)")
 	ssePort := flag.Int("sse-port", 0, "SSE port (overrides SERVER_SSE_PORT env)")
 	sseToken := flag.String("sse-token", "", "SSE bearer token (overrides SSE_TOKEN env)")
 
+	tmdbRecord := flag.String("tmdb-record", "", "Record TMDB responses (api_key stripped) into cassette files in DIR")
+	tmdbReplay := flag.String("tmdb-replay", "", "Serve TMDB responses from cassette files in DIR without network access")
+
 	logLevel := flag.String("logging-level", "", "Logging level: debug|info|warn|error (overrides LOGGING_LEVEL env)")
 
 	flag.Parse()
 
+	if *tmdbRecord != "" && *tmdbReplay != "" {
+		fmt.Fprintln(os.Stderr, "Error: --tmdb-record and --tmdb-replay cannot be used together")
+		os.Exit(exitUsage)
+	}
+	// 回放模式不访问 TMDB，无需真实的 API Key
+	if *tmdbReplay != "" && *tmdbAPIKey == "" && *tmdbAccessToken == "" &&
+		os.Getenv("TMDB_API_KEY") == "" && os.Getenv("TMDB_ACCESS_TOKEN") == "" {
+		os.Setenv("TMDB_API_KEY", "replay")
+	}
+
 	// 将提供的 flags 映射为环境变量，确保优先级：CLI > ENV > 文件
 	if *tmdbAPIKey != "" {
 		os.Setenv("TMDB_API_KEY", *tmdbAPIKey)
 	}
+	if *tmdbAccessToken != "" {
+		os.Setenv("TMDB_ACCESS_TOKEN", *tmdbAccessToken)
+	}
 	if *tmdbLang != "" {
 		os.Setenv("TMDB_LANGUAGE", *tmdbLang)
 	}
 	if *tmdbRate > 0 {
 		os.Setenv("TMDB_RATE_LIMIT", fmt.Sprintf("%d", *tmdbRate))
 	}
+	if *tmdbBaseURL != "" {
+		os.Setenv("TMDB_BASE_URL", *tmdbBaseURL)
+	}
 	if *serverMode != "" {
 		os.Setenv("SERVER_MODE", *serverMode)
 	}
@@ -85,7 +141,14 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
-	defer log.Sync()
+
+	// 生命周期管理：SIGINT/SIGTERM 时停止接收新调用，等待进行中的调用完成后按注册的相反顺序关闭
+	lc := lifecycle.NewManager(cfg.Server.ShutdownTimeout, log)
+	lc.HandleSignals()
+	lc.OnShutdown("flush logs", func(context.Context) error {
+		log.Sync() // stderr 不支持 sync 时会返回错误，忽略
+		return nil
+	})
 
 	// 记录程序启动
 	log.Info("TMDB MCP Service starting",
@@ -100,6 +163,9 @@ func main() {
 		zap.String("logging_level", cfg.Logging.Level),
 	)
 
+	// 具名访问令牌（含 server.sse.token 对应的 "default"），日志只记录名称
+	tokenStore := tokens.NewStore(cfg.Server.SSE, log)
+
 	// 如果 SSE 模式启用，显示 Token 信息
 	if cfg.Server.Mode == "sse" || cfg.Server.Mode == "both" {
 		if cfg.TokenGenerated {
@@ -108,23 +174,65 @@ func main() {
 				zap.String("token", cfg.Server.SSE.Token),
 				zap.String("config_file", "~/.tmdb-mcp/config.yaml"),
 			)
-		} else {
-			// Token 是从配置或环境变量加载的，仅显示前 8 个字符
-			tokenPrefix := cfg.Server.SSE.Token
-			if len(tokenPrefix) > 8 {
-				tokenPrefix = tokenPrefix[:8] + "..."
-			}
-			log.Info("SSE Token loaded from configuration",
-				zap.String("token_prefix", tokenPrefix),
-			)
+		}
+		if oauthCfg := cfg.Server.SSE.OAuth; !oauthCfg.Enabled || oauthCfg.AllowStaticTokens {
+			log.Info("Access tokens loaded", zap.Strings("tokens", tokenStore.Names()))
 		}
 	}
 
+	// 初始化 OpenTelemetry tracing（未启用时为 no-op）
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, version.Version)
+	if err != nil {
+		log.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	// 退出前导出尚未发送的 span
+	lc.OnShutdown("flush traces", shutdownTracing)
+	if cfg.Tracing.Enabled {
+		log.Info("OpenTelemetry tracing enabled",
+			zap.String("protocol", cfg.Tracing.Protocol),
+			zap.String("endpoint", cfg.Tracing.Endpoint),
+			zap.Float64("sample_ratio", cfg.Tracing.SampleRatio),
+		)
+	}
+
 	// 创建 TMDB Client
 	tmdbClient := tmdb.NewClient(cfg.TMDB, log)
-	log.Info("TMDB Client created")
+	lc.OnShutdown("close TMDB client", func(context.Context) error {
+		// key 池的使用统计
+		for _, key := range tmdbClient.KeyUsage() {
+			log.Info("TMDB API key usage",
+				zap.String("api_key", key.Name),
+				zap.Uint64("requests", key.Requests),
+				zap.Uint64("rejected", key.Rejected),
+				zap.Uint64("throttled", key.Throttled),
+				zap.Bool("healthy", key.Healthy),
+			)
+		}
+		return tmdbClient.Close()
+	})
+	if usage := tmdbClient.KeyUsage(); usage != nil {
+		names := make([]string, 0, len(usage))
+		for _, key := range usage {
+			names = append(names, key.Name)
+		}
+		log.Info("TMDB Client created",
+			zap.Strings("api_keys", names),
+			zap.String("key_selection", cfg.TMDB.KeySelection),
+		)
+	} else {
+		credentialKind, credentialFingerprint := tmdbClient.Credentials()
+		log.Info("TMDB Client created",
+			zap.String("credentials", credentialKind),
+			zap.String("fingerprint", credentialFingerprint),
+		)
+	}
+
+	// 录制/回放模式：替换 TMDB 请求的 transport
+	if err := configureCassette(tmdbClient, *tmdbRecord, *tmdbReplay, log); err != nil {
+		log.Fatal("Failed to configure TMDB record/replay", zap.Error(err))
+	}
 
-	// 启动时性能基准测试: 验证 TMDB API Key 有效性并记录响应时间
+	// 启动时性能基准测试: 验证 TMDB 凭据有效性并记录响应时间
 	log.Info("Running TMDB API baseline check...")
 	ctx := context.Background()
 	baselineStart := time.Now()
@@ -144,61 +252,228 @@ func main() {
 	)
 
 	// 创建 MCP Server
-	mcpServer := mcp.NewServer(tmdbClient, log)
+	mcpServer := mcp.NewServer(tmdbClient, cfg, log)
+	mcpServer.TrackCalls(lc)
+
+	// 收到 SIGHUP 时重新加载配置
+	go watchConfigReload(mcpServer, tokenStore, log)
 
 	// 根据配置模式启动服务
 	switch cfg.Server.Mode {
 	case "stdio":
-		RunStdioModeServer(ctx, mcpServer, log)
+		StartStdioServer(mcpServer, lc, true, log)
 	case "sse":
-		RunSSEModeServer(ctx, mcpServer, cfg, log)
+		StartHTTPServer(mcpServer, tokenStore, cfg, lc, log)
 	case "both":
-		RunBothModeServer(ctx, mcpServer, cfg, log)
+		// 同时运行 stdio 和 SSE 模式
+		log.Info("Starting MCP server in both stdio and SSE modes")
+		StartHTTPServer(mcpServer, tokenStore, cfg, lc, log)
+		StartStdioServer(mcpServer, lc, cfg.Server.StopOnStdioClose, log)
 	default:
 		log.Fatal("Invalid server mode", zap.String("mode", cfg.Server.Mode))
 	}
+
+	os.Exit(exitCode(lc.Wait(), log))
 }
 
-func RunBothModeServer(ctx context.Context, mcpServer *mcp.Server, cfg *config.Config, log *zap.Logger) {
-	// 同时运行 stdio 和 SSE 模式
-	log.Info("Starting MCP server in both stdio and SSE modes")
-	go func() {
-		RunSSEModeServer(ctx, mcpServer, cfg, log)
-	}()
-	RunStdioModeServer(ctx, mcpServer, log)
+// exitCode maps the result of the lifecycle manager to the process exit code
+func exitCode(err error, log *zap.Logger) int {
+	switch {
+	case err == nil:
+		log.Info("Shutdown complete")
+		return exitOK
+	case errors.Is(err, lifecycle.ErrDrainTimeout):
+		log.Warn("Shutdown complete with aborted calls", zap.Error(err))
+		return exitDrainTimeout
+	default:
+		log.Error("Server stopped with error", zap.Error(err))
+		return exitError
+	}
 }
 
-func RunStdioModeServer(ctx context.Context, mcpServer *mcp.Server, log *zap.Logger) {
+// watchConfigReload reloads the configuration on SIGHUP and applies the settings
+// that can change at runtime: tool enablement, the logging level and access tokens
+func watchConfigReload(mcpServer *mcp.Server, tokenStore *tokens.Store, log *zap.Logger) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	for range sigCh {
+		log.Info("Reloading configuration")
+
+		cfg, err := config.Load()
+		if err != nil {
+			log.Error("Failed to reload configuration", zap.Error(err))
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			log.Error("Invalid configuration, keeping current settings", zap.Error(err))
+			continue
+		}
+
+		mcpServer.ApplyToolsConfig(cfg.Tools)
+		tokenStore.Update(cfg.Server.SSE)
+		if err := logger.SetLevel(cfg.Logging.Level); err != nil {
+			log.Error("Failed to apply logging level", zap.Error(err))
+		}
+
+		log.Info("Configuration reloaded",
+			zap.String("logging_level", cfg.Logging.Level),
+			zap.Strings("tools_enabled", cfg.Tools.Enabled),
+			zap.Strings("tools_disabled", cfg.Tools.Disabled),
+			zap.Strings("tokens", tokenStore.Names()),
+		)
+	}
+}
+
+// configureCassette switches the TMDB client to record or replay mode
+func configureCassette(client *tmdb.Client, recordDir, replayDir string, log *zap.Logger) error {
+	switch {
+	case recordDir != "":
+		recorder, err := cassette.NewRecorder(recordDir, http.DefaultTransport)
+		if err != nil {
+			return err
+		}
+		client.SetTransport(recorder)
+		log.Info("Recording TMDB responses", zap.String("dir", recordDir))
+	case replayDir != "":
+		player, err := cassette.NewPlayer(replayDir)
+		if err != nil {
+			return err
+		}
+		client.SetTransport(player)
+		log.Info("Replaying TMDB responses, network access disabled", zap.String("dir", replayDir))
+	}
+	return nil
+}
+
+// StartStdioServer serves MCP over stdin/stdout in the background
+// When stdin is closed the process shuts down if stopOnClose is set; otherwise
+// (both mode by default) the HTTP server keeps running until a signal arrives
+func StartStdioServer(mcpServer *mcp.Server, lc *lifecycle.Manager, stopOnClose bool, log *zap.Logger) {
 	// stdio 模式：通过标准输入输出通信
 	log.Info("Starting MCP server in stdio mode")
-	transport := &mcpsdk.StdioTransport{}
-	if err := mcpServer.Run(ctx, transport); err != nil {
-		log.Fatal("MCP server failed", zap.Error(err))
-	}
+
+	// 会话在进行中的调用完成后才关闭，因此不直接使用关闭信号作为 context
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.OnShutdown("close stdio session", func(context.Context) error {
+		cancel()
+		return nil
+	})
+
+	go func() {
+		err := mcpServer.Run(ctx, &mcpsdk.StdioTransport{})
+		switch {
+		case lc.Draining():
+			// 关闭流程中结束
+		case err != nil:
+			lc.Fail("stdio transport failed", err)
+		case stopOnClose:
+			lc.Shutdown("stdin closed")
+		default:
+			log.Info("stdin closed, HTTP server keeps running (set server.stop_on_stdio_close to exit instead)")
+		}
+	}()
 }
 
-func RunSSEModeServer(ctx context.Context, mcpServer *mcp.Server, cfg *config.Config, log *zap.Logger) {
+// StartHTTPServer serves MCP over SSE and Streamable HTTP in the background
+// On shutdown, open sessions are closed (ending their streams) before the
+// HTTP server stops, so that clients see a clean end of stream
+func StartHTTPServer(mcpServer *mcp.Server, tokenStore *tokens.Store, cfg *config.Config, lc *lifecycle.Manager, log *zap.Logger) {
 	// SSE 模式：通过 HTTP SSE 通信
 	log.Info("Starting MCP server in SSE mode")
 
+	// 认证：静态 bearer token，或 OAuth 2.1 访问令牌（可同时接受静态 token）
+	authenticate := func(next http.Handler) http.Handler {
+		return middleware.AuthMiddlewareWithLogger(log, tokenStore, next)
+	}
+	mux := http.NewServeMux()
+	if oauthCfg := cfg.Server.SSE.OAuth; oauthCfg.Enabled {
+		verifier, err := oauth.NewVerifier(oauthCfg, log)
+		if err != nil {
+			log.Fatal("Failed to configure OAuth", zap.Error(err))
+		}
+		var static *tokens.Store
+		if oauthCfg.AllowStaticTokens {
+			static = tokenStore
+		}
+		authenticate = func(next http.Handler) http.Handler {
+			return middleware.OAuthMiddleware(log, verifier, static, next)
+		}
+		// Protected resource metadata（RFC 9728），无需认证
+		mux.Handle(oauth.MetadataPath, verifier.MetadataHandler())
+		mux.Handle(oauth.MetadataPath+"/", verifier.MetadataHandler())
+		log.Info("OAuth protected resource enabled",
+			zap.String("resource", oauthCfg.Resource),
+			zap.Strings("authorization_servers", oauthCfg.AuthorizationServers),
+			zap.String("metadata_url", verifier.MetadataURL()),
+			zap.Bool("allow_static_tokens", oauthCfg.AllowStaticTokens),
+		)
+	}
+
+	// 客户端自带的 TMDB 凭据（认证之后检查）；会话的 TMDB 调用使用创建会话的请求携带的凭据
+	clientKeys := func(next http.Handler) http.Handler {
+		return middleware.ClientKeyMiddleware(log, cfg.TMDB.ClientKeys, next)
+	}
+	if cfg.TMDB.ClientKeys.Enabled() {
+		log.Info("Client-supplied TMDB keys enabled",
+			zap.String("mode", cfg.TMDB.ClientKeys.Mode),
+			zap.String("header", cfg.TMDB.ClientKeys.Header),
+		)
+	}
+
 	// 设置 SSE 处理器（仅 GET）
-	sseHandler := mcpServer.GetSSEHandler()
-	sseHandler = middleware.AuthMiddlewareWithLogger(log, cfg.Server.SSE.Token, sseHandler)
+	sseHandler := authenticate(clientKeys(mcpServer.GetSSEHandler()))
 
 	// 设置 Streamable 处理器（GET/POST/DELETE）
-	streamHandler := mcpServer.GetStreamableHandler()
-	streamHandler = middleware.AuthMiddlewareWithLogger(log, cfg.Server.SSE.Token, streamHandler)
+	streamHandler := authenticate(clientKeys(mcpServer.GetStreamableHandler()))
+
+	// Prometheus 指标：包含 key 名称、工具使用情况和会话数，与 MCP 端点一样需要认证
+	metricsHandler := authenticate(metrics.Handler())
+
+	// 运行时日志级别（GET 查询 / PUT {"level":"debug"} 修改）；影响整个进程，仅限管理令牌
+	levelHandler := authenticate(middleware.AdminMiddleware(log, logger.LevelHandler()))
 
 	// 设置路由
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/health", healthHandler(lc))
+	mux.Handle("/metrics", metricsHandler)
 	mux.Handle("/mcp/sse", sseHandler)
 	mux.Handle("/mcp/stream", streamHandler)
+	mux.Handle("/logging/level", levelHandler)
+
+	// 客户端证书身份（mTLS）用于日志和认证
+	tlsCfg := cfg.Server.SSE.TLS
+	handler := middleware.ClientCertMiddleware(log, tlsCfg.AllowedClients, tlsCfg.TrustClientCert, mux)
 
-	// 启动服务器
 	addr := fmt.Sprintf("%s:%d", cfg.Server.SSE.Host, cfg.Server.SSE.Port)
-	log.Info("Starting HTTP server", zap.String("addr", addr))
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatal("HTTP server failed", zap.Error(err))
+	httpServer := &http.Server{Addr: addr, Handler: handler}
+	if tlsCfg.Enabled {
+		reloader, err := tlsconfig.New(tlsCfg, log)
+		if err != nil {
+			log.Fatal("Failed to configure TLS", zap.Error(err))
+		}
+		httpServer.TLSConfig = reloader.TLSConfig()
 	}
+	lc.OnShutdown("stop HTTP server", func(ctx context.Context) error {
+		mcpServer.CloseSessions()
+		return httpServer.Shutdown(ctx)
+	})
+
+	// 启动服务器
+	go func() {
+		log.Info("Starting HTTP server",
+			zap.String("addr", addr),
+			zap.Bool("tls", tlsCfg.Enabled),
+			zap.Bool("mtls", tlsCfg.Enabled && tlsCfg.ClientCAFile != ""),
+		)
+		var err error
+		if tlsCfg.Enabled {
+			// 证书由 TLSConfig 提供（支持热加载）
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			lc.Fail("HTTP server failed", err)
+		}
+	}()
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// tokenUsage describes the token subcommands
+const tokenUsage = `Usage: tmdb-mcp token <command>
+
+Commands:
+  new NAME     Generate a token and print it with the server.sse.tokens entry to add
+  hash         Read a token from stdin and print its hash
+`
+
+// runTokenCommand implements the "tmdb-mcp token" subcommands and returns the exit code
+func runTokenCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, tokenUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "new":
+		if len(args) != 2 || args[1] == "" {
+			fmt.Fprint(os.Stderr, tokenUsage)
+			return 2
+		}
+		token, err := config.GenerateSSEToken()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating token: %v\n", err)
+			return 1
+		}
+		// 明文仅输出这一次，配置文件中只保存哈希
+		fmt.Printf("Token (give this to the client, it is not stored anywhere):\n\n  %s\n\n", token)
+		fmt.Printf("Add to server.sse.tokens in config.yaml, then send SIGHUP or restart:\n\n")
+		fmt.Printf("    - name: %s\n      hash: %s\n", args[1], config.HashToken(token))
+		return 0
+	case "hash":
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		token := strings.TrimSpace(line)
+		if token == "" {
+			fmt.Fprintf(os.Stderr, "Error reading token from stdin: %v\n", err)
+			return 1
+		}
+		fmt.Println(config.HashToken(token))
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown token command: %s\n\n%s", args[0], tokenUsage)
+		return 2
+	}
+}
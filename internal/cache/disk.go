@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// diskFileName is the database file name inside the cache directory
+const diskFileName = "cache.db"
+
+// evictLowWater is the fraction of maxSize eviction shrinks the store to, so that
+// the next writes fit without another scan of the bucket
+const evictLowWater = 0.9
+
+// entriesBucket is the bbolt bucket holding cache records
+var entriesBucket = []byte("entries")
+
+// Record is a cached TMDB response stored on disk
+// ETag and LastModified allow stale records to be revalidated with a conditional request
+type Record struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Fresh reports whether the record has not expired at now
+func (r *Record) Fresh(now time.Time) bool {
+	return now.Before(r.ExpiresAt)
+}
+
+// Revalidatable reports whether the record carries validators for a conditional request
+func (r *Record) Revalidatable() bool {
+	return r.ETag != "" || r.LastModified != ""
+}
+
+// Stats summarizes the contents of a disk store
+type Stats struct {
+	Path    string
+	Entries int
+	Expired int
+	Size    int64 // 记录编码后的总字节数
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// DiskStore is a persistent cache backed by an embedded bbolt database
+// When the total size exceeds maxSize, expired records are evicted first,
+// then the oldest ones, down to evictLowWater of maxSize
+type DiskStore struct {
+	db      *bolt.DB
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex // 保护 size
+	size int64
+
+	now func() time.Time // 便于测试替换时间
+}
+
+// OpenDiskStore opens (or creates) the disk cache in dir
+// maxSize is the size limit in bytes; 0 means unlimited
+// Only one process can open the store at a time; timeout bounds the wait for the file lock
+func OpenDiskStore(dir string, maxSize int64, timeout time.Duration) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	path := filepath.Join(dir, diskFileName)
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database %s: %w", path, err)
+	}
+
+	s := &DiskStore{db: db, path: path, maxSize: maxSize, now: time.Now}
+
+	// 启动时统计已有记录大小，用于容量限制
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(entriesBucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			s.size += int64(len(k) + len(v))
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize cache database: %w", err)
+	}
+
+	return s, nil
+}
+
+// Path returns the database file path
+func (s *DiskStore) Path() string {
+	return s.path
+}
+
+// Close closes the underlying database
+func (s *DiskStore) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the record for key, fresh or stale
+func (s *DiskStore) Get(key string) (*Record, bool) {
+	var record *Record
+	s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(entriesBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		var r Record
+		if err := json.Unmarshal(data, &r); err == nil {
+			record = &r
+		}
+		return nil
+	})
+	return record, record != nil
+}
+
+// Set stores a record for key and evicts records if the size limit is exceeded
+// The size accounting is updated only once the transaction has committed
+func (s *DiskStore) Set(key string, record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var size int64
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		size = s.size
+		b := tx.Bucket(entriesBucket)
+		if old := b.Get([]byte(key)); old != nil {
+			size -= int64(len(key) + len(old))
+		}
+		if err := b.Put([]byte(key), data); err != nil {
+			return err
+		}
+		size += int64(len(key) + len(data))
+
+		if s.maxSize > 0 && size > s.maxSize {
+			var err error
+			size, err = s.evict(b, key, size)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.size = size
+	return nil
+}
+
+// evict removes records until size is at most evictLowWater of maxSize and returns
+// the remaining size; keep is never evicted. Evicting below maxSize leaves room for
+// later writes, so the bucket is scanned once per batch rather than on every Set
+// Callers must hold s.mu inside an update transaction
+func (s *DiskStore) evict(b *bolt.Bucket, keep string, size int64) (int64, error) {
+	type candidate struct {
+		key      string
+		size     int64
+		expired  bool
+		storedAt time.Time
+	}
+
+	target := int64(float64(s.maxSize) * evictLowWater)
+	now := s.now()
+	var candidates []candidate
+	err := b.ForEach(func(k, v []byte) error {
+		if string(k) == keep {
+			return nil
+		}
+		var r Record
+		if err := json.Unmarshal(v, &r); err != nil {
+			// 无法解析的记录优先清理
+			candidates = append(candidates, candidate{key: string(k), size: int64(len(k) + len(v)), expired: true})
+			return nil
+		}
+		candidates = append(candidates, candidate{
+			key:      string(k),
+			size:     int64(len(k) + len(v)),
+			expired:  !r.Fresh(now),
+			storedAt: r.StoredAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return size, err
+	}
+
+	// 过期记录优先，其次按写入时间从旧到新
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].expired != candidates[j].expired {
+			return candidates[i].expired
+		}
+		return candidates[i].storedAt.Before(candidates[j].storedAt)
+	})
+
+	for _, c := range candidates {
+		if size <= target {
+			break
+		}
+		if err := b.Delete([]byte(c.key)); err != nil {
+			return size, err
+		}
+		size -= c.size
+	}
+	return size, nil
+}
+
+// Keys returns all keys in the store, sorted
+func (s *DiskStore) Keys() ([]string, error) {
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(entriesBucket).ForEach(func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// Stats returns a summary of the store contents
+func (s *DiskStore) Stats() (Stats, error) {
+	stats := Stats{Path: s.path}
+	now := s.now()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(entriesBucket).ForEach(func(k, v []byte) error {
+			stats.Entries++
+			stats.Size += int64(len(k) + len(v))
+
+			var r Record
+			if err := json.Unmarshal(v, &r); err != nil {
+				stats.Expired++
+				return nil
+			}
+			if !r.Fresh(now) {
+				stats.Expired++
+			}
+			if stats.Oldest.IsZero() || r.StoredAt.Before(stats.Oldest) {
+				stats.Oldest = r.StoredAt
+			}
+			if r.StoredAt.After(stats.Newest) {
+				stats.Newest = r.StoredAt
+			}
+			return nil
+		})
+	})
+	return stats, err
+}
+
+// Purge removes records and returns how many were removed
+// If expiredOnly is true, only expired records are removed
+func (s *DiskStore) Purge(expiredOnly bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	removed := 0
+	var freed int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		removed, freed = 0, 0
+		b := tx.Bucket(entriesBucket)
+
+		var keys [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if expiredOnly {
+				var r Record
+				if err := json.Unmarshal(v, &r); err == nil && r.Fresh(now) {
+					return nil
+				}
+			}
+			keys = append(keys, append([]byte(nil), k...))
+			freed += int64(len(k) + len(v))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.size -= freed
+	return removed, nil
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTestStore opens a disk store in a temporary directory
+func openTestStore(t *testing.T, maxSize int64) *DiskStore {
+	store, err := OpenDiskStore(t.TempDir(), maxSize, time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testRecord creates a record stored at storedAt that expires after ttl
+func testRecord(body string, storedAt time.Time, ttl time.Duration) *Record {
+	return &Record{Body: []byte(body), StoredAt: storedAt, ExpiresAt: storedAt.Add(ttl)}
+}
+
+// TestDiskStore_GetSet tests basic get/set and persistence across reopen
+func TestDiskStore_GetSet(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenDiskStore(dir, 0, time.Second)
+	require.NoError(t, err)
+
+	_, ok := store.Get("a")
+	assert.False(t, ok)
+
+	now := time.Now()
+	require.NoError(t, store.Set("a", &Record{Body: []byte(`{"id":1}`), ETag: `"x"`, StoredAt: now, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, store.Close())
+
+	store, err = OpenDiskStore(dir, 0, time.Second)
+	require.NoError(t, err)
+	defer store.Close()
+
+	record, ok := store.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, []byte(`{"id":1}`), record.Body)
+	assert.Equal(t, `"x"`, record.ETag)
+	assert.True(t, record.Fresh(now))
+	assert.True(t, record.Revalidatable())
+}
+
+// TestDiskStore_EvictsExpiredThenOldest tests the size bound
+func TestDiskStore_EvictsExpiredThenOldest(t *testing.T) {
+	store := openTestStore(t, 0)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Set("old", testRecord("1", now.Add(-3*time.Hour), 24*time.Hour)))
+	require.NoError(t, store.Set("expired", testRecord("2", now.Add(-time.Hour), time.Minute)))
+	require.NoError(t, store.Set("new", testRecord("3", now.Add(-2*time.Hour), 24*time.Hour)))
+
+	// 容量只够保留两条记录
+	store.maxSize = store.size * 3 / 4
+	require.NoError(t, store.Set("latest", testRecord("4", now, 24*time.Hour)))
+
+	keys, err := store.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"latest", "new"}, keys)
+}
+
+// TestDiskStore_EvictsToLowWater tests that eviction leaves room so later writes do not evict again
+func TestDiskStore_EvictsToLowWater(t *testing.T) {
+	store := openTestStore(t, 0)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, store.Set(fmt.Sprintf("k%02d", i), testRecord("1", now.Add(time.Duration(i)*time.Second), time.Hour)))
+	}
+	store.maxSize = store.size
+	require.NoError(t, store.Set("k20", testRecord("1", now.Add(20*time.Second), time.Hour)))
+	assert.LessOrEqual(t, store.size, int64(float64(store.maxSize)*evictLowWater))
+
+	keys, err := store.Keys()
+	require.NoError(t, err)
+	evicted := 21 - len(keys)
+	assert.Equal(t, "k"+fmt.Sprintf("%02d", evicted), keys[0])
+
+	// 后续写入在容量内，不再淘汰
+	require.NoError(t, store.Set("k21", testRecord("1", now.Add(21*time.Second), time.Hour)))
+	keys, err = store.Keys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 21-evicted+1)
+}
+
+// TestDiskStore_SizeAccounting tests that the tracked size matches the stored records,
+// including after overwrites, evictions and failed writes
+func TestDiskStore_SizeAccounting(t *testing.T) {
+	store := openTestStore(t, 0)
+	now := time.Now()
+
+	require.NoError(t, store.Set("a", testRecord("1", now, time.Hour)))
+	require.NoError(t, store.Set("b", testRecord("22", now, time.Hour)))
+	require.NoError(t, store.Set("a", testRecord("333", now, time.Hour)))
+
+	// 写入失败时事务回滚，大小保持不变
+	size := store.size
+	assert.Error(t, store.Set(strings.Repeat("k", bolt.MaxKeySize+1), testRecord("4", now, time.Hour)))
+	assert.Equal(t, size, store.size)
+
+	store.maxSize = store.size
+	require.NoError(t, store.Set("c", testRecord("5", now, time.Hour)))
+
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, stats.Size, store.size)
+	assert.LessOrEqual(t, store.size, store.maxSize)
+}
+
+// TestDiskStore_Purge tests purging expired and all records
+func TestDiskStore_Purge(t *testing.T) {
+	store := openTestStore(t, 0)
+	now := time.Now()
+
+	require.NoError(t, store.Set("fresh", testRecord("1", now, time.Hour)))
+	require.NoError(t, store.Set("expired", testRecord("2", now.Add(-time.Hour), time.Minute)))
+
+	removed, err := store.Purge(true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, ok := store.Get("expired")
+	assert.False(t, ok)
+
+	removed, err = store.Purge(false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, int64(0), store.size)
+}
+
+// TestDiskStore_Stats tests the inspection summary
+func TestDiskStore_Stats(t *testing.T) {
+	store := openTestStore(t, 0)
+	now := time.Now()
+
+	require.NoError(t, store.Set("a", testRecord("1", now.Add(-time.Hour), time.Minute)))
+	require.NoError(t, store.Set("b", testRecord("2", now, time.Hour)))
+
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, store.Path(), stats.Path)
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, 1, stats.Expired)
+	assert.Equal(t, store.size, stats.Size)
+	assert.True(t, stats.Oldest.Equal(now.Add(-time.Hour)))
+	assert.True(t, stats.Newest.Equal(now))
+}
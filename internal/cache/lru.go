@@ -0,0 +1,148 @@
+// Package cache provides response caching for TMDB API requests.
+// Entries are raw response bodies keyed by endpoint and normalized query
+// parameters, so repeated lookups of popular titles do not consume the
+// TMDB rate limit.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LRU is a size-bounded in-memory cache with per-entry expiry
+// When full, the least recently used entry is evicted
+// It is safe for concurrent use
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+
+	hits   uint64
+	misses uint64
+
+	staleFor time.Duration // 过期条目保留多久以供 GetStale 使用
+
+	now func() time.Time // 便于测试替换时间
+}
+
+// entry is a cached value with its expiry time
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRU creates a new LRU cache holding at most maxEntries entries
+func NewLRU(maxEntries int) *LRU {
+	return &LRU{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+// Get returns the cached value for key if present and not expired
+// Every call counts as a hit or a miss
+func (c *LRU) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if now := c.now(); !now.Before(e.expiresAt) {
+		// 过期条目直接删除（保留期内的除外，见 KeepStale）
+		if !now.Before(e.expiresAt.Add(c.staleFor)) {
+			c.removeElement(elem)
+		}
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+	atomic.AddUint64(&c.hits, 1)
+	return e.value, true
+}
+
+// KeepStale keeps expired entries for d after they expire, so that GetStale can
+// still return them (e.g. while TMDB is unavailable); they remain misses for Get
+func (c *LRU) KeepStale(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.staleFor = d
+}
+
+// GetStale returns the value for key even if it has expired, as long as it is
+// within the KeepStale window, together with its expiry time
+// It does not count as a hit or a miss
+func (c *LRU) GetStale(key string) ([]byte, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	e := elem.Value.(*entry)
+	if !c.now().Before(e.expiresAt.Add(c.staleFor)) {
+		c.removeElement(elem)
+		return nil, time.Time{}, false
+	}
+	return e.value, e.expiresAt, true
+}
+
+// Set stores value under key for the given TTL
+// A non-positive TTL is ignored
+func (c *LRU) Set(key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry)
+		e.value = value
+		e.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Len returns the number of cached entries (including expired ones not yet evicted)
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Hits returns the number of cache hits (thread-safe)
+func (c *LRU) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+// Misses returns the number of cache misses (thread-safe)
+func (c *LRU) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
+// removeElement removes elem from the cache; callers must hold c.mu
+func (c *LRU) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+}
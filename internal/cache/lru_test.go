@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLRU_GetSet tests basic get/set with hit and miss counting
+func TestLRU_GetSet(t *testing.T) {
+	c := NewLRU(10)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+
+	c.Set("a", []byte("1"), time.Minute)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	assert.Equal(t, uint64(1), c.Hits())
+	assert.Equal(t, uint64(1), c.Misses())
+}
+
+// TestLRU_Expiry tests that expired entries are treated as misses and removed
+func TestLRU_Expiry(t *testing.T) {
+	c := NewLRU(10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", []byte("1"), time.Minute)
+	now = now.Add(time.Minute)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+// TestLRU_EvictsLeastRecentlyUsed tests the size bound
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2)
+
+	c.Set("a", []byte("1"), time.Minute)
+	c.Set("b", []byte("2"), time.Minute)
+	c.Get("a") // a 变为最近使用
+	c.Set("c", []byte("3"), time.Minute)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "b should be evicted")
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+// TestLRU_ZeroTTL tests that a zero TTL disables caching
+func TestLRU_ZeroTTL(t *testing.T) {
+	c := NewLRU(10)
+
+	c.Set("a", []byte("1"), 0)
+
+	assert.Equal(t, 0, c.Len())
+}
+
+// TestLRU_KeepStale tests that expired entries stay available to GetStale within the window
+func TestLRU_KeepStale(t *testing.T) {
+	c := NewLRU(10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.KeepStale(time.Hour)
+
+	c.Set("a", []byte("1"), time.Minute)
+	expiresAt := now.Add(time.Minute)
+	now = now.Add(30 * time.Minute)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok, "expired entries are still misses")
+
+	value, expired, ok := c.GetStale("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, expiresAt, expired)
+
+	now = now.Add(time.Hour)
+	_, _, ok = c.GetStale("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
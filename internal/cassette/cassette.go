@@ -0,0 +1,199 @@
+// Package cassette records TMDB HTTP traffic to disk and replays it without network access.
+// Each interaction is stored as one JSON file named after a hash of the request
+// (method, path and query parameters without api_key), so recordings are easy to
+// inspect, diff and attach to bug reports. Credentials are never written.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNoRecording is returned in replay mode for requests missing from the cassette
+var ErrNoRecording = errors.New("cassette: no recorded response")
+
+// sensitiveParams are query parameters stripped from recorded requests
+var sensitiveParams = []string{"api_key"}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// RecordedRequest identifies a request; credentials are removed
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"` // 排序后的查询参数（不含 api_key）
+}
+
+// RecordedResponse is a recorded HTTP response
+type RecordedResponse struct {
+	Status  int             `json:"status"`
+	Headers http.Header     `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"` // JSON 响应体原样保存，便于阅读
+	RawBody string          `json:"raw_body,omitempty"`
+}
+
+// recordedHeaders are the response headers kept in recordings
+var recordedHeaders = []string{"Content-Type", "ETag", "Last-Modified", "Retry-After", "Cache-Control"}
+
+// Recorder is an http.RoundTripper that saves every response to dir
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+// NewRecorder creates a recorder writing to dir (created if missing) and sending requests with next
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{dir: dir, next: next}, nil
+}
+
+// RoundTrip sends the request and records the response
+// 304 responses are not recorded: they depend on a local cache that replay may not have
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusNotModified {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Request:    recordedRequest(req),
+		Response:   RecordedResponse{Status: resp.StatusCode, Headers: http.Header{}},
+		RecordedAt: time.Now().UTC(),
+	}
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			interaction.Response.Headers.Set(name, value)
+		}
+	}
+	if json.Valid(body) {
+		interaction.Response.Body = body
+	} else {
+		interaction.Response.RawBody = string(body)
+	}
+
+	if err := r.save(interaction); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// save writes an interaction atomically
+func (r *Recorder) save(interaction Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	path := filepath.Join(r.dir, fileName(interaction.Request))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Player is an http.RoundTripper that serves recorded responses and never uses the network
+type Player struct {
+	dir string
+}
+
+// NewPlayer creates a player reading recordings from dir
+func NewPlayer(dir string) (*Player, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cassette path %s is not a directory", dir)
+	}
+	return &Player{dir: dir}, nil
+}
+
+// RoundTrip returns the recorded response for req, or an error wrapping ErrNoRecording
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	recorded := recordedRequest(req)
+	data, err := os.ReadFile(filepath.Join(p.dir, fileName(recorded)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for %s %s?%s (record it with --tmdb-record)", ErrNoRecording, recorded.Method, recorded.Path, recorded.Query)
+		}
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var interaction Interaction
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette for %s %s: %w", recorded.Method, recorded.Path, err)
+	}
+
+	body := []byte(interaction.Response.Body)
+	if len(body) == 0 {
+		body = []byte(interaction.Response.RawBody)
+	}
+	header := interaction.Response.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+		StatusCode:    interaction.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// recordedRequest builds the credential-free identity of req
+func recordedRequest(req *http.Request) RecordedRequest {
+	query := req.URL.Query()
+	for _, name := range sensitiveParams {
+		query.Del(name)
+	}
+	return RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  query.Encode(), // Encode 按 key 排序
+	}
+}
+
+// fileName returns the cassette file name for a request
+func fileName(r RecordedRequest) string {
+	sum := sha256.Sum256([]byte(r.Method + " " + r.Path + "?" + r.Query))
+	return hex.EncodeToString(sum[:8]) + ".json"
+}
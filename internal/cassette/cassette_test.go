@@ -0,0 +1,99 @@
+package cassette
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/XDwanj/tmdb-mcp/internal/faketmdb"
+)
+
+// fetch performs a GET with client and returns status and body
+func fetch(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+// TestRecordReplay tests recording responses and replaying them without the server
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(faketmdb.New("secret-key"))
+	dir := t.TempDir()
+
+	recorder, err := NewRecorder(dir, http.DefaultTransport)
+	require.NoError(t, err)
+	recording := &http.Client{Transport: recorder}
+
+	status, body := fetch(t, recording, server.URL+"/3/movie/27205?api_key=secret-key&language=en-US")
+	require.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "Inception")
+	status, _ = fetch(t, recording, server.URL+"/3/movie/1?api_key=secret-key")
+	require.Equal(t, http.StatusNotFound, status)
+	server.Close()
+
+	// 录制文件中不包含 api_key
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "secret-key")
+	}
+
+	player, err := NewPlayer(dir)
+	require.NoError(t, err)
+	replaying := &http.Client{Transport: player}
+
+	// 参数顺序和 api_key 不影响匹配
+	status, replayed := fetch(t, replaying, server.URL+"/3/movie/27205?language=en-US&api_key=other")
+	assert.Equal(t, http.StatusOK, status)
+	var movie map[string]any
+	require.NoError(t, json.Unmarshal([]byte(replayed), &movie))
+	assert.Equal(t, "Inception", movie["title"])
+
+	status, _ = fetch(t, replaying, server.URL+"/3/movie/1")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	_, err = replaying.Get(server.URL + "/3/movie/27205?language=zh-CN")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrNoRecording)
+	assert.Contains(t, err.Error(), "/3/movie/27205?language=zh-CN")
+}
+
+// TestRecorder_SkipsNotModified tests that 304 responses are not recorded
+func TestRecorder_SkipsNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	recorder, err := NewRecorder(dir, nil)
+	require.NoError(t, err)
+	status, _ := fetch(t, &http.Client{Transport: recorder}, server.URL+"/3/movie/27205")
+	assert.Equal(t, http.StatusNotModified, status)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestNewPlayer_MissingDirectory tests that replay fails for a missing cassette directory
+func TestNewPlayer_MissingDirectory(t *testing.T) {
+	_, err := NewPlayer(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "cassette directory"))
+}
@@ -2,17 +2,25 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 )
 
 // Config is the root configuration structure
 type Config struct {
-	TMDB    TMDBConfig   `mapstructure:"tmdb" json:"tmdb"`
-	Server  ServerConfig `mapstructure:"server" json:"server"`
-	Logging LogConfig    `mapstructure:"logging" json:"logging"`
+	TMDB     TMDBConfig     `mapstructure:"tmdb" json:"tmdb"`
+	Server   ServerConfig   `mapstructure:"server" json:"server"`
+	Logging  LogConfig      `mapstructure:"logging" json:"logging"`
+	Response ResponseConfig `mapstructure:"response" json:"response"`
+	Tools    ToolsConfig    `mapstructure:"tools" json:"tools"`
+	Tracing  TracingConfig  `mapstructure:"tracing" json:"tracing"`
 
 	// TokenGenerated indicates if the SSE token was auto-generated
 	// This is not persisted to config file
@@ -24,20 +32,384 @@ type TMDBConfig struct {
 	APIKey    string `mapstructure:"api_key" json:"api_key"`
 	Language  string `mapstructure:"language" json:"language"`
 	RateLimit int    `mapstructure:"rate_limit" json:"rate_limit"`
+
+	// AdaptiveRateLimit 根据 TMDB 的 429 响应和限流响应头调整限流
+	AdaptiveRateLimit AdaptiveRateLimitConfig `mapstructure:"adaptive_rate_limit" json:"adaptive_rate_limit"`
+
+	// Retry 控制失败请求的重试策略和每次调用的总时限
+	Retry RetryConfig `mapstructure:"retry" json:"retry"`
+
+	// CircuitBreaker 在 TMDB 持续故障时快速失败，并在有缓存时返回过期数据
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker" json:"circuit_breaker"`
+
+	// AccessToken 是 TMDB v4 API Read Access Token，通过 Authorization 请求头发送
+	// 与 APIKey 二选一；两个字段都会自动识别凭据类型
+	AccessToken string `mapstructure:"access_token" json:"access_token"`
+
+	// APIKeys 配置多个凭据组成的 key 池，每个 key 有独立的限流配额（与 api_key/access_token 互斥）
+	APIKeys []APIKeyConfig `mapstructure:"api_keys" json:"api_keys"`
+	// KeySelection: round_robin（默认）或 least_loaded（等待和进行中请求最少的 key）
+	KeySelection string `mapstructure:"key_selection" json:"key_selection"`
+
+	// BaseURL 和 ImageBaseURL 可指向代理或本地的 faketmdb 服务
+	BaseURL      string `mapstructure:"base_url" json:"base_url"`             // TMDB API v3 地址
+	ImageBaseURL string `mapstructure:"image_base_url" json:"image_base_url"` // 图片地址前缀（不含尺寸）
+
+	Cache CacheConfig `mapstructure:"cache" json:"cache"`
+
+	// ClientKeys 允许 HTTP 客户端通过请求头使用自己的 TMDB 凭据
+	ClientKeys ClientKeysConfig `mapstructure:"client_keys" json:"client_keys"`
+}
+
+// AdaptiveRateLimitConfig controls feeding TMDB's rate limit feedback back into the limiter
+// A 429 (or, with Headers, an exhausted X-RateLimit-Remaining) pauses every caller
+// of the limiter until Retry-After (or X-RateLimit-Reset), after which the rate
+// ramps up from a quarter of rate_limit over RampUp
+type AdaptiveRateLimitConfig struct {
+	Enabled bool          `mapstructure:"enabled" json:"enabled"`
+	RampUp  time.Duration `mapstructure:"ramp_up" json:"ramp_up"` // 暂停结束后恢复到完整速率所需时间
+	Headers bool          `mapstructure:"headers" json:"headers"` // 读取 X-RateLimit-Remaining/X-RateLimit-Reset 响应头
+}
+
+// RetryConfig controls retries of failed TMDB requests
+// The wait before retry n is BaseDelay doubled n-1 times, capped at MaxDelay and
+// shortened by a random fraction of up to Jitter; a 429 with Retry-After waits
+// exactly that long instead. Timeout bounds a whole call, including rate-limit
+// waits and retries: no retry is started that could not finish within it
+type RetryConfig struct {
+	MaxAttempts   int           `mapstructure:"max_attempts" json:"max_attempts"`     // 每次调用的最大尝试次数（含首次请求），1 表示不重试
+	BaseDelay     time.Duration `mapstructure:"base_delay" json:"base_delay"`         // 首次重试前的等待，之后每次翻倍
+	MaxDelay      time.Duration `mapstructure:"max_delay" json:"max_delay"`           // 单次等待上限
+	Jitter        float64       `mapstructure:"jitter" json:"jitter"`                 // 随机缩短等待的最大比例（0–1），避免调用同时重试
+	Statuses      []int         `mapstructure:"statuses" json:"statuses"`             // 需要重试的 HTTP 状态码
+	NetworkErrors bool          `mapstructure:"network_errors" json:"network_errors"` // 是否重试网络错误（连接失败、超时）
+	Timeout       time.Duration `mapstructure:"timeout" json:"timeout"`               // 每次调用的总时限，0 表示不限制
+}
+
+// validate checks the retry policy; zero values are allowed and mean the defaults
+func (r RetryConfig) validate() error {
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("invalid tmdb.retry.max_attempts: must be at least 1")
+	}
+	if r.BaseDelay < 0 || r.MaxDelay < 0 || r.Timeout < 0 {
+		return fmt.Errorf("invalid tmdb.retry: base_delay, max_delay and timeout must not be negative")
+	}
+	if r.MaxDelay > 0 && r.BaseDelay > r.MaxDelay {
+		return fmt.Errorf("invalid tmdb.retry.base_delay: must not be greater than max_delay")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("invalid tmdb.retry.jitter: must be between 0 and 1")
+	}
+	for _, status := range r.Statuses {
+		if status < 400 || status > 599 {
+			return fmt.Errorf("invalid tmdb.retry.statuses: %d is not an HTTP error status", status)
+		}
+	}
+	return nil
+}
+
+// CircuitBreakerConfig controls the circuit breaker around TMDB requests
+// After FailureThreshold consecutive network or 5xx failures the breaker opens
+// and requests fail immediately; after OpenTimeout one probe request is let
+// through, and its outcome closes or reopens the breaker. While TMDB is
+// unavailable, cached responses that expired less than MaxStale ago are served
+// instead, marked as stale in the tool result
+type CircuitBreakerConfig struct {
+	Enabled          bool          `mapstructure:"enabled" json:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold" json:"failure_threshold"` // 连续失败多少次后打开
+	OpenTimeout      time.Duration `mapstructure:"open_timeout" json:"open_timeout"`           // 打开后多久放行一个探测请求
+	MaxStale         time.Duration `mapstructure:"max_stale" json:"max_stale"`                 // 可返回的缓存最多过期多久；0 表示不返回过期数据
+}
+
+// APIKeyConfig is one credential of the tmdb.api_keys pool
+// A plain string in the list (or in the comma-separated TMDB_API_KEYS) is a key without options
+type APIKeyConfig struct {
+	Name      string `mapstructure:"name" json:"name"`             // 日志和指标中使用，默认 key1、key2…
+	Key       string `mapstructure:"key" json:"key"`               // v3 API key 或 v4 read access token
+	RateLimit int    `mapstructure:"rate_limit" json:"rate_limit"` // 每 10 秒请求数，0 表示与 tmdb.rate_limit 相同
+}
+
+// Key selection strategies of the tmdb.api_keys pool
+const (
+	KeySelectionRoundRobin  = "round_robin"
+	KeySelectionLeastLoaded = "least_loaded"
+)
+
+// ClientKeysConfig controls TMDB credentials (v3 API key or v4 read access token)
+// supplied by HTTP clients; calls of such sessions go through a per-key client
+// with its own rate limiter and memory cache instead of the server's key
+type ClientKeysConfig struct {
+	// Mode: disabled（拒绝携带该请求头的请求）、optional（未携带时使用服务器的 key）或 required
+	Mode   string `mapstructure:"mode" json:"mode"`
+	Header string `mapstructure:"header" json:"header"` // 携带凭据的请求头
+	// RateLimit 是每个 key 每 10 秒的请求数，0 表示与 tmdb.rate_limit 相同
+	RateLimit int `mapstructure:"rate_limit" json:"rate_limit"`
+	// IdleTimeout 后未使用的客户端被回收；MaxClients 限制同时保留的客户端数
+	IdleTimeout time.Duration `mapstructure:"idle_timeout" json:"idle_timeout"`
+	MaxClients  int           `mapstructure:"max_clients" json:"max_clients"`
+}
+
+// Enabled reports whether clients may supply their own TMDB credentials
+func (k ClientKeysConfig) Enabled() bool {
+	return k.Mode == "optional" || k.Mode == "required"
+}
+
+// CacheConfig contains TMDB response cache configuration
+type CacheConfig struct {
+	Enabled    bool            `mapstructure:"enabled" json:"enabled"`
+	MaxEntries int             `mapstructure:"max_entries" json:"max_entries"` // LRU 最大条目数
+	TTL        CacheTTLConfig  `mapstructure:"ttl" json:"ttl"`
+	Disk       DiskCacheConfig `mapstructure:"disk" json:"disk"`
+}
+
+// DiskCacheConfig contains the persistent on-disk cache configuration
+// The disk cache keeps responses across restarts (e.g. stdio mode, where every
+// client session starts a new process) and uses the same TTLs as the memory cache
+// The database file is locked by the process that opens it: concurrent processes
+// sharing a path run without the disk cache (a warning is logged), so give each
+// concurrently running server its own path
+type DiskCacheConfig struct {
+	Enabled   bool   `mapstructure:"enabled" json:"enabled"`
+	Path      string `mapstructure:"path" json:"path"`               // 缓存目录，默认 ~/.tmdb-mcp/cache
+	MaxSizeMB int    `mapstructure:"max_size_mb" json:"max_size_mb"` // 最大容量（MB），超出后淘汰过期和最旧的记录至 90%；0 表示不限制
+}
+
+// CacheTTLConfig contains cache TTLs per endpoint category
+// A TTL of 0 disables caching for that category
+type CacheTTLConfig struct {
+	Details         time.Duration `mapstructure:"details" json:"details"`                 // /movie/{id}, /tv/{id}, /person/{id}
+	Configuration   time.Duration `mapstructure:"configuration" json:"configuration"`     // /configuration, /genre/*
+	Trending        time.Duration `mapstructure:"trending" json:"trending"`               // /trending/*
+	Search          time.Duration `mapstructure:"search" json:"search"`                   // /search/*
+	Discover        time.Duration `mapstructure:"discover" json:"discover"`               // /discover/*
+	Recommendations time.Duration `mapstructure:"recommendations" json:"recommendations"` // /{movie,tv}/{id}/recommendations
 }
 
 // ServerConfig contains server configuration
 type ServerConfig struct {
 	Mode string    `mapstructure:"mode" json:"mode"`
 	SSE  SSEConfig `mapstructure:"sse" json:"sse"`
+
+	// ShutdownTimeout 是收到 SIGINT/SIGTERM 后等待进行中工具调用完成的最长时间
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" json:"shutdown_timeout"`
+	// StopOnStdioClose 为 true 时，both 模式下 stdin 关闭会停止整个进程（包括 HTTP）
+	StopOnStdioClose bool `mapstructure:"stop_on_stdio_close" json:"stop_on_stdio_close"`
 }
 
 // SSEConfig contains SSE server configuration
 type SSEConfig struct {
 	// Enabled bool   `mapstructure:"enabled" json:"enabled"`
-	Host  string `mapstructure:"host" json:"host"`
-	Port  int    `mapstructure:"port" json:"port"`
-	Token string `mapstructure:"token" json:"token"`
+	Host  string    `mapstructure:"host" json:"host"`
+	Port  int       `mapstructure:"port" json:"port"`
+	Token string    `mapstructure:"token" json:"token"`
+	TLS   TLSConfig `mapstructure:"tls" json:"tls"`
+
+	// OAuth 启用后按 MCP 授权规范校验 OAuth 2.1 访问令牌（JWT）
+	OAuth OAuthConfig `mapstructure:"oauth" json:"oauth"`
+
+	// Tokens 是具名访问令牌列表（仅保存哈希），设置后可与 Token 共存
+	// 修改后发送 SIGHUP 即可重新加载，无需重启
+	Tokens []TokenConfig `mapstructure:"tokens" json:"tokens"`
+}
+
+// TokenConfig describes a named access token for the HTTP transport
+// Only the SHA-256 hash of the secret is stored; generate one with "tmdb-mcp token new NAME"
+type TokenConfig struct {
+	Name string `mapstructure:"name" json:"name"`
+	Hash string `mapstructure:"hash" json:"hash"` // sha256:<64 位十六进制>
+	// ExpiresAt: RFC 3339 时间或 YYYY-MM-DD 日期（当天 UTC 0 点过期），为空表示永不过期
+	ExpiresAt time.Time `mapstructure:"expires_at" json:"expires_at"`
+	// AllowedTools 限制可调用的工具，为空表示不限制
+	AllowedTools []string `mapstructure:"allowed_tools" json:"allowed_tools"`
+	// RateLimit 是每分钟允许的工具调用次数，0 表示不限制
+	RateLimit int `mapstructure:"rate_limit" json:"rate_limit"`
+	// Admin 允许修改整个服务器的设置（如 PUT /logging/level）
+	Admin bool `mapstructure:"admin" json:"admin"`
+}
+
+// TokenHashPrefix prefixes the hex-encoded SHA-256 hash of a token secret
+const TokenHashPrefix = "sha256:"
+
+// stringToTimeHook decodes RFC 3339 times and YYYY-MM-DD dates given as strings
+// (YAML timestamps and dates already arrive as time.Time)
+func stringToTimeHook(from, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(time.Time{}) {
+		return data, nil
+	}
+	value := strings.TrimSpace(data.(string))
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	at, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q: must be an RFC 3339 time or YYYY-MM-DD date", value)
+	}
+	return at, nil
+}
+
+// stringToAPIKeyHook decodes a plain string in tmdb.api_keys as a key without
+// options, and a comma-separated string (TMDB_API_KEYS) as a list of such keys
+func stringToAPIKeyHook(from, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String {
+		return data, nil
+	}
+	switch to {
+	case reflect.TypeOf(APIKeyConfig{}):
+		return APIKeyConfig{Key: strings.TrimSpace(data.(string))}, nil
+	case reflect.TypeOf([]APIKeyConfig{}):
+		var keys []APIKeyConfig
+		for _, key := range strings.Split(data.(string), ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, APIKeyConfig{Key: key})
+			}
+		}
+		return keys, nil
+	}
+	return data, nil
+}
+
+// validateAPIKeys checks the tmdb.api_keys pool
+func validateAPIKeys(keys []APIKeyConfig) error {
+	seen := make(map[string]bool, len(keys))
+	for i, k := range keys {
+		if k.Name == "" {
+			return fmt.Errorf("invalid tmdb.api_keys[%d]: name is required", i)
+		}
+		if seen[k.Name] {
+			return fmt.Errorf("invalid tmdb.api_keys[%d]: duplicate name %q", i, k.Name)
+		}
+		seen[k.Name] = true
+		if k.Key == "" {
+			return fmt.Errorf("invalid tmdb.api_keys[%d] (%s): key is required", i, k.Name)
+		}
+		if k.RateLimit < 0 {
+			return fmt.Errorf("invalid tmdb.api_keys[%d] (%s): rate_limit must not be negative", i, k.Name)
+		}
+	}
+	return nil
+}
+
+// validateTokens checks the named token list; legacy is server.sse.token
+func validateTokens(tokens []TokenConfig, legacy string) error {
+	seen := make(map[string]bool, len(tokens))
+	for i, t := range tokens {
+		if t.Name == "" {
+			return fmt.Errorf("invalid server.sse.tokens[%d]: name is required", i)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("invalid server.sse.tokens[%d]: duplicate name %q", i, t.Name)
+		}
+		seen[t.Name] = true
+		if err := ValidateTokenHash(t.Hash); err != nil {
+			return fmt.Errorf("invalid server.sse.tokens[%d] (%s).hash: %w", i, t.Name, err)
+		}
+		if t.Name == "default" && legacy != "" {
+			return fmt.Errorf("invalid server.sse.tokens[%d]: name \"default\" is reserved for server.sse.token", i)
+		}
+		if t.RateLimit < 0 {
+			return fmt.Errorf("invalid server.sse.tokens[%d] (%s).rate_limit: must not be negative", i, t.Name)
+		}
+	}
+	return nil
+}
+
+// TLSConfig contains TLS and mutual TLS configuration for the HTTP server
+// Certificate, key and CA files are reloaded automatically when they change
+type TLSConfig struct {
+	Enabled    bool   `mapstructure:"enabled" json:"enabled"`
+	CertFile   string `mapstructure:"cert_file" json:"cert_file"`
+	KeyFile    string `mapstructure:"key_file" json:"key_file"`
+	MinVersion string `mapstructure:"min_version" json:"min_version"` // 1.2 或 1.3
+
+	// ClientCAFile 设置后启用客户端证书校验（mTLS）
+	ClientCAFile string `mapstructure:"client_ca_file" json:"client_ca_file"`
+	// ClientAuth: require（握手时必须提供证书）或 optional（未提供证书时回退到 bearer token）
+	ClientAuth string `mapstructure:"client_auth" json:"client_auth"`
+	// AllowedClients 限制允许的客户端身份（证书 CN 或 SAN），为空表示 CA 签发的证书均可
+	AllowedClients []string `mapstructure:"allowed_clients" json:"allowed_clients"`
+	// TrustClientCert 为 true 时，通过校验的客户端证书无需再提供 bearer token
+	TrustClientCert bool `mapstructure:"trust_client_cert" json:"trust_client_cert"`
+}
+
+// OAuthConfig makes the HTTP endpoints an OAuth 2.1 protected resource
+// Access tokens are JWTs issued by the authorization server and verified
+// against its JWKS; clients discover the authorization server through the
+// protected resource metadata (RFC 9728)
+type OAuthConfig struct {
+	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	// Resource 是本服务的规范 URL（如 https://mcp.example.com），客户端以此申请令牌
+	Resource string `mapstructure:"resource" json:"resource"`
+	// AuthorizationServers 是签发令牌的授权服务器 issuer 列表，写入 resource metadata
+	AuthorizationServers []string `mapstructure:"authorization_servers" json:"authorization_servers"`
+	// Issuer 是期望的 iss 声明，默认为 AuthorizationServers 的第一个
+	Issuer string `mapstructure:"issuer" json:"issuer"`
+	// Audience 是可接受的 aud 声明（任意一个匹配即可），默认为 Resource
+	Audience []string `mapstructure:"audience" json:"audience"`
+	// JWKSURL 和 JWKSFile 二选一：URL 定期刷新并在遇到未知 kid 时重新获取，文件变更后自动重新加载
+	JWKSURL  string `mapstructure:"jwks_url" json:"jwks_url"`
+	JWKSFile string `mapstructure:"jwks_file" json:"jwks_file"`
+	// ScopesSupported 写入 resource metadata；RequiredScopes 是访问令牌必须包含的全部 scope
+	ScopesSupported []string `mapstructure:"scopes_supported" json:"scopes_supported"`
+	RequiredScopes  []string `mapstructure:"required_scopes" json:"required_scopes"`
+	// AllowStaticTokens 为 true 时 server.sse.token / server.sse.tokens 仍然有效
+	AllowStaticTokens bool `mapstructure:"allow_static_tokens" json:"allow_static_tokens"`
+}
+
+// validate checks the OAuth configuration when OAuth is enabled
+func (o OAuthConfig) validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if o.Resource == "" {
+		return fmt.Errorf("invalid server.sse.oauth: resource is required when OAuth is enabled")
+	}
+	if err := validateBaseURL("server.sse.oauth.resource", o.Resource); err != nil {
+		return err
+	}
+	if len(o.AuthorizationServers) == 0 {
+		return fmt.Errorf("invalid server.sse.oauth: authorization_servers is required when OAuth is enabled")
+	}
+	for _, server := range o.AuthorizationServers {
+		if err := validateBaseURL("server.sse.oauth.authorization_servers", server); err != nil {
+			return err
+		}
+	}
+	if (o.JWKSURL == "") == (o.JWKSFile == "") {
+		return fmt.Errorf("invalid server.sse.oauth: exactly one of jwks_url and jwks_file is required")
+	}
+	return validateBaseURL("server.sse.oauth.jwks_url", o.JWKSURL)
+}
+
+// ExpectedIssuer returns the iss claim access tokens must carry
+func (o OAuthConfig) ExpectedIssuer() string {
+	if o.Issuer != "" || len(o.AuthorizationServers) == 0 {
+		return o.Issuer
+	}
+	return o.AuthorizationServers[0]
+}
+
+// ExpectedAudience returns the aud values accepted in access tokens
+func (o OAuthConfig) ExpectedAudience() []string {
+	if len(o.Audience) > 0 {
+		return o.Audience
+	}
+	return []string{o.Resource}
+}
+
+// TracingConfig contains OpenTelemetry tracing configuration
+// Spans are exported over OTLP; an empty endpoint falls back to the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables
+type TracingConfig struct {
+	Enabled     bool              `mapstructure:"enabled" json:"enabled"`
+	Protocol    string            `mapstructure:"protocol" json:"protocol"`         // http 或 grpc
+	Endpoint    string            `mapstructure:"endpoint" json:"endpoint"`         // host:port，如 localhost:4318
+	Insecure    bool              `mapstructure:"insecure" json:"insecure"`         // 不使用 TLS
+	Headers     map[string]string `mapstructure:"headers" json:"headers"`           // 附加请求头（如认证）
+	ServiceName string            `mapstructure:"service_name" json:"service_name"` // service.name 资源属性
+	SampleRatio float64           `mapstructure:"sample_ratio" json:"sample_ratio"` // 无上游 trace context 时的采样比例（0-1）
 }
 
 // LogConfig contains logging configuration
@@ -45,13 +417,49 @@ type LogConfig struct {
 	Level string `mapstructure:"level" json:"level"`
 }
 
-// Load loads configuration from multiple sources with priority: CLI > ENV > File
-func Load() (*Config, error) {
-	v := viper.New()
+// ResponseConfig contains the server-wide default budget for tool responses
+// A value of 0 disables the corresponding limit
+type ResponseConfig struct {
+	MaxResults int `mapstructure:"max_results" json:"max_results"` // 列表（results/cast/crew 等）最大条目数
+	MaxChars   int `mapstructure:"max_chars" json:"max_chars"`     // 长文本字段（overview/biography）最大字符数
+}
 
-	// 设置默认值
-	setDefaults(v)
+// ToolsConfig contains MCP tool configuration
+// Enabled 为空时暴露所有工具；Disabled 中的工具始终隐藏
+type ToolsConfig struct {
+	Enabled  []string    `mapstructure:"enabled" json:"enabled"`   // 仅暴露这些工具（为空表示全部）
+	Disabled []string    `mapstructure:"disabled" json:"disabled"` // 隐藏这些工具
+	Batch    BatchConfig `mapstructure:"batch" json:"batch"`
+}
 
+// IsToolEnabled reports whether the named tool should be exposed
+func (c ToolsConfig) IsToolEnabled(name string) bool {
+	for _, disabled := range c.Disabled {
+		if disabled == name {
+			return false
+		}
+	}
+	if len(c.Enabled) == 0 {
+		return true
+	}
+	for _, enabled := range c.Enabled {
+		if enabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchConfig contains configuration for the get_details_batch tool
+type BatchConfig struct {
+	MaxItems    int `mapstructure:"max_items" json:"max_items"`     // 单次批量请求的最大条目数
+	Concurrency int `mapstructure:"concurrency" json:"concurrency"` // 并发获取的最大数量
+}
+
+// Load loads configuration from multiple sources with priority: CLI > ENV > File
+// It creates the configuration directory and, in SSE mode, may generate an SSE
+// token and save it to the configuration file
+func Load() (*Config, error) {
 	// 配置文件路径
 	configDir, err := getConfigDir()
 	if err != nil {
@@ -63,6 +471,40 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to ensure config directory: %w", err)
 	}
 
+	cfg, v, err := readConfig(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// 处理 SSE Token（如果 SSE 模式启用）
+	if cfg.Server.Mode == "sse" || cfg.Server.Mode == "both" {
+		if err := handleSSEToken(cfg, v, configDir); err != nil {
+			return nil, fmt.Errorf("failed to handle SSE token: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Read loads configuration like Load, without side effects: it neither creates
+// the configuration directory nor generates or saves an SSE token
+// Used by CLI commands that only need a few settings (e.g. the cache path)
+func Read() (*Config, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+	cfg, _, err := readConfig(configDir)
+	return cfg, err
+}
+
+// readConfig reads defaults, the configuration file in configDir and environment variables
+func readConfig(configDir string) (*Config, *viper.Viper, error) {
+	v := viper.New()
+
+	// 设置默认值
+	setDefaults(v)
+
 	// 设置配置文件路径
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
@@ -71,7 +513,7 @@ func Load() (*Config, error) {
 	// 读取配置文件（允许不存在）
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("failed to read config file: %w", err)
+			return nil, nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 		// 配置文件不存在是可以接受的，使用默认值和环境变量
 	}
@@ -85,25 +527,50 @@ func Load() (*Config, error) {
 
 	// 解析配置到结构体
 	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := v.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToTimeHook,
+		stringToAPIKeyHook,
+	))); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	// 处理 SSE Token（如果 SSE 模式启用）
-	if cfg.Server.Mode == "sse" || cfg.Server.Mode == "both" {
-		if err := handleSSEToken(&cfg, v, configDir); err != nil {
-			return nil, fmt.Errorf("failed to handle SSE token: %w", err)
+	// key 池中未命名的 key 按位置命名
+	for i := range cfg.TMDB.APIKeys {
+		if cfg.TMDB.APIKeys[i].Name == "" {
+			cfg.TMDB.APIKeys[i].Name = fmt.Sprintf("key%d", i+1)
 		}
 	}
 
-	return &cfg, nil
+	// 磁盘缓存默认位于配置目录下
+	if cfg.TMDB.Cache.Disk.Path == "" {
+		cfg.TMDB.Cache.Disk.Path = filepath.Join(configDir, "cache")
+	}
+
+	return &cfg, v, nil
 }
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	// 检查必需配置：TMDB API Key
-	if c.TMDB.APIKey == "" {
-		return fmt.Errorf("missing required configuration: TMDB API Key. Please set TMDB_API_KEY environment variable or add it to ~/.tmdb-mcp/config.yaml")
+	// 检查必需配置：TMDB API Key、Read Access Token 或 key 池
+	credentials := 0
+	for _, set := range []bool{c.TMDB.APIKey != "", c.TMDB.AccessToken != "", len(c.TMDB.APIKeys) > 0} {
+		if set {
+			credentials++
+		}
+	}
+	if credentials == 0 {
+		return fmt.Errorf("missing required configuration: TMDB API Key or Read Access Token. Please set TMDB_API_KEY or TMDB_ACCESS_TOKEN environment variable or add it to ~/.tmdb-mcp/config.yaml")
+	}
+	if credentials > 1 {
+		return fmt.Errorf("invalid tmdb configuration: set only one of api_key, access_token and api_keys")
+	}
+	if err := validateAPIKeys(c.TMDB.APIKeys); err != nil {
+		return err
+	}
+	if s := c.TMDB.KeySelection; s != "" && s != KeySelectionRoundRobin && s != KeySelectionLeastLoaded {
+		return fmt.Errorf("invalid tmdb.key_selection: %s (must be one of: round_robin, least_loaded)", s)
 	}
 
 	// 检查 rate limit 有效性
@@ -111,6 +578,63 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid rate_limit: must be greater than 0")
 	}
 
+	// 检查 TMDB 地址有效性
+	if err := validateBaseURL("tmdb.base_url", c.TMDB.BaseURL); err != nil {
+		return err
+	}
+	if err := validateBaseURL("tmdb.image_base_url", c.TMDB.ImageBaseURL); err != nil {
+		return err
+	}
+
+	// 检查缓存配置有效性
+	if c.TMDB.Cache.Enabled && c.TMDB.Cache.MaxEntries <= 0 {
+		return fmt.Errorf("invalid tmdb.cache.max_entries: must be greater than 0 when cache is enabled")
+	}
+	ttl := c.TMDB.Cache.TTL
+	if c.TMDB.Cache.Disk.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid tmdb.cache.disk.max_size_mb: must not be negative")
+	}
+	if ttl.Details < 0 || ttl.Configuration < 0 || ttl.Trending < 0 || ttl.Search < 0 || ttl.Discover < 0 || ttl.Recommendations < 0 {
+		return fmt.Errorf("invalid tmdb.cache.ttl: must not be negative")
+	}
+
+	if c.TMDB.AdaptiveRateLimit.RampUp < 0 {
+		return fmt.Errorf("invalid tmdb.adaptive_rate_limit.ramp_up: must not be negative")
+	}
+
+	if err := c.TMDB.Retry.validate(); err != nil {
+		return err
+	}
+
+	if breaker := c.TMDB.CircuitBreaker; breaker.Enabled {
+		if breaker.FailureThreshold <= 0 {
+			return fmt.Errorf("invalid tmdb.circuit_breaker.failure_threshold: must be greater than 0")
+		}
+		if breaker.OpenTimeout <= 0 {
+			return fmt.Errorf("invalid tmdb.circuit_breaker.open_timeout: must be greater than 0")
+		}
+		if breaker.MaxStale < 0 {
+			return fmt.Errorf("invalid tmdb.circuit_breaker.max_stale: must not be negative")
+		}
+	}
+
+	// 检查客户端 TMDB 凭据配置有效性
+	keys := c.TMDB.ClientKeys
+	if keys.Mode != "" && keys.Mode != "disabled" && !keys.Enabled() {
+		return fmt.Errorf("invalid tmdb.client_keys.mode: %s (must be one of: disabled, optional, required)", keys.Mode)
+	}
+	if keys.Enabled() {
+		if keys.Header == "" {
+			return fmt.Errorf("invalid tmdb.client_keys.header: must not be empty")
+		}
+		if keys.RateLimit < 0 {
+			return fmt.Errorf("invalid tmdb.client_keys.rate_limit: must not be negative")
+		}
+		if keys.IdleTimeout <= 0 || keys.MaxClients <= 0 {
+			return fmt.Errorf("invalid tmdb.client_keys: idle_timeout and max_clients must be greater than 0")
+		}
+	}
+
 	// 检查日志级别有效性
 	validLevels := map[string]bool{
 		"debug": true,
@@ -131,7 +655,77 @@ func (c *Config) Validate() error {
 	if !validModes[c.Server.Mode] {
 		return fmt.Errorf("invalid server mode: %s (must be one of: stdio, sse, both)", c.Server.Mode)
 	}
+	if c.Server.ShutdownTimeout < 0 {
+		return fmt.Errorf("invalid server.shutdown_timeout: must not be negative")
+	}
+	if err := c.Server.SSE.TLS.validate(); err != nil {
+		return err
+	}
+	if err := validateTokens(c.Server.SSE.Tokens, c.Server.SSE.Token); err != nil {
+		return err
+	}
+	if err := c.Server.SSE.OAuth.validate(); err != nil {
+		return err
+	}
+
+	// 检查响应预算有效性
+	if c.Response.MaxResults < 0 {
+		return fmt.Errorf("invalid response.max_results: must not be negative")
+	}
+	if c.Response.MaxChars < 0 {
+		return fmt.Errorf("invalid response.max_chars: must not be negative")
+	}
 
+	// 检查批量工具配置有效性
+	if c.Tools.Batch.MaxItems <= 0 {
+		return fmt.Errorf("invalid tools.batch.max_items: must be greater than 0")
+	}
+	if c.Tools.Batch.Concurrency <= 0 {
+		return fmt.Errorf("invalid tools.batch.concurrency: must be greater than 0")
+	}
+
+	// 检查 tracing 配置有效性
+	if c.Tracing.Enabled {
+		if c.Tracing.Protocol != "http" && c.Tracing.Protocol != "grpc" {
+			return fmt.Errorf("invalid tracing.protocol: %s (must be one of: http, grpc)", c.Tracing.Protocol)
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("invalid tracing.sample_ratio: must be between 0 and 1")
+		}
+	}
+
+	return nil
+}
+
+// validate checks the TLS configuration when TLS is enabled
+func (t TLSConfig) validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return fmt.Errorf("invalid server.sse.tls: cert_file and key_file are required when TLS is enabled")
+	}
+	if t.MinVersion != "1.2" && t.MinVersion != "1.3" {
+		return fmt.Errorf("invalid server.sse.tls.min_version: %s (must be one of: 1.2, 1.3)", t.MinVersion)
+	}
+	if t.ClientAuth != "require" && t.ClientAuth != "optional" {
+		return fmt.Errorf("invalid server.sse.tls.client_auth: %s (must be one of: require, optional)", t.ClientAuth)
+	}
+	if t.ClientCAFile == "" && (len(t.AllowedClients) > 0 || t.TrustClientCert) {
+		return fmt.Errorf("invalid server.sse.tls: allowed_clients and trust_client_cert require client_ca_file")
+	}
+	return nil
+}
+
+// validateBaseURL checks that value (if set) is an absolute http(s) URL
+func validateBaseURL(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s: %q must be an absolute http or https URL", key, value)
+	}
 	return nil
 }
 
@@ -140,23 +734,93 @@ func setDefaults(v *viper.Viper) {
 	// TMDB defaults
 	v.SetDefault("tmdb.language", "en-US")
 	v.SetDefault("tmdb.rate_limit", 40)
+	v.SetDefault("tmdb.base_url", "https://api.themoviedb.org/3")
+	v.SetDefault("tmdb.image_base_url", "https://image.tmdb.org/t/p/")
+	v.SetDefault("tmdb.cache.enabled", true)
+	v.SetDefault("tmdb.key_selection", KeySelectionRoundRobin)
+	v.SetDefault("tmdb.adaptive_rate_limit.enabled", true)
+	v.SetDefault("tmdb.adaptive_rate_limit.ramp_up", "30s")
+	v.SetDefault("tmdb.adaptive_rate_limit.headers", false)
+	v.SetDefault("tmdb.retry.max_attempts", 4)
+	v.SetDefault("tmdb.retry.base_delay", "1s")
+	v.SetDefault("tmdb.retry.max_delay", "10s")
+	v.SetDefault("tmdb.retry.jitter", 0.5)
+	v.SetDefault("tmdb.retry.statuses", []int{429, 500, 502, 503, 504})
+	v.SetDefault("tmdb.retry.network_errors", true)
+	v.SetDefault("tmdb.retry.timeout", "30s")
+	v.SetDefault("tmdb.circuit_breaker.enabled", true)
+	v.SetDefault("tmdb.circuit_breaker.failure_threshold", 5)
+	v.SetDefault("tmdb.circuit_breaker.open_timeout", "30s")
+	v.SetDefault("tmdb.circuit_breaker.max_stale", "24h")
+	v.SetDefault("tmdb.client_keys.mode", "disabled")
+	v.SetDefault("tmdb.client_keys.header", "X-TMDB-API-Key")
+	v.SetDefault("tmdb.client_keys.rate_limit", 0)
+	v.SetDefault("tmdb.client_keys.idle_timeout", "10m")
+	v.SetDefault("tmdb.client_keys.max_clients", 100)
+	v.SetDefault("tmdb.cache.max_entries", 1000)
+	v.SetDefault("tmdb.cache.ttl.details", "24h")
+	v.SetDefault("tmdb.cache.ttl.configuration", "168h")
+	v.SetDefault("tmdb.cache.ttl.trending", "1h")
+	v.SetDefault("tmdb.cache.ttl.search", "15m")
+	v.SetDefault("tmdb.cache.ttl.discover", "1h")
+	v.SetDefault("tmdb.cache.ttl.recommendations", "24h")
+	v.SetDefault("tmdb.cache.disk.enabled", false)
+	v.SetDefault("tmdb.cache.disk.max_size_mb", 100)
 
 	// Server defaults
 	v.SetDefault("server.mode", "both")
 	v.SetDefault("server.sse.enabled", false)
 	v.SetDefault("server.sse.host", "0.0.0.0")
 	v.SetDefault("server.sse.port", 8910)
+	v.SetDefault("server.sse.tls.enabled", false)
+	v.SetDefault("server.sse.tls.min_version", "1.2")
+	v.SetDefault("server.sse.tls.client_auth", "require")
+	v.SetDefault("server.sse.oauth.enabled", false)
+	v.SetDefault("server.sse.oauth.allow_static_tokens", false)
+	v.SetDefault("server.shutdown_timeout", "25s")
+	v.SetDefault("server.stop_on_stdio_close", false)
 
 	// Logging defaults
 	v.SetDefault("logging.level", "info")
+
+	// Response defaults
+	v.SetDefault("response.max_results", 20)
+	v.SetDefault("response.max_chars", 1000)
+
+	// Tools defaults
+	v.SetDefault("tools.batch.max_items", 20)
+	v.SetDefault("tools.batch.concurrency", 4)
+
+	// Tracing defaults
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.protocol", "http")
+	v.SetDefault("tracing.service_name", "tmdb-mcp")
+	v.SetDefault("tracing.sample_ratio", 1.0)
 }
 
 // bindEnvVars binds all configuration keys to environment variables
 func bindEnvVars(v *viper.Viper) {
 	// TMDB
 	v.BindEnv("tmdb.api_key", "TMDB_API_KEY")
+	v.BindEnv("tmdb.access_token", "TMDB_ACCESS_TOKEN")
+	v.BindEnv("tmdb.api_keys", "TMDB_API_KEYS")
+	v.BindEnv("tmdb.key_selection", "TMDB_KEY_SELECTION")
+	v.BindEnv("tmdb.adaptive_rate_limit.enabled", "TMDB_ADAPTIVE_RATE_LIMIT_ENABLED")
+	v.BindEnv("tmdb.adaptive_rate_limit.headers", "TMDB_ADAPTIVE_RATE_LIMIT_HEADERS")
+	v.BindEnv("tmdb.retry.max_attempts", "TMDB_RETRY_MAX_ATTEMPTS")
+	v.BindEnv("tmdb.retry.timeout", "TMDB_RETRY_TIMEOUT")
+	v.BindEnv("tmdb.circuit_breaker.enabled", "TMDB_CIRCUIT_BREAKER_ENABLED")
 	v.BindEnv("tmdb.language", "TMDB_LANGUAGE")
 	v.BindEnv("tmdb.rate_limit", "TMDB_RATE_LIMIT")
+	v.BindEnv("tmdb.base_url", "TMDB_BASE_URL")
+	v.BindEnv("tmdb.image_base_url", "TMDB_IMAGE_BASE_URL")
+	v.BindEnv("tmdb.cache.enabled", "TMDB_CACHE_ENABLED")
+	v.BindEnv("tmdb.client_keys.mode", "TMDB_CLIENT_KEYS_MODE")
+	v.BindEnv("tmdb.client_keys.header", "TMDB_CLIENT_KEYS_HEADER")
+	v.BindEnv("tmdb.cache.max_entries", "TMDB_CACHE_MAX_ENTRIES")
+	v.BindEnv("tmdb.cache.disk.enabled", "TMDB_CACHE_DISK_ENABLED")
+	v.BindEnv("tmdb.cache.disk.path", "TMDB_CACHE_DISK_PATH")
+	v.BindEnv("tmdb.cache.disk.max_size_mb", "TMDB_CACHE_DISK_MAX_SIZE_MB")
 
 	// Server
 	v.BindEnv("server.mode", "SERVER_MODE")
@@ -164,9 +828,39 @@ func bindEnvVars(v *viper.Viper) {
 	v.BindEnv("server.sse.host", "SERVER_SSE_HOST")
 	v.BindEnv("server.sse.port", "SERVER_SSE_PORT")
 	v.BindEnv("server.sse.token", "SSE_TOKEN")
+	v.BindEnv("server.sse.tls.enabled", "SERVER_SSE_TLS_ENABLED")
+	v.BindEnv("server.sse.tls.cert_file", "SERVER_SSE_TLS_CERT_FILE")
+	v.BindEnv("server.sse.tls.key_file", "SERVER_SSE_TLS_KEY_FILE")
+	v.BindEnv("server.sse.tls.client_ca_file", "SERVER_SSE_TLS_CLIENT_CA_FILE")
+	v.BindEnv("server.sse.oauth.enabled", "SERVER_SSE_OAUTH_ENABLED")
+	v.BindEnv("server.sse.oauth.resource", "SERVER_SSE_OAUTH_RESOURCE")
+	v.BindEnv("server.sse.oauth.authorization_servers", "SERVER_SSE_OAUTH_AUTHORIZATION_SERVERS")
+	v.BindEnv("server.sse.oauth.issuer", "SERVER_SSE_OAUTH_ISSUER")
+	v.BindEnv("server.sse.oauth.jwks_url", "SERVER_SSE_OAUTH_JWKS_URL")
+	v.BindEnv("server.sse.oauth.jwks_file", "SERVER_SSE_OAUTH_JWKS_FILE")
+	v.BindEnv("server.shutdown_timeout", "SERVER_SHUTDOWN_TIMEOUT")
+	v.BindEnv("server.stop_on_stdio_close", "SERVER_STOP_ON_STDIO_CLOSE")
 
 	// Logging
 	v.BindEnv("logging.level", "LOGGING_LEVEL")
+
+	// Response
+	v.BindEnv("response.max_results", "RESPONSE_MAX_RESULTS")
+	v.BindEnv("response.max_chars", "RESPONSE_MAX_CHARS")
+
+	// Tools
+	v.BindEnv("tools.enabled", "TOOLS_ENABLED")
+	v.BindEnv("tools.disabled", "TOOLS_DISABLED")
+	v.BindEnv("tools.batch.max_items", "TOOLS_BATCH_MAX_ITEMS")
+	v.BindEnv("tools.batch.concurrency", "TOOLS_BATCH_CONCURRENCY")
+
+	// Tracing
+	v.BindEnv("tracing.enabled", "TRACING_ENABLED")
+	v.BindEnv("tracing.protocol", "TRACING_PROTOCOL")
+	v.BindEnv("tracing.endpoint", "TRACING_ENDPOINT")
+	v.BindEnv("tracing.insecure", "TRACING_INSECURE")
+	v.BindEnv("tracing.service_name", "TRACING_SERVICE_NAME")
+	v.BindEnv("tracing.sample_ratio", "TRACING_SAMPLE_RATIO")
 }
 
 // getConfigDir returns the configuration directory path
@@ -192,6 +886,12 @@ func ensureConfigDir(configDir string) error {
 func handleSSEToken(cfg *Config, v *viper.Viper, configDir string) error {
 	token := cfg.Server.SSE.Token
 
+	// 已配置具名令牌，或仅接受 OAuth 访问令牌时，不再自动生成单一令牌
+	oauth := cfg.Server.SSE.OAuth
+	if token == "" && (len(cfg.Server.SSE.Tokens) > 0 || (oauth.Enabled && !oauth.AllowStaticTokens)) {
+		return nil
+	}
+
 	// If token is empty, generate a new one
 	if token == "" {
 		newToken, err := GenerateSSEToken()
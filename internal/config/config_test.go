@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -35,6 +36,12 @@ func TestConfig_Validate(t *testing.T) {
 				Logging: LogConfig{
 					Level: "info",
 				},
+				Tools: ToolsConfig{
+					Batch: BatchConfig{
+						MaxItems:    20,
+						Concurrency: 4,
+					},
+				},
 			},
 			wantErr: false,
 		},
@@ -110,6 +117,311 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid server mode",
 		},
+		{
+			name: "negative response budget",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey:    "test_api_key",
+					Language:  "en-US",
+					RateLimit: 40,
+				},
+				Server: ServerConfig{
+					Mode: "stdio",
+				},
+				Logging: LogConfig{
+					Level: "info",
+				},
+				Response: ResponseConfig{
+					MaxResults: -1,
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid response.max_results",
+		},
+		{
+			name: "invalid batch concurrency",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey:    "test_api_key",
+					Language:  "en-US",
+					RateLimit: 40,
+				},
+				Server: ServerConfig{
+					Mode: "stdio",
+				},
+				Logging: LogConfig{
+					Level: "info",
+				},
+				Tools: ToolsConfig{
+					Batch: BatchConfig{
+						MaxItems:    20,
+						Concurrency: 0,
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid tools.batch.concurrency",
+		},
+		{
+			name: "cache enabled without max entries",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey:    "test_api_key",
+					Language:  "en-US",
+					RateLimit: 40,
+					Cache: CacheConfig{
+						Enabled:    true,
+						MaxEntries: 0,
+					},
+				},
+				Server: ServerConfig{
+					Mode: "stdio",
+				},
+				Logging: LogConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid tmdb.cache.max_entries",
+		},
+		{
+			name: "relative base url",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey:    "test_api_key",
+					Language:  "en-US",
+					RateLimit: 40,
+					BaseURL:   "localhost:8787/3",
+				},
+				Server: ServerConfig{
+					Mode: "stdio",
+				},
+				Logging: LogConfig{
+					Level: "info",
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid tmdb.base_url",
+		},
+		{
+			name: "invalid tracing protocol",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey:    "test_api_key",
+					Language:  "en-US",
+					RateLimit: 40,
+				},
+				Server: ServerConfig{
+					Mode: "stdio",
+				},
+				Logging: LogConfig{
+					Level: "info",
+				},
+				Tools: ToolsConfig{
+					Batch: BatchConfig{MaxItems: 20, Concurrency: 4},
+				},
+				Tracing: TracingConfig{
+					Enabled:     true,
+					Protocol:    "zipkin",
+					SampleRatio: 1,
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid tracing.protocol",
+		},
+		{
+			name: "tls without key file",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey:    "test_api_key",
+					Language:  "en-US",
+					RateLimit: 40,
+				},
+				Server: ServerConfig{
+					Mode: "sse",
+					SSE: SSEConfig{
+						TLS: TLSConfig{Enabled: true, CertFile: "server.crt", MinVersion: "1.2", ClientAuth: "require"},
+					},
+				},
+				Logging: LogConfig{
+					Level: "info",
+				},
+				Tools: ToolsConfig{
+					Batch: BatchConfig{MaxItems: 20, Concurrency: 4},
+				},
+			},
+			wantErr: true,
+			errMsg:  "cert_file and key_file are required",
+		},
+		{
+			name: "oauth without jwks",
+			config: Config{
+				TMDB: TMDBConfig{APIKey: "test_api_key", Language: "en-US", RateLimit: 40},
+				Server: ServerConfig{
+					Mode: "sse",
+					SSE: SSEConfig{
+						OAuth: OAuthConfig{
+							Enabled:              true,
+							Resource:             "https://mcp.example.com",
+							AuthorizationServers: []string{"https://auth.example.com"},
+						},
+					},
+				},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "exactly one of jwks_url and jwks_file",
+		},
+		{
+			name: "oauth without authorization servers",
+			config: Config{
+				TMDB: TMDBConfig{APIKey: "test_api_key", Language: "en-US", RateLimit: 40},
+				Server: ServerConfig{
+					Mode: "sse",
+					SSE: SSEConfig{
+						OAuth: OAuthConfig{Enabled: true, Resource: "https://mcp.example.com", JWKSFile: "jwks.json"},
+					},
+				},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "authorization_servers is required",
+		},
+		{
+			name: "api key and access token",
+			config: Config{
+				TMDB:    TMDBConfig{APIKey: "test_api_key", AccessToken: "eyJ.a.b", Language: "en-US", RateLimit: 40},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "set only one of api_key, access_token and api_keys",
+		},
+		{
+			name: "duplicate api key names",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKeys:  []APIKeyConfig{{Name: "a", Key: "k1"}, {Name: "a", Key: "k2"}},
+					Language: "en-US", RateLimit: 40,
+				},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "duplicate name",
+		},
+		{
+			name: "negative adaptive ramp up",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey: "test_api_key", Language: "en-US", RateLimit: 40,
+					AdaptiveRateLimit: AdaptiveRateLimitConfig{Enabled: true, RampUp: -time.Second},
+				},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "ramp_up",
+		},
+		{
+			name: "circuit breaker without failure threshold",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey: "test_api_key", Language: "en-US", RateLimit: 40,
+					CircuitBreaker: CircuitBreakerConfig{Enabled: true, OpenTimeout: time.Second},
+				},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "failure_threshold",
+		},
+		{
+			name: "retry base delay above max delay",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey: "test_api_key", Language: "en-US", RateLimit: 40,
+					Retry: RetryConfig{BaseDelay: 5 * time.Second, MaxDelay: time.Second},
+				},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "base_delay",
+		},
+		{
+			name: "retry status not an error",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey: "test_api_key", Language: "en-US", RateLimit: 40,
+					Retry: RetryConfig{Statuses: []int{503, 200}},
+				},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "statuses",
+		},
+		{
+			name: "retry jitter above 1",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey: "test_api_key", Language: "en-US", RateLimit: 40,
+					Retry: RetryConfig{Jitter: 1.5},
+				},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "jitter",
+		},
+		{
+			name: "access token only",
+			config: Config{
+				TMDB:    TMDBConfig{AccessToken: "eyJ.a.b", Language: "en-US", RateLimit: 40},
+				Server:  ServerConfig{Mode: "stdio"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid client keys mode",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey: "test_api_key", Language: "en-US", RateLimit: 40,
+					ClientKeys: ClientKeysConfig{Mode: "always"},
+				},
+				Server:  ServerConfig{Mode: "sse"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "invalid tmdb.client_keys.mode",
+		},
+		{
+			name: "client keys without max clients",
+			config: Config{
+				TMDB: TMDBConfig{
+					APIKey: "test_api_key", Language: "en-US", RateLimit: 40,
+					ClientKeys: ClientKeysConfig{Mode: "required", Header: "X-TMDB-API-Key", IdleTimeout: time.Minute},
+				},
+				Server:  ServerConfig{Mode: "sse"},
+				Logging: LogConfig{Level: "info"},
+				Tools:   ToolsConfig{Batch: BatchConfig{MaxItems: 20, Concurrency: 4}},
+			},
+			wantErr: true,
+			errMsg:  "idle_timeout and max_clients must be greater than 0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -155,11 +467,49 @@ func TestLoad_Defaults(t *testing.T) {
 	assert.Equal(t, "test_key_for_defaults", cfg.TMDB.APIKey)
 	assert.Equal(t, "en-US", cfg.TMDB.Language)
 	assert.Equal(t, 40, cfg.TMDB.RateLimit)
+	assert.True(t, cfg.TMDB.AdaptiveRateLimit.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.TMDB.AdaptiveRateLimit.RampUp)
+	assert.False(t, cfg.TMDB.AdaptiveRateLimit.Headers)
+	assert.True(t, cfg.TMDB.CircuitBreaker.Enabled)
+	assert.Equal(t, 5, cfg.TMDB.CircuitBreaker.FailureThreshold)
+	assert.Equal(t, 30*time.Second, cfg.TMDB.CircuitBreaker.OpenTimeout)
+	assert.Equal(t, 24*time.Hour, cfg.TMDB.CircuitBreaker.MaxStale)
+	assert.Equal(t, 4, cfg.TMDB.Retry.MaxAttempts)
+	assert.Equal(t, time.Second, cfg.TMDB.Retry.BaseDelay)
+	assert.Equal(t, 10*time.Second, cfg.TMDB.Retry.MaxDelay)
+	assert.Equal(t, 0.5, cfg.TMDB.Retry.Jitter)
+	assert.Equal(t, []int{429, 500, 502, 503, 504}, cfg.TMDB.Retry.Statuses)
+	assert.True(t, cfg.TMDB.Retry.NetworkErrors)
+	assert.Equal(t, 30*time.Second, cfg.TMDB.Retry.Timeout)
+	assert.Equal(t, "https://api.themoviedb.org/3", cfg.TMDB.BaseURL)
+	assert.Equal(t, "https://image.tmdb.org/t/p/", cfg.TMDB.ImageBaseURL)
 	assert.Equal(t, "stdio", cfg.Server.Mode)
 	// assert.Equal(t, false, cfg.Server.SSE.Enabled)
 	assert.Equal(t, "0.0.0.0", cfg.Server.SSE.Host)
 	assert.Equal(t, 8910, cfg.Server.SSE.Port)
 	assert.Equal(t, "info", cfg.Logging.Level)
+	assert.Equal(t, 20, cfg.Response.MaxResults)
+	assert.Equal(t, 1000, cfg.Response.MaxChars)
+	assert.Equal(t, 20, cfg.Tools.Batch.MaxItems)
+	assert.Equal(t, 4, cfg.Tools.Batch.Concurrency)
+	assert.True(t, cfg.TMDB.Cache.Enabled)
+	assert.Equal(t, 1000, cfg.TMDB.Cache.MaxEntries)
+	assert.Equal(t, 24*time.Hour, cfg.TMDB.Cache.TTL.Details)
+	assert.Equal(t, 7*24*time.Hour, cfg.TMDB.Cache.TTL.Configuration)
+	assert.Equal(t, time.Hour, cfg.TMDB.Cache.TTL.Trending)
+	assert.Equal(t, 15*time.Minute, cfg.TMDB.Cache.TTL.Search)
+	assert.False(t, cfg.TMDB.Cache.Disk.Enabled)
+	assert.Equal(t, filepath.Join(tempDir, ".tmdb-mcp", "cache"), cfg.TMDB.Cache.Disk.Path)
+	assert.Equal(t, 100, cfg.TMDB.Cache.Disk.MaxSizeMB)
+	assert.Equal(t, 25*time.Second, cfg.Server.ShutdownTimeout)
+	assert.False(t, cfg.Server.SSE.TLS.Enabled)
+	assert.Equal(t, "1.2", cfg.Server.SSE.TLS.MinVersion)
+	assert.Equal(t, "require", cfg.Server.SSE.TLS.ClientAuth)
+	assert.False(t, cfg.Server.StopOnStdioClose)
+	assert.False(t, cfg.Tracing.Enabled)
+	assert.Equal(t, "http", cfg.Tracing.Protocol)
+	assert.Equal(t, "tmdb-mcp", cfg.Tracing.ServiceName)
+	assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
 }
 
 func TestLoad_EnvironmentVariables(t *testing.T) {
@@ -183,6 +533,8 @@ func TestLoad_EnvironmentVariables(t *testing.T) {
 		"SERVER_SSE_HOST":    "127.0.0.1",
 		"SERVER_SSE_PORT":    "9000",
 		"SERVER_SSE_ENABLED": "true",
+		"TOOLS_DISABLED":     "get_trending,discover_tv",
+		"TMDB_BASE_URL":      "http://127.0.0.1:8787/3",
 	}
 
 	for k, v := range testEnvVars {
@@ -202,6 +554,8 @@ func TestLoad_EnvironmentVariables(t *testing.T) {
 	assert.Equal(t, "sse", cfg.Server.Mode)
 	assert.Equal(t, "127.0.0.1", cfg.Server.SSE.Host)
 	assert.Equal(t, 9000, cfg.Server.SSE.Port)
+	assert.Equal(t, []string{"get_trending", "discover_tv"}, cfg.Tools.Disabled)
+	assert.Equal(t, "http://127.0.0.1:8787/3", cfg.TMDB.BaseURL)
 }
 
 func TestLoad_ConfigFile(t *testing.T) {
@@ -304,6 +658,153 @@ server:
 	assert.Equal(t, 40, cfg.TMDB.RateLimit)
 }
 
+// TestLoad_APIKeys tests loading the key pool from entries, plain strings and TMDB_API_KEYS
+func TestLoad_APIKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	configDir := filepath.Join(tempDir, ".tmdb-mcp")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+
+	configContent := `
+tmdb:
+  key_selection: least_loaded
+  api_keys:
+    - name: batch
+      key: "batch_key"
+      rate_limit: 10
+    - "interactive_key"
+server:
+  mode: "stdio"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(configContent), 0644))
+	t.Setenv("HOME", tempDir)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, []APIKeyConfig{
+		{Name: "batch", Key: "batch_key", RateLimit: 10},
+		{Name: "key2", Key: "interactive_key"},
+	}, cfg.TMDB.APIKeys)
+	assert.Equal(t, KeySelectionLeastLoaded, cfg.TMDB.KeySelection)
+	require.NoError(t, cfg.Validate())
+
+	t.Setenv("TMDB_API_KEYS", "first,second")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, []APIKeyConfig{{Name: "key1", Key: "first"}, {Name: "key2", Key: "second"}}, cfg.TMDB.APIKeys)
+}
+
+// TestRead_NoSideEffects tests that Read neither creates the config directory nor saves a generated token
+func TestRead_NoSideEffects(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("TMDB_CACHE_DISK_PATH", "")
+	t.Setenv("SERVER_MODE", "sse")
+
+	cfg, err := Read()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tempDir, ".tmdb-mcp", "cache"), cfg.TMDB.Cache.Disk.Path)
+	assert.Empty(t, cfg.Server.SSE.Token)
+	assert.NoDirExists(t, filepath.Join(tempDir, ".tmdb-mcp"))
+
+	// 配置文件存在时也不改写
+	configDir := filepath.Join(tempDir, ".tmdb-mcp")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	configFile := filepath.Join(configDir, "config.yaml")
+	content := "tmdb:\n  cache:\n    disk:\n      path: /var/cache/tmdb\nserver:\n  mode: sse\n"
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0644))
+
+	cfg, err = Read()
+	require.NoError(t, err)
+	assert.Equal(t, "/var/cache/tmdb", cfg.TMDB.Cache.Disk.Path)
+	data, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+// TestLoad_Tokens tests loading named tokens, including YAML dates and quoted timestamps
+func TestLoad_Tokens(t *testing.T) {
+	tempDir := t.TempDir()
+	configDir := filepath.Join(tempDir, ".tmdb-mcp")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+
+	configContent := `
+tmdb:
+  api_key: "file_api_key"
+server:
+  mode: "sse"
+  sse:
+    tokens:
+      - name: alice
+        hash: "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+        expires_at: 2026-12-31
+        allowed_tools: [search, get_details]
+        rate_limit: 30
+      - name: ci
+        hash: "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25c"
+        expires_at: "2026-06-30T12:00:00+08:00"
+      - name: bob
+        hash: "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25d"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(configContent), 0644))
+
+	originalHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", originalHome)
+	os.Setenv("HOME", tempDir)
+	os.Unsetenv("SSE_TOKEN")
+	os.Unsetenv("SERVER_MODE")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	// 已配置具名令牌时不自动生成单一令牌
+	assert.False(t, cfg.TokenGenerated)
+	assert.Empty(t, cfg.Server.SSE.Token)
+
+	tokens := cfg.Server.SSE.Tokens
+	require.Len(t, tokens, 3)
+	assert.Equal(t, "alice", tokens[0].Name)
+	assert.True(t, tokens[0].ExpiresAt.Equal(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"search", "get_details"}, tokens[0].AllowedTools)
+	assert.Equal(t, 30, tokens[0].RateLimit)
+	assert.True(t, tokens[1].ExpiresAt.Equal(time.Date(2026, 6, 30, 4, 0, 0, 0, time.UTC)))
+	assert.True(t, tokens[2].ExpiresAt.IsZero())
+}
+
+// TestOAuthConfig_Expected tests the issuer and audience defaults
+func TestOAuthConfig_Expected(t *testing.T) {
+	cfg := OAuthConfig{Resource: "https://mcp.example.com", AuthorizationServers: []string{"https://auth.example.com"}}
+	assert.Equal(t, "https://auth.example.com", cfg.ExpectedIssuer())
+	assert.Equal(t, []string{"https://mcp.example.com"}, cfg.ExpectedAudience())
+
+	cfg.Issuer = "https://auth.example.com/realms/mcp"
+	cfg.Audience = []string{"tmdb-mcp"}
+	assert.Equal(t, "https://auth.example.com/realms/mcp", cfg.ExpectedIssuer())
+	assert.Equal(t, []string{"tmdb-mcp"}, cfg.ExpectedAudience())
+}
+
+// TestToolsConfig_IsToolEnabled tests tool enablement rules
+func TestToolsConfig_IsToolEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      ToolsConfig
+		tool     string
+		expected bool
+	}{
+		{"empty lists enable all", ToolsConfig{}, "get_trending", true},
+		{"disabled tool", ToolsConfig{Disabled: []string{"get_trending"}}, "get_trending", false},
+		{"other tool not disabled", ToolsConfig{Disabled: []string{"get_trending"}}, "search", true},
+		{"enabled tool", ToolsConfig{Enabled: []string{"search", "get_details"}}, "search", true},
+		{"not in enabled list", ToolsConfig{Enabled: []string{"search"}}, "get_trending", false},
+		{"disabled wins over enabled", ToolsConfig{Enabled: []string{"search"}, Disabled: []string{"search"}}, "search", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.cfg.IsToolEnabled(tt.tool))
+		})
+	}
+}
+
 func TestEnsureConfigDir(t *testing.T) {
 	// 创建临时目录
 	tempDir := t.TempDir()
@@ -2,8 +2,10 @@ package config
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 )
 
 // GenerateSSEToken generates a cryptographically secure random token
@@ -34,3 +36,24 @@ func ValidateToken(token string) error {
 
 	return nil
 }
+
+// HashToken returns the hash stored in server.sse.tokens for a token secret
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return TokenHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// ValidateTokenHash validates a hash produced by HashToken
+func ValidateTokenHash(hash string) error {
+	digest, ok := strings.CutPrefix(hash, TokenHashPrefix)
+	if !ok {
+		return fmt.Errorf("must start with %q", TokenHashPrefix)
+	}
+	if len(digest) != sha256.Size*2 {
+		return fmt.Errorf("invalid hash length: expected %d hexadecimal characters, got %d", sha256.Size*2, len(digest))
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return fmt.Errorf("invalid hash format: must be valid hexadecimal string")
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -119,3 +120,26 @@ func TestGenerateAndValidateToken(t *testing.T) {
 	err = ValidateToken(token)
 	assert.NoError(t, err, "generated token should pass validation")
 }
+
+// TestHashToken tests that token hashes are stable and pass validation
+func TestHashToken(t *testing.T) {
+	hash := HashToken("secret")
+	assert.Equal(t, "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", hash)
+	assert.NoError(t, ValidateTokenHash(hash))
+
+	assert.Error(t, ValidateTokenHash("2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"), "missing prefix")
+	assert.Error(t, ValidateTokenHash("sha256:2bb80d"), "short digest")
+	assert.Error(t, ValidateTokenHash("sha256:"+strings.Repeat("z", 64)), "not hexadecimal")
+}
+
+// TestValidateTokens tests validation of named tokens
+func TestValidateTokens(t *testing.T) {
+	valid := TokenConfig{Name: "alice", Hash: HashToken("a")}
+
+	assert.NoError(t, validateTokens([]TokenConfig{valid, {Name: "bob", Hash: HashToken("b"), RateLimit: 30}}, ""))
+	assert.ErrorContains(t, validateTokens([]TokenConfig{{Hash: HashToken("a")}}, ""), "name is required")
+	assert.ErrorContains(t, validateTokens([]TokenConfig{valid, valid}, ""), "duplicate name")
+	assert.ErrorContains(t, validateTokens([]TokenConfig{{Name: "alice", Hash: "plaintext"}}, ""), "hash")
+	assert.ErrorContains(t, validateTokens([]TokenConfig{{Name: "alice", Hash: HashToken("a"), RateLimit: -1}}, ""), "rate_limit")
+	assert.ErrorContains(t, validateTokens([]TokenConfig{{Name: "default", Hash: HashToken("a")}}, "legacy"), "reserved")
+}
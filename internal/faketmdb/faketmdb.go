@@ -0,0 +1,412 @@
+// Package faketmdb implements a fake TMDB API v3 server for offline testing.
+// It serves realistic fixture JSON for every endpoint used by the tmdb client
+// and can be scripted to fail (401, 404, 429 with Retry-After, 5xx) or to
+// respond slowly, so the full tool suite can run without a real API key.
+package faketmdb
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// ControlPrefix is the path prefix of the endpoints used to script the server at runtime
+//
+//	GET    /_faketmdb/rules     列出当前规则
+//	POST   /_faketmdb/rules     添加规则（每行一条，格式见 ParseRule）
+//	DELETE /_faketmdb/rules     清除所有规则
+//	GET    /_faketmdb/requests  按路径统计的请求次数
+const ControlPrefix = "/_faketmdb/"
+
+// apiPrefix is the optional version prefix of the TMDB API (base URL ".../3")
+const apiPrefix = "/3"
+
+// Server is a fake TMDB API server
+// It is safe for concurrent use
+type Server struct {
+	apiKey string // 非空时校验 api_key 查询参数或 Bearer token
+
+	mu       sync.Mutex
+	rules    []*rule
+	requests map[string]int
+}
+
+// rule is a scripted Rule with its remaining match count
+type rule struct {
+	Rule
+	remaining int // Times 为 0 时不限次数
+}
+
+// New creates a fake TMDB server
+// If apiKey is not empty, requests with a different api_key (or Bearer token,
+// as sent with a v4 read access token) get a 401 like the real API
+func New(apiKey string) *Server {
+	return &Server{
+		apiKey:   apiKey,
+		requests: make(map[string]int),
+	}
+}
+
+// Script adds failure rules; rules are matched in the order they were added
+func (s *Server) Script(rules ...Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range rules {
+		s.rules = append(s.rules, &rule{Rule: r, remaining: r.Times})
+	}
+}
+
+// Reset removes all rules and request counts
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = nil
+	s.requests = make(map[string]int)
+}
+
+// Requests returns the number of API requests whose path matches pattern (see path.Match)
+func (s *Server) Requests(pattern string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for p, n := range s.requests {
+		if ok, _ := path.Match(pattern, p); ok {
+			total += n
+		}
+	}
+	return total
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, ControlPrefix) {
+		s.serveControl(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/t/p/") {
+		serveImage(w, r)
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	matched := s.record(p)
+
+	if matched != nil {
+		if matched.Delay > 0 {
+			select {
+			case <-time.After(matched.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if matched.Status >= http.StatusBadRequest {
+			if matched.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(matched.RetryAfter))
+			}
+			writeError(w, matched.Status)
+			return
+		}
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if s.apiKey != "" && r.URL.Query().Get("api_key") != s.apiKey && r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	s.serveAPI(w, r, p)
+}
+
+// record counts the request and returns the first rule that applies to it
+func (s *Server) record(p string) *Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[p]++
+	for _, r := range s.rules {
+		if r.Times > 0 && r.remaining == 0 {
+			continue
+		}
+		if r.Path != "" && r.Path != "*" {
+			if ok, _ := path.Match(r.Path, p); !ok {
+				continue
+			}
+		}
+		if r.Times > 0 {
+			r.remaining--
+		}
+		matched := r.Rule
+		return &matched
+	}
+	return nil
+}
+
+// serveAPI routes a TMDB API request to its fixture
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request, p string) {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	query := r.URL.Query()
+
+	switch {
+	case p == "/configuration":
+		serveConfiguration(w, r)
+	case p == "/search/multi":
+		serveList(w, "search_multi.json", func(item map[string]any) bool {
+			return matchesQuery(item, query.Get("query"))
+		})
+	case len(segments) == 2 && segments[0] == "discover" && (segments[1] == "movie" || segments[1] == "tv"):
+		serveList(w, "discover_"+segments[1]+".json", func(item map[string]any) bool {
+			return hasGenres(item, query.Get("with_genres"))
+		})
+	case len(segments) == 3 && segments[0] == "trending":
+		mediaType, window := segments[1], segments[2]
+		if (mediaType != "all" && mediaType != "movie" && mediaType != "tv" && mediaType != "person") ||
+			(window != "day" && window != "week") {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		serveList(w, "trending.json", func(item map[string]any) bool {
+			return mediaType == "all" || item["media_type"] == mediaType
+		})
+	case len(segments) == 2 && (segments[0] == "movie" || segments[0] == "tv" || segments[0] == "person") && isID(segments[1]):
+		serveFixture(w, segments[0]+"/"+segments[1]+".json")
+	case len(segments) == 3 && (segments[0] == "movie" || segments[0] == "tv") && isID(segments[1]) && segments[2] == "recommendations":
+		serveRecommendations(w, segments[0], segments[1])
+	default:
+		writeError(w, http.StatusNotFound)
+	}
+}
+
+// isID reports whether s is a positive numeric TMDB ID
+func isID(s string) bool {
+	id, err := strconv.Atoi(s)
+	return err == nil && id > 0
+}
+
+// serveFixture writes a fixture file, or a 404 if it does not exist
+func serveFixture(w http.ResponseWriter, name string) {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, json.RawMessage(data))
+}
+
+// page is a paginated TMDB list response
+type page struct {
+	Page         int              `json:"page"`
+	Results      []map[string]any `json:"results"`
+	TotalPages   int              `json:"total_pages"`
+	TotalResults int              `json:"total_results"`
+}
+
+// serveList writes a paginated fixture keeping only the results accepted by keep
+func serveList(w http.ResponseWriter, name string, keep func(map[string]any) bool) {
+	var list page
+	if err := readFixture(name, &list); err != nil {
+		writeError(w, http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]map[string]any, 0, len(list.Results))
+	for _, item := range list.Results {
+		if keep(item) {
+			results = append(results, item)
+		}
+	}
+	list.Results = results
+	list.TotalResults = len(results)
+	list.TotalPages = 1
+	if len(results) == 0 {
+		list.TotalPages = 0
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// serveRecommendations writes recommendations for a known title (empty if it has no fixture)
+func serveRecommendations(w http.ResponseWriter, mediaType, id string) {
+	if _, err := fixtures.ReadFile("fixtures/" + mediaType + "/" + id + ".json"); err != nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	name := mediaType + "/" + id + "_recommendations.json"
+	if _, err := fixtures.ReadFile("fixtures/" + name); err != nil {
+		writeJSON(w, http.StatusOK, page{Page: 1, Results: []map[string]any{}})
+		return
+	}
+	serveFixture(w, name)
+}
+
+// serveConfiguration writes /configuration with image URLs pointing at this server
+func serveConfiguration(w http.ResponseWriter, r *http.Request) {
+	var cfg map[string]any
+	if err := readFixture("configuration.json", &cfg); err != nil {
+		writeError(w, http.StatusInternalServerError)
+		return
+	}
+	if images, ok := cfg["images"].(map[string]any); ok {
+		base := "http://" + r.Host + "/t/p/"
+		images["base_url"] = base
+		images["secure_base_url"] = base
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// placeholderImage is a 1x1 PNG served for every image path
+var placeholderImage = func() []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	return buf.Bytes()
+}()
+
+// serveImage writes the placeholder image for /t/p/{size}/{file}
+func serveImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(placeholderImage)
+}
+
+// matchesQuery reports whether a search result's title or name contains query (case-insensitive)
+func matchesQuery(item map[string]any, query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return false
+	}
+	for _, key := range []string{"title", "name", "original_title", "original_name"} {
+		if value, ok := item[key].(string); ok && strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGenres reports whether a discover result has all comma-separated genre IDs in withGenres
+func hasGenres(item map[string]any, withGenres string) bool {
+	if withGenres == "" {
+		return true
+	}
+	ids, _ := item["genre_ids"].([]any)
+	for _, want := range strings.Split(withGenres, ",") {
+		found := false
+		for _, id := range ids {
+			if fmt.Sprint(id) == strings.TrimSpace(want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// readFixture decodes a fixture file into v
+func readFixture(name string, v any) error {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// tmdbErrors are the error bodies returned by the real API for common statuses
+var tmdbErrors = map[int]struct {
+	code    int
+	message string
+}{
+	http.StatusUnauthorized:        {7, "Invalid API key: You must be granted a valid key."},
+	http.StatusNotFound:            {34, "The resource you requested could not be found."},
+	http.StatusTooManyRequests:     {25, "Your request count (#) is over the allowed limit of (40)."},
+	http.StatusInternalServerError: {11, "Internal error: Something went wrong, contact TMDB."},
+	http.StatusServiceUnavailable:  {9, "Service offline: This service is temporarily offline, try again later."},
+	http.StatusGatewayTimeout:      {24, "Your request to the backend server timed out. Try again."},
+}
+
+// writeError writes a TMDB-style error response
+func writeError(w http.ResponseWriter, status int) {
+	body := map[string]any{
+		"success":        false,
+		"status_code":    status,
+		"status_message": http.StatusText(status),
+	}
+	if e, ok := tmdbErrors[status]; ok {
+		body["status_code"] = e.code
+		body["status_message"] = e.message
+	}
+	writeJSON(w, status, body)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// serveControl handles the /_faketmdb/ scripting endpoints
+func (s *Server) serveControl(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, ControlPrefix) {
+	case "rules":
+		switch r.Method {
+		case http.MethodGet:
+			s.mu.Lock()
+			specs := make([]string, 0, len(s.rules))
+			for _, rule := range s.rules {
+				specs = append(specs, rule.String())
+			}
+			s.mu.Unlock()
+			writeJSON(w, http.StatusOK, specs)
+		case http.MethodPost:
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var rules []Rule
+			for _, line := range strings.Split(string(body), "\n") {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				rule, err := ParseRule(line)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				rules = append(rules, rule)
+			}
+			s.Script(rules...)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			s.Reset()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	case "requests":
+		s.mu.Lock()
+		counts := make(map[string]int, len(s.requests))
+		for p, n := range s.requests {
+			counts[p] = n
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, counts)
+	default:
+		http.NotFound(w, r)
+	}
+}
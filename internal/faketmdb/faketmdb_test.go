@@ -0,0 +1,139 @@
+package faketmdb
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// get performs a GET request against the fake server and decodes the JSON body
+func get(t *testing.T, server *httptest.Server, path string) (*http.Response, map[string]any) {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+	return resp, body
+}
+
+// TestParseRule tests parsing and formatting failure rules
+func TestParseRule(t *testing.T) {
+	r, err := ParseRule("/movie/*=429,retry_after=2,delay=150ms,times=3")
+	require.NoError(t, err)
+	assert.Equal(t, Rule{Path: "/movie/*", Status: 429, RetryAfter: 2, Delay: 150 * time.Millisecond, Times: 3}, r)
+	assert.Equal(t, "/movie/*=429,retry_after=2,delay=150ms,times=3", r.String())
+
+	for _, spec := range []string{"/movie/*", "/movie/*=abc", "/movie/*=500,times", "/movie/*=500,color=red"} {
+		_, err := ParseRule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// TestServer_Fixtures tests the fixture endpoints with and without the /3 prefix
+func TestServer_Fixtures(t *testing.T) {
+	server := httptest.NewServer(New("key"))
+	defer server.Close()
+
+	resp, body := get(t, server, "/3/movie/27205?api_key=key")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Inception", body["title"])
+
+	resp, body = get(t, server, "/search/multi?api_key=key&query=the+office")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, float64(2), body["total_results"])
+
+	_, body = get(t, server, "/3/trending/person/day?api_key=key")
+	assert.Len(t, body["results"], 1)
+
+	_, body = get(t, server, "/3/tv/2316/recommendations?api_key=key")
+	assert.Empty(t, body["results"])
+
+	_, body = get(t, server, "/3/configuration?api_key=key")
+	images := body["images"].(map[string]any)
+	assert.Equal(t, server.URL+"/t/p/", images["secure_base_url"])
+
+	resp, _ = get(t, server, "/t/p/w500/poster.jpg")
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+
+	resp, body = get(t, server, "/3/movie/1?api_key=key")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, float64(34), body["status_code"])
+
+	resp, body = get(t, server, "/3/movie/27205?api_key=wrong")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, float64(7), body["status_code"])
+
+	// v4 read access token 通过 Authorization 请求头发送
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/3/movie/27205", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer key")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// TestServer_ScriptedFailures tests status, Retry-After, times and delay rules
+func TestServer_ScriptedFailures(t *testing.T) {
+	fake := New("")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	fake.Script(
+		Rule{Path: "/movie/*", Status: 429, RetryAfter: 2, Times: 1},
+		Rule{Path: "/tv/*", Status: 200, Delay: 100 * time.Millisecond},
+	)
+
+	resp, _ := get(t, server, "/3/movie/27205")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+
+	resp, _ = get(t, server, "/3/movie/27205")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "rule applies only once")
+
+	start := time.Now()
+	resp, _ = get(t, server, "/3/tv/1396")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	assert.Equal(t, 2, fake.Requests("/movie/*"))
+	assert.Equal(t, 3, fake.Requests("/*/*"))
+}
+
+// TestServer_ControlEndpoints tests scripting the server over HTTP
+func TestServer_ControlEndpoints(t *testing.T) {
+	fake := New("")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+ControlPrefix+"rules", "text/plain", strings.NewReader("*=503,times=1\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = get(t, server, "/3/search/multi?query=inception")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + ControlPrefix + "requests")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.JSONEq(t, `{"/search/multi": 1}`, string(data))
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+ControlPrefix+"rules", nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Zero(t, fake.Requests("/search/multi"))
+}
@@ -0,0 +1,88 @@
+package faketmdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule scripts how the server answers matching requests
+type Rule struct {
+	// Path is a path.Match pattern relative to the API root, e.g. "/movie/*";
+	// an empty pattern or "*" matches every request
+	Path string
+
+	// Status is returned instead of the fixture when it is an error status (>= 400);
+	// use 0 or 200 together with Delay to only slow responses down
+	Status int
+
+	// RetryAfter is sent as the Retry-After header in seconds (typically with 429)
+	RetryAfter int
+
+	// Delay is waited before responding
+	Delay time.Duration
+
+	// Times limits how many requests the rule applies to; 0 means every request
+	Times int
+}
+
+// ParseRule parses a rule spec of the form
+//
+//	PATH=STATUS[,retry_after=SECONDS][,delay=DURATION][,times=N]
+//
+// for example "/movie/*=429,retry_after=2,times=1" or "*=200,delay=3s"
+func ParseRule(spec string) (Rule, error) {
+	spec = strings.TrimSpace(spec)
+	pattern, rest, ok := strings.Cut(spec, "=")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid rule %q: expected PATH=STATUS[,option=value...]", spec)
+	}
+
+	options := strings.Split(rest, ",")
+	status, err := strconv.Atoi(strings.TrimSpace(options[0]))
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid rule %q: status %q is not a number", spec, options[0])
+	}
+
+	r := Rule{Path: strings.TrimSpace(pattern), Status: status}
+	for _, option := range options[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid rule %q: option %q must be key=value", spec, option)
+		}
+		switch key {
+		case "retry_after":
+			r.RetryAfter, err = strconv.Atoi(value)
+		case "delay":
+			r.Delay, err = time.ParseDuration(value)
+		case "times":
+			r.Times, err = strconv.Atoi(value)
+		default:
+			return Rule{}, fmt.Errorf("invalid rule %q: unknown option %q", spec, key)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid rule %q: bad %s: %w", spec, key, err)
+		}
+	}
+	return r, nil
+}
+
+// String formats the rule in the ParseRule syntax
+func (r Rule) String() string {
+	pattern := r.Path
+	if pattern == "" {
+		pattern = "*"
+	}
+	s := fmt.Sprintf("%s=%d", pattern, r.Status)
+	if r.RetryAfter > 0 {
+		s += fmt.Sprintf(",retry_after=%d", r.RetryAfter)
+	}
+	if r.Delay > 0 {
+		s += ",delay=" + r.Delay.String()
+	}
+	if r.Times > 0 {
+		s += fmt.Sprintf(",times=%d", r.Times)
+	}
+	return s
+}
@@ -0,0 +1,245 @@
+// Package lifecycle coordinates graceful shutdown of the server.
+// On SIGINT/SIGTERM (or when a transport asks to stop) the Manager stops admitting
+// new tool calls, waits for in-flight calls to finish within a deadline (aborting
+// them when it passes), then runs the registered shutdown hooks in reverse order:
+// closing MCP sessions and HTTP servers, closing caches and flushing telemetry and logs.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrShuttingDown is returned by StartCall once shutdown has begun
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// ErrDrainTimeout is returned by Wait when in-flight calls had to be aborted
+var ErrDrainTimeout = errors.New("shutdown deadline exceeded, in-flight calls were aborted")
+
+// hookTimeout bounds each shutdown hook
+const hookTimeout = 5 * time.Second
+
+// hook is a named shutdown step
+type hook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Manager tracks in-flight tool calls and runs shutdown hooks
+type Manager struct {
+	logger  *zap.Logger
+	timeout time.Duration // 等待进行中调用完成的最长时间
+
+	ctx    context.Context // 开始关闭时取消
+	cancel context.CancelFunc
+	abort  context.Context // 超过期限（或收到第二个信号）时取消，中止进行中的调用
+	abortC context.CancelFunc
+
+	mu     sync.Mutex
+	reason string
+	err    error // 导致关闭的错误（如 HTTP 监听失败）
+	active int
+	idle   chan struct{} // active 降为 0 时关闭
+	hooks  []hook
+}
+
+// NewManager creates a manager that waits up to timeout for in-flight calls on shutdown
+func NewManager(timeout time.Duration, logger *zap.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	abort, abortC := context.WithCancel(context.Background())
+	return &Manager{
+		logger:  logger,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		abort:   abort,
+		abortC:  abortC,
+	}
+}
+
+// Done returns a channel closed when shutdown begins
+func (m *Manager) Done() <-chan struct{} {
+	return m.ctx.Done()
+}
+
+// Draining reports whether shutdown has begun
+func (m *Manager) Draining() bool {
+	return m.ctx.Err() != nil
+}
+
+// OnShutdown registers a hook run after in-flight calls are drained
+// Hooks run in reverse registration order, like defer
+func (m *Manager) OnShutdown(name string, fn func(context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Shutdown begins shutdown; later calls are ignored
+func (m *Manager) Shutdown(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx.Err() != nil {
+		return
+	}
+	m.reason = reason
+	m.cancel()
+}
+
+// Fail begins shutdown because of err, which Wait then returns
+func (m *Manager) Fail(reason string, err error) {
+	m.mu.Lock()
+	if m.err == nil && m.ctx.Err() == nil {
+		m.err = err
+	}
+	m.mu.Unlock()
+	m.Shutdown(reason)
+}
+
+// HandleSignals begins shutdown on the first SIGINT/SIGTERM and aborts
+// in-flight calls immediately on the second
+func (m *Manager) HandleSignals() {
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		m.Shutdown("received " + sig.String())
+		sig = <-sigCh
+		m.logger.Warn("Received second signal, aborting in-flight calls", zap.String("signal", sig.String()))
+		m.abortC()
+	}()
+}
+
+// StartCall admits a tool call, returning a context that is cancelled if the call
+// is still running when the shutdown deadline passes, and a function to call when
+// it completes; it returns ErrShuttingDown once shutdown has begun
+func (m *Manager) StartCall(ctx context.Context) (context.Context, func(), error) {
+	m.mu.Lock()
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		return ctx, func() {}, ErrShuttingDown
+	}
+	m.active++
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(m.abort, cancel)
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			stop()
+			cancel()
+			m.mu.Lock()
+			m.active--
+			if m.active == 0 && m.idle != nil {
+				close(m.idle)
+				m.idle = nil
+			}
+			m.mu.Unlock()
+		})
+	}, nil
+}
+
+// Active returns the number of in-flight calls
+func (m *Manager) Active() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// Wait blocks until shutdown begins, drains in-flight calls and runs the hooks
+// It returns the error passed to Fail, ErrDrainTimeout when calls were aborted,
+// or nil for a clean shutdown; hook errors are logged
+func (m *Manager) Wait() error {
+	<-m.ctx.Done()
+
+	m.mu.Lock()
+	reason, failure := m.reason, m.err
+	m.mu.Unlock()
+	m.logger.Info("Shutting down",
+		zap.String("reason", reason),
+		zap.Int("in_flight_calls", m.Active()),
+		zap.Duration("timeout", m.timeout),
+	)
+
+	drained := m.drain()
+
+	// 按注册的相反顺序执行
+	m.mu.Lock()
+	hooks := m.hooks
+	m.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		if err := hooks[i].fn(ctx); err != nil {
+			m.logger.Warn("Shutdown step failed", zap.String("step", hooks[i].name), zap.Error(err))
+		}
+		cancel()
+	}
+
+	if failure != nil {
+		return failure
+	}
+	if !drained {
+		return ErrDrainTimeout
+	}
+	return nil
+}
+
+// drain waits for in-flight calls to complete, aborting them at the deadline
+// It reports whether all calls completed on their own
+func (m *Manager) drain() bool {
+	timer := time.NewTimer(m.timeout)
+	defer timer.Stop()
+
+	for {
+		m.mu.Lock()
+		if m.active == 0 {
+			m.mu.Unlock()
+			return m.abort.Err() == nil
+		}
+		if m.idle == nil {
+			m.idle = make(chan struct{})
+		}
+		idle := m.idle
+		m.mu.Unlock()
+
+		select {
+		case <-idle:
+		case <-timer.C:
+			m.logger.Warn("Shutdown deadline exceeded, aborting in-flight calls", zap.Int("in_flight_calls", m.Active()))
+			m.abortC()
+			// 被中止的调用会很快返回，给它们发送错误响应的机会
+			m.waitIdle(hookTimeout)
+			return false
+		case <-m.abort.Done():
+			m.waitIdle(hookTimeout)
+			return false
+		}
+	}
+}
+
+// waitIdle waits up to timeout for the in-flight calls to return
+func (m *Manager) waitIdle(timeout time.Duration) {
+	m.mu.Lock()
+	if m.active == 0 {
+		m.mu.Unlock()
+		return
+	}
+	if m.idle == nil {
+		m.idle = make(chan struct{})
+	}
+	idle := m.idle
+	m.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,80 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestManager_DrainsThenRunsHooks tests that hooks run in reverse order after in-flight calls finish
+func TestManager_DrainsThenRunsHooks(t *testing.T) {
+	m := NewManager(time.Second, zap.NewNop())
+
+	var order []string
+	m.OnShutdown("first", func(context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	m.OnShutdown("second", func(context.Context) error {
+		order = append(order, "second")
+		return errors.New("ignored")
+	})
+
+	_, done, err := m.StartCall(context.Background())
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		order = append(order, "call")
+		done()
+	}()
+
+	m.Shutdown("test")
+	require.NoError(t, m.Wait())
+	assert.Equal(t, []string{"call", "second", "first"}, order)
+}
+
+// TestManager_RefusesCallsWhenDraining tests that new calls are refused after shutdown begins
+func TestManager_RefusesCallsWhenDraining(t *testing.T) {
+	m := NewManager(time.Second, zap.NewNop())
+	assert.False(t, m.Draining())
+
+	m.Shutdown("test")
+
+	assert.True(t, m.Draining())
+	_, _, err := m.StartCall(context.Background())
+	assert.ErrorIs(t, err, ErrShuttingDown)
+	assert.Zero(t, m.Active())
+}
+
+// TestManager_AbortsCallsAfterTimeout tests that calls still running at the deadline are cancelled
+func TestManager_AbortsCallsAfterTimeout(t *testing.T) {
+	m := NewManager(50*time.Millisecond, zap.NewNop())
+
+	ctx, done, err := m.StartCall(context.Background())
+	require.NoError(t, err)
+	go func() {
+		<-ctx.Done()
+		done()
+	}()
+
+	m.Shutdown("test")
+	assert.ErrorIs(t, m.Wait(), ErrDrainTimeout)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Zero(t, m.Active())
+}
+
+// TestManager_Fail tests that the failure passed to Fail is returned by Wait
+func TestManager_Fail(t *testing.T) {
+	m := NewManager(time.Second, zap.NewNop())
+	failure := errors.New("listen failed")
+
+	m.Fail("test", failure)
+	m.Fail("later", errors.New("ignored"))
+
+	assert.ErrorIs(t, m.Wait(), failure)
+}
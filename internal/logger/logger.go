@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"net/http"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -9,6 +10,10 @@ import (
 	"github.com/XDwanj/tmdb-mcp/internal/config"
 )
 
+// atomicLevel is the process-wide log level shared by all loggers built by InitLogger
+// 使用 AtomicLevel 以便在运行时调整日志级别，无需重启进程
+var atomicLevel = zap.NewAtomicLevel()
+
 // InitLogger initializes and returns a configured zap logger
 func InitLogger(cfg config.LogConfig) (*zap.Logger, error) {
 	// Parse log level from config
@@ -16,20 +21,23 @@ func InitLogger(cfg config.LogConfig) (*zap.Logger, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse log level: %w", err)
 	}
+	atomicLevel.SetLevel(level)
 
 	// Use development mode for debug level, production mode for others
 	var logger *zap.Logger
 	zap.AddCaller()
 	if cfg.Level == "debug" {
 		// Development mode: console output with color
-		logger, err = zap.NewDevelopment()
+		config := zap.NewDevelopmentConfig()
+		config.Level = atomicLevel
+		logger, err = config.Build()
 		if err != nil {
 			return nil, fmt.Errorf("failed to create development logger: %w", err)
 		}
 	} else {
 		// Production mode: JSON output
 		config := zap.NewProductionConfig()
-		config.Level = zap.NewAtomicLevelAt(level)
+		config.Level = atomicLevel
 		logger, err = config.Build()
 		if err != nil {
 			return nil, fmt.Errorf("failed to create production logger: %w", err)
@@ -39,6 +47,27 @@ func InitLogger(cfg config.LogConfig) (*zap.Logger, error) {
 	return logger, nil
 }
 
+// SetLevel changes the process-wide log level at runtime
+func SetLevel(level string) error {
+	parsed, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(parsed)
+	return nil
+}
+
+// GetLevel returns the current process-wide log level
+func GetLevel() string {
+	return atomicLevel.Level().String()
+}
+
+// LevelHandler returns an HTTP handler that reports (GET) and changes (PUT) the
+// process-wide log level, e.g. PUT {"level":"debug"}
+func LevelHandler() http.Handler {
+	return atomicLevel
+}
+
 // parseLogLevel converts a string log level to zapcore.Level
 // Supports case-insensitive input (e.g., "DEBUG", "Info", "warn")
 func parseLogLevel(level string) (zapcore.Level, error) {
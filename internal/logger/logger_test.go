@@ -278,3 +278,20 @@ func TestMaskToken_DoesNotLeakSensitiveData(t *testing.T) {
 	// Ensure it only shows first 8 chars + "..."
 	assert.Equal(t, "mysecret...", masked)
 }
+
+// TestSetLevel tests changing the process-wide log level at runtime
+func TestSetLevel(t *testing.T) {
+	logger, err := InitLogger(config.LogConfig{Level: "info"})
+	assert.NoError(t, err)
+	assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	assert.NoError(t, SetLevel("DEBUG"))
+	assert.Equal(t, "debug", GetLevel())
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	assert.Error(t, SetLevel("verbose"))
+	assert.Equal(t, "debug", GetLevel())
+
+	assert.NoError(t, SetLevel("info"))
+	assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+}
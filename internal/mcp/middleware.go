@@ -2,10 +2,23 @@ package mcp
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+	"github.com/XDwanj/tmdb-mcp/internal/progress"
+	"github.com/XDwanj/tmdb-mcp/internal/ratelimit"
+	"github.com/XDwanj/tmdb-mcp/internal/sessionlog"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/XDwanj/tmdb-mcp/internal/tracing"
 )
 
 // LoggingMiddleware creates a middleware that logs all MCP method calls
@@ -15,6 +28,8 @@ import (
 // - Execution duration
 // - Success/failure status
 // - Result information (for CallToolResult)
+// Each call is also a server span (continuing the client's trace when _meta
+// carries traceparent), the parent of all spans created while handling it
 func LoggingMiddleware(logger *zap.Logger) mcp.Middleware {
 	return func(next mcp.MethodHandler) mcp.MethodHandler {
 		return func(
@@ -28,20 +43,46 @@ func LoggingMiddleware(logger *zap.Logger) mcp.Middleware {
 				zap.String("session_id", req.GetSession().ID()),
 				zap.Bool("has_params", req.GetParams() != nil))
 
-			// 2. For CallToolRequest, log additional tool information
+			// 2. Start the span, honoring trace context from the client's _meta
+			ctx = tracing.ExtractMeta(ctx, requestMeta(req))
+			spanName := "MCP " + method
+			attrs := []attribute.KeyValue{
+				tracing.AttrMCPMethod.String(method),
+				tracing.AttrMCPSession.String(req.GetSession().ID()),
+			}
+			if ctr, ok := req.(*mcp.CallToolRequest); ok {
+				spanName += " " + ctr.Params.Name
+				attrs = append(attrs, tracing.AttrMCPTool.String(ctr.Params.Name))
+			}
+			ctx, span := tracing.Tracer().Start(ctx, spanName,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			// 3. For CallToolRequest, log additional tool information
 			if ctr, ok := req.(*mcp.CallToolRequest); ok {
 				logger.Info("Calling tool",
 					zap.String("name", ctr.Params.Name),
 					zap.Any("args", ctr.Params.Arguments))
 			}
 
-			// 3. Execute actual method and measure duration
+			// 4. Execute actual method and measure duration
 			start := time.Now()
 			result, err := next(ctx, method, req)
 			duration := time.Since(start)
 
-			// 4. Record result based on error status
-			if err != nil {
+			// 5. Record result based on error status
+			if errors.Is(ctx.Err(), context.Canceled) {
+				span.SetStatus(codes.Error, "cancelled")
+				// notifications/cancelled 会取消 ctx，进行中的 TMDB 请求和限流等待随之中止
+				logger.Info("MCP method cancelled",
+					zap.String("method", method),
+					zap.String("session_id", req.GetSession().ID()),
+					zap.Duration("duration", duration))
+			} else if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
 				logger.Error("MCP method failed",
 					zap.String("method", method),
 					zap.String("session_id", req.GetSession().ID()),
@@ -54,8 +95,12 @@ func LoggingMiddleware(logger *zap.Logger) mcp.Middleware {
 					zap.Duration("duration", duration),
 					zap.Bool("has_result", result != nil))
 
-				// 5. For CallToolResult, log additional result information
+				// 6. For CallToolResult, log additional result information
 				if ctr, ok := result.(*mcp.CallToolResult); ok {
+					span.SetAttributes(tracing.AttrMCPToolError.Bool(ctr.IsError))
+					if ctr.IsError {
+						span.SetStatus(codes.Error, "tool error")
+					}
 					logger.Info("Tool result",
 						zap.Bool("isError", ctr.IsError),
 						zap.Int("content_count", len(ctr.Content)))
@@ -66,3 +111,180 @@ func LoggingMiddleware(logger *zap.Logger) mcp.Middleware {
 		}
 	}
 }
+
+// requestMeta returns the _meta of req's params, or nil when the request has no params
+func requestMeta(req mcp.Request) map[string]any {
+	params := req.GetParams()
+	if params == nil {
+		return nil
+	}
+	// 无参数的请求可能带有 typed nil 指针
+	if v := reflect.ValueOf(params); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+	return params.GetMeta()
+}
+
+// ProgressMiddleware attaches a progress tracker to tools/call requests that carry
+// a progress token, so that rate-limit waits, retries and batch progress deeper in
+// the call are reported to the client as notifications/progress
+func ProgressMiddleware(logger *zap.Logger) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(
+			ctx context.Context,
+			method string,
+			req mcp.Request,
+		) (mcp.Result, error) {
+			if ctr, ok := req.(*mcp.CallToolRequest); ok && ctr.Params != nil {
+				if token := ctr.Params.GetProgressToken(); token != nil {
+					tracker := progress.NewTracker(ctr.Session, token, logger)
+					ctx = progress.WithTracker(ctx, tracker)
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// SessionLoggingMiddleware attaches the calling session to the request context, so
+// that retries, rate-limit waits and TMDB errors logged during the call are also
+// sent to the client as notifications/message (filtered by its logging/setLevel)
+func SessionLoggingMiddleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(
+			ctx context.Context,
+			method string,
+			req mcp.Request,
+		) (mcp.Result, error) {
+			if session, ok := req.GetSession().(*mcp.ServerSession); ok {
+				ctx = sessionlog.WithSession(ctx, session)
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// RateLimitSessionMiddleware attaches the calling session to the request context,
+// so that the rate limiter serves queued requests of different sessions in turn
+// and one session's batch cannot starve the others
+func RateLimitSessionMiddleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(
+			ctx context.Context,
+			method string,
+			req mcp.Request,
+		) (mcp.Result, error) {
+			if session, ok := req.GetSession().(*mcp.ServerSession); ok && session != nil {
+				id := session.ID()
+				if id == "" {
+					// stdio 与 SSE 会话可能没有 ID，用会话本身区分
+					id = fmt.Sprintf("%p", session)
+				}
+				ctx = ratelimit.WithSession(ctx, id)
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// MetricsMiddleware records Prometheus metrics for every MCP method call:
+// call counts by method, tool and outcome, and call latency
+// knownTool bounds the tool label to registered tool names
+// It also tracks Streamable HTTP sessions (the only transport that sets request
+// Extra); stdio and SSE sessions are tracked by Run and GetSSEHandler
+func MetricsMiddleware(knownTool func(string) bool) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			tool := ""
+			if ctr, ok := req.(*mcp.CallToolRequest); ok {
+				tool = ctr.Params.Name
+				if !knownTool(tool) {
+					tool = "unknown"
+				}
+			}
+
+			start := time.Now()
+			result, err := next(ctx, method, req)
+			duration := time.Since(start)
+
+			outcome := "success"
+			if errors.Is(ctx.Err(), context.Canceled) {
+				outcome = "cancelled"
+			} else if err != nil {
+				outcome = "error"
+			} else if ctr, ok := result.(*mcp.CallToolResult); ok && ctr.IsError {
+				outcome = "tool_error"
+			}
+			metrics.MCPRequests.WithLabelValues(method, tool, outcome).Inc()
+			metrics.MCPRequestDuration.WithLabelValues(method, tool).Observe(duration.Seconds())
+
+			if method == "initialize" && err == nil && req.GetExtra() != nil {
+				if session, ok := req.GetSession().(*mcp.ServerSession); ok {
+					trackSession(session, "streamable")
+				}
+			}
+			return result, err
+		}
+	}
+}
+
+// trackSession counts session as active on transport until it is closed
+func trackSession(session *mcp.ServerSession, transport string) {
+	gauge := metrics.ActiveSessions.WithLabelValues(transport)
+	gauge.Inc()
+	go func() {
+		session.Wait()
+		gauge.Dec()
+	}()
+}
+
+// CallTrackingMiddleware admits tools/call requests through the CallTracker returned
+// by tracker (nil disables tracking), so that shutdown can refuse new calls and wait
+// for in-flight ones before closing sessions
+func CallTrackingMiddleware(tracker func() CallTracker) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			calls := tracker()
+			if calls == nil || method != "tools/call" {
+				return next(ctx, method, req)
+			}
+			ctx, done, err := calls.StartCall(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// StaleResultMiddleware marks tools/call results that were (partly) served from
+// expired cache entries because TMDB was unavailable (tmdb.circuit_breaker):
+// the result's _meta carries "stale": true and "stale_since" (when the oldest
+// data expired), and a text note tells the model the data may be outdated
+func StaleResultMiddleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method != "tools/call" {
+				return next(ctx, method, req)
+			}
+			ctx = tmdb.WithStaleMarker(ctx)
+			result, err := next(ctx, method, req)
+			since, stale := tmdb.StaleSince(ctx)
+			ctr, ok := result.(*mcp.CallToolResult)
+			if err != nil || !stale || !ok || ctr == nil {
+				return result, err
+			}
+
+			if ctr.Meta == nil {
+				ctr.Meta = mcp.Meta{}
+			}
+			ctr.Meta["stale"] = true
+			ctr.Meta["stale_since"] = since.UTC().Format(time.RFC3339)
+			ctr.Content = append(ctr.Content, &mcp.TextContent{
+				Text: fmt.Sprintf("Note: TMDB is currently unavailable; this result was served from cache and may be out of date (expired %s).", since.UTC().Format(time.RFC3339)),
+			})
+			return ctr, nil
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/faketmdb"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+)
+
+// newOfflineServer creates an MCP server backed by a fake TMDB server
+func newOfflineServer(t *testing.T) (*Server, *faketmdb.Server) {
+	fake := faketmdb.New("fake-key")
+	httpServer := httptest.NewServer(fake)
+	t.Cleanup(httpServer.Close)
+
+	cfg := &config.Config{
+		TMDB: config.TMDBConfig{
+			APIKey:       "fake-key",
+			Language:     "en-US",
+			RateLimit:    40,
+			BaseURL:      httpServer.URL + "/3",
+			ImageBaseURL: httpServer.URL + "/t/p/",
+		},
+		Response: config.ResponseConfig{MaxResults: 20, MaxChars: 1000},
+		Tools:    config.ToolsConfig{Batch: config.BatchConfig{MaxItems: 20, Concurrency: 4}},
+	}
+	client := tmdb.NewClient(cfg.TMDB, zap.NewNop())
+	return NewServer(client, cfg, zap.NewNop()), fake
+}
+
+// callToolText calls a tool and returns its text content
+func callToolText(t *testing.T, session *mcpsdk.ClientSession, name string, args map[string]any) (string, bool) {
+	t.Helper()
+	result, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: name, Arguments: args})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Content)
+	text, ok := result.Content[0].(*mcpsdk.TextContent)
+	require.True(t, ok)
+	return text.Text, result.IsError
+}
+
+// TestOffline_AllTools runs every tool against the fake TMDB server
+func TestOffline_AllTools(t *testing.T) {
+	server, fake := newOfflineServer(t)
+	session := connectTestClient(t, server, nil)
+
+	tests := []struct {
+		tool     string
+		args     map[string]any
+		contains []string
+	}{
+		{"search", map[string]any{"query": "inception", "page": 1}, []string{"Inception", "27205"}},
+		{"get_details", map[string]any{"media_type": "movie", "id": 27205}, []string{"Leonardo DiCaprio", "/t/p/w500/oYuLEt3zVCKq57qu2F8dT7NIa6f.jpg"}},
+		{"get_details", map[string]any{"media_type": "person", "id": 525}, []string{"Christopher Nolan", "Interstellar"}},
+		{"get_details", map[string]any{"query": "Breaking Bad"}, []string{"Bryan Cranston"}},
+		{"get_details_batch", map[string]any{"items": []map[string]any{{"media_type": "movie", "id": 157336}, {"media_type": "tv", "id": 1396}}}, []string{"Interstellar", "Breaking Bad"}},
+		{"lookup", map[string]any{"title": "The Office", "year": 2001}, []string{"2996", "Ricky Gervais"}},
+		{"discover_movies", map[string]any{"with_genres": "878"}, []string{"Interstellar", "Inception"}},
+		{"discover_tv", map[string]any{"with_genres": "35"}, []string{"The Office"}},
+		{"get_trending", map[string]any{"media_type": "tv", "time_window": "week"}, []string{"Breaking Bad"}},
+		{"get_recommendations", map[string]any{"media_type": "movie", "id": 27205}, []string{"The Dark Knight"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			text, isError := callToolText(t, session, tt.tool, tt.args)
+			require.False(t, isError, text)
+			for _, want := range tt.contains {
+				assert.Contains(t, text, want)
+			}
+		})
+	}
+
+	assert.Zero(t, fake.Requests("/configuration"))
+	assert.Positive(t, fake.Requests("/movie/*"))
+}
+
+// TestOffline_ScriptedFailures tests tool errors for scripted TMDB failures
+func TestOffline_ScriptedFailures(t *testing.T) {
+	server, fake := newOfflineServer(t)
+	session := connectTestClient(t, server, nil)
+
+	fake.Script(faketmdb.Rule{Path: "/movie/*", Status: 401})
+	text, isError := callToolText(t, session, "get_details", map[string]any{"media_type": "movie", "id": 27205})
+	assert.True(t, isError)
+	assert.Contains(t, text, "API Key")
+
+	fake.Reset()
+	text, isError = callToolText(t, session, "get_details", map[string]any{"media_type": "movie", "id": 999999})
+	assert.True(t, isError)
+	assert.Contains(t, text, "not found")
+}
+
+// TestOffline_Metrics tests that tool calls are recorded by outcome
+func TestOffline_Metrics(t *testing.T) {
+	server, fake := newOfflineServer(t)
+	session := connectTestClient(t, server, nil)
+
+	success := metrics.MCPRequests.WithLabelValues("tools/call", "get_details", "success")
+	toolError := metrics.MCPRequests.WithLabelValues("tools/call", "get_details", "tool_error")
+	tmdb401 := metrics.TMDBRequests.WithLabelValues("/movie/{id}", "401")
+	successBefore := testutil.ToFloat64(success)
+	toolErrorBefore := testutil.ToFloat64(toolError)
+	tmdb401Before := testutil.ToFloat64(tmdb401)
+
+	_, isError := callToolText(t, session, "get_details", map[string]any{"media_type": "movie", "id": 27205})
+	require.False(t, isError)
+
+	fake.Script(faketmdb.Rule{Path: "/movie/*", Status: 401})
+	_, isError = callToolText(t, session, "get_details", map[string]any{"media_type": "movie", "id": 157336})
+	require.True(t, isError)
+
+	assert.Equal(t, successBefore+1, testutil.ToFloat64(success))
+	assert.Equal(t, toolErrorBefore+1, testutil.ToFloat64(toolError))
+	assert.Equal(t, tmdb401Before+1, testutil.ToFloat64(tmdb401))
+}
+
+// TestOffline_ProjectedStructuredContent tests that projected typed results pass output
+// validation and keep selected zero-valued fields
+func TestOffline_ProjectedStructuredContent(t *testing.T) {
+	server, _ := newOfflineServer(t)
+	session := connectTestClient(t, server, nil)
+
+	result, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Name:      "search",
+		Arguments: map[string]any{"query": "inception", "page": 1, "fields": "id,title,name"},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	var structured struct {
+		Results []map[string]any `json:"results"`
+	}
+	data, err := json.Marshal(result.StructuredContent)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &structured))
+	require.NotEmpty(t, structured.Results)
+	// 电影没有 name，但已选择的字段保留零值
+	assert.Equal(t, map[string]any{"id": float64(27205), "title": "Inception", "name": ""}, structured.Results[0])
+}
@@ -3,7 +3,11 @@ package mcp
 import (
 	"context"
 	"net/http"
+	"sync"
 
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+	"github.com/XDwanj/tmdb-mcp/internal/server/middleware"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
 	"github.com/XDwanj/tmdb-mcp/internal/tools"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -15,13 +19,36 @@ type Server struct {
 	mcpServer  *mcp.Server
 	tmdbClient *tmdb.Client
 	logger     *zap.Logger
+
+	// toolsMu 保护 active，配置重载时可能与启动流程并发
+	toolsMu sync.Mutex
+	tools   []registeredTool
+	active  map[string]bool
+
+	// calls 在启动服务前设置，用于关闭时等待进行中的工具调用
+	calls CallTracker
+}
+
+// CallTracker admits tool calls and tracks them until they complete
+// StartCall returns the context to run the call with, a function to call when it
+// completes, and an error when the call must be refused (e.g. during shutdown)
+type CallTracker interface {
+	StartCall(ctx context.Context) (context.Context, func(), error)
+}
+
+// registeredTool is a tool that can be added to or removed from the MCP server
+type registeredTool struct {
+	name string
+	add  func(*mcp.Server)
 }
 
 // NewServer creates a new MCP server instance with TMDB client integration
-func NewServer(tmdbClient *tmdb.Client, logger *zap.Logger) *Server {
+func NewServer(tmdbClient *tmdb.Client, cfg *config.Config, logger *zap.Logger) *Server {
 	// Create server options
+	// HasTools 保证即使所有工具都被禁用，也声明 tools 能力（以便之后发送 list_changed）
 	opts := &mcp.ServerOptions{
 		Instructions: "TMDB Movie Database MCP Server - provides tools for searching and retrieving movie information",
+		HasTools:     true,
 	}
 
 	// Create MCP server with implementation info
@@ -30,61 +57,174 @@ func NewServer(tmdbClient *tmdb.Client, logger *zap.Logger) *Server {
 		Version: "1.0.0",
 	}, opts)
 
-	// Add logging middleware (must be added before registering tools)
-	mcpServer.AddReceivingMiddleware(LoggingMiddleware(logger))
-
-	// Create and register search tool
-	searchTool := tools.NewSearchTool(tmdbClient, logger)
-	mcp.AddTool(mcpServer, &mcp.Tool{
-		Name:        searchTool.Name(),
-		Description: searchTool.Description(),
-	}, searchTool.Handler())
-
-	// Create and register get_details tool
-	getDetailsTool := tools.NewGetDetailsTool(tmdbClient, logger)
-	mcp.AddTool(mcpServer, &mcp.Tool{
-		Name:        getDetailsTool.Name(),
-		Description: getDetailsTool.Description(),
-	}, getDetailsTool.Handler())
-
-	// Create and register discover_movies tool
-	discoverMoviesTool := tools.NewDiscoverMoviesTool(tmdbClient, logger)
-	mcp.AddTool(mcpServer, &mcp.Tool{
-		Name:        discoverMoviesTool.Name(),
-		Description: discoverMoviesTool.Description(),
-	}, discoverMoviesTool.Handler())
-
-	// Create and register discover_tv tool
-	discoverTVTool := tools.NewDiscoverTVTool(tmdbClient, logger)
-	mcp.AddTool(mcpServer, &mcp.Tool{
-		Name:        discoverTVTool.Name(),
-		Description: discoverTVTool.Description(),
-	}, discoverTVTool.Handler())
-
-	// Create and register get_trending tool
-	getTrendingTool := tools.NewGetTrendingTool(tmdbClient, logger)
-	mcp.AddTool(mcpServer, &mcp.Tool{
-		Name:        getTrendingTool.Name(),
-		Description: getTrendingTool.Description(),
-	}, getTrendingTool.Handler())
-
-	// Create and register get_recommendations tool
-	getRecommendationsTool := tools.NewGetRecommendationsTool(tmdbClient, logger)
-	mcp.AddTool(mcpServer, &mcp.Tool{
-		Name:        getRecommendationsTool.Name(),
-		Description: getRecommendationsTool.Description(),
-	}, getRecommendationsTool.Handler())
-
-	return &Server{
+	s := &Server{
 		mcpServer:  mcpServer,
 		tmdbClient: tmdbClient,
 		logger:     logger,
+		active:     make(map[string]bool),
+	}
+
+	// Add logging, progress and metrics middleware (must be added before registering tools)
+	mcpServer.AddReceivingMiddleware(LoggingMiddleware(logger), ProgressMiddleware(logger), SessionLoggingMiddleware(), RateLimitSessionMiddleware(), StaleResultMiddleware(), MetricsMiddleware(s.isKnownTool), CallTrackingMiddleware(func() CallTracker { return s.calls }))
+
+	// Create search tool
+	searchTool := tools.NewSearchTool(tmdbClient, cfg.Response, logger)
+	s.tools = append(s.tools, registeredTool{name: searchTool.Name(), add: func(srv *mcp.Server) {
+		tool := newTool(searchTool.Name(), searchTool.Title(), searchTool.Description())
+		tool.OutputSchema = searchTool.OutputSchema()
+		mcp.AddTool(srv, tool, searchTool.Handler())
+	}})
+
+	// Create get_details tool
+	getDetailsTool := tools.NewGetDetailsTool(tmdbClient, cfg.Response, logger)
+	s.tools = append(s.tools, registeredTool{name: getDetailsTool.Name(), add: func(srv *mcp.Server) {
+		mcp.AddTool(srv, newTool(getDetailsTool.Name(), getDetailsTool.Title(), getDetailsTool.Description()), getDetailsTool.Handler())
+	}})
+
+	// Create get_details_batch tool
+	getDetailsBatchTool := tools.NewGetDetailsBatchTool(tmdbClient, cfg.Response, cfg.Tools.Batch, logger)
+	s.tools = append(s.tools, registeredTool{name: getDetailsBatchTool.Name(), add: func(srv *mcp.Server) {
+		mcp.AddTool(srv, newTool(getDetailsBatchTool.Name(), getDetailsBatchTool.Title(), getDetailsBatchTool.Description()), getDetailsBatchTool.Handler())
+	}})
+
+	// Create lookup tool
+	lookupTool := tools.NewLookupTool(tmdbClient, cfg.Response, logger)
+	s.tools = append(s.tools, registeredTool{name: lookupTool.Name(), add: func(srv *mcp.Server) {
+		mcp.AddTool(srv, newTool(lookupTool.Name(), lookupTool.Title(), lookupTool.Description()), lookupTool.Handler())
+	}})
+
+	// Create discover_movies tool
+	discoverMoviesTool := tools.NewDiscoverMoviesTool(tmdbClient, cfg.Response, logger)
+	s.tools = append(s.tools, registeredTool{name: discoverMoviesTool.Name(), add: func(srv *mcp.Server) {
+		mcp.AddTool(srv, newTool(discoverMoviesTool.Name(), discoverMoviesTool.Title(), discoverMoviesTool.Description()), discoverMoviesTool.Handler())
+	}})
+
+	// Create discover_tv tool
+	discoverTVTool := tools.NewDiscoverTVTool(tmdbClient, cfg.Response, logger)
+	s.tools = append(s.tools, registeredTool{name: discoverTVTool.Name(), add: func(srv *mcp.Server) {
+		mcp.AddTool(srv, newTool(discoverTVTool.Name(), discoverTVTool.Title(), discoverTVTool.Description()), discoverTVTool.Handler())
+	}})
+
+	// Create get_trending tool
+	getTrendingTool := tools.NewGetTrendingTool(tmdbClient, cfg.Response, logger)
+	s.tools = append(s.tools, registeredTool{name: getTrendingTool.Name(), add: func(srv *mcp.Server) {
+		tool := newTool(getTrendingTool.Name(), getTrendingTool.Title(), getTrendingTool.Description())
+		tool.OutputSchema = getTrendingTool.OutputSchema()
+		mcp.AddTool(srv, tool, getTrendingTool.Handler())
+	}})
+
+	// Create get_recommendations tool
+	getRecommendationsTool := tools.NewGetRecommendationsTool(tmdbClient, cfg.Response, logger)
+	s.tools = append(s.tools, registeredTool{name: getRecommendationsTool.Name(), add: func(srv *mcp.Server) {
+		tool := newTool(getRecommendationsTool.Name(), getRecommendationsTool.Title(), getRecommendationsTool.Description())
+		tool.OutputSchema = getRecommendationsTool.OutputSchema()
+		mcp.AddTool(srv, tool, getRecommendationsTool.Handler())
+	}})
+
+	// Register the tools enabled by configuration
+	s.ApplyToolsConfig(cfg.Tools)
+
+	return s
+}
+
+// newTool builds the tool definition with annotations shared by all TMDB tools:
+// they only read from TMDB (an external, open-world system) and have no side effects
+func newTool(name, title, description string) *mcp.Tool {
+	return &mcp.Tool{
+		Name:        name,
+		Title:       title,
+		Description: description,
+		Annotations: &mcp.ToolAnnotations{
+			Title:           title,
+			ReadOnlyHint:    true,
+			DestructiveHint: boolPtr(false),
+			IdempotentHint:  true,
+			OpenWorldHint:   boolPtr(true),
+		},
+	}
+}
+
+// boolPtr returns a pointer to b
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// ApplyToolsConfig registers or removes tools according to tools.enabled / tools.disabled
+// Changes after startup (e.g. config reload) are announced to connected clients
+// via notifications/tools/list_changed
+func (s *Server) ApplyToolsConfig(cfg config.ToolsConfig) {
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+
+	// 未知的工具名通常是拼写错误，记录警告便于排查
+	known := make(map[string]bool, len(s.tools))
+	for _, tool := range s.tools {
+		known[tool.name] = true
+	}
+	for _, name := range append(append([]string(nil), cfg.Enabled...), cfg.Disabled...) {
+		if !known[name] {
+			s.logger.Warn("Unknown tool name in tools configuration", zap.String("tool", name))
+		}
+	}
+
+	var added, removed []string
+	for _, tool := range s.tools {
+		enabled := cfg.IsToolEnabled(tool.name)
+		switch {
+		case enabled && !s.active[tool.name]:
+			tool.add(s.mcpServer)
+			s.active[tool.name] = true
+			added = append(added, tool.name)
+		case !enabled && s.active[tool.name]:
+			removed = append(removed, tool.name)
+			delete(s.active, tool.name)
+		}
+	}
+	if len(removed) > 0 {
+		s.mcpServer.RemoveTools(removed...)
+	}
+
+	if len(added) > 0 || len(removed) > 0 {
+		s.logger.Info("MCP tools updated",
+			zap.Strings("added", added),
+			zap.Strings("removed", removed),
+			zap.Int("active", len(s.active)),
+		)
+	}
+}
+
+// isKnownTool reports whether name is one of the server's tools (enabled or not)
+func (s *Server) isKnownTool(name string) bool {
+	for _, tool := range s.tools {
+		if tool.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackCalls routes tools/call requests through tracker; call it before serving
+func (s *Server) TrackCalls(tracker CallTracker) {
+	s.calls = tracker
+}
+
+// CloseSessions closes all connected sessions on every transport
+// Closing an SSE or Streamable HTTP session ends its open stream, so that the
+// HTTP server can shut down without waiting for clients to disconnect
+func (s *Server) CloseSessions() {
+	for session := range s.mcpServer.Sessions() {
+		if err := session.Close(); err != nil {
+			s.logger.Debug("Failed to close MCP session", zap.String("session_id", session.ID()), zap.Error(err))
+		}
 	}
 }
 
 // Run starts the MCP server with the specified transport
 func (s *Server) Run(ctx context.Context, transport mcp.Transport) error {
 	s.logger.Info("Starting MCP server")
+	gauge := metrics.ActiveSessions.WithLabelValues("stdio")
+	gauge.Inc()
+	defer gauge.Dec()
 	return s.mcpServer.Run(ctx, transport)
 }
 
@@ -94,11 +234,22 @@ func (s *Server) Run(ctx context.Context, transport mcp.Transport) error {
 func (s *Server) GetSSEHandler() http.Handler {
 	// Use MCP SDK's NewSSEHandler with a factory function
 	// that returns our MCP server instance for each request
-	return mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
+	handler := mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
 		// Return the MCP server instance
 		// The SDK will use this server to handle MCP requests via SSE
+		s.logHTTPSession("sse", r)
 		return s.mcpServer
 	}, nil) // nil for default SSEOptions
+
+	// 每个 SSE 会话对应一个长连接 GET 请求，连接期间计为活跃会话
+	gauge := metrics.ActiveSessions.WithLabelValues("sse")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gauge.Inc()
+			defer gauge.Dec()
+		}
+		handler.ServeHTTP(w, r)
+	})
 }
 
 // GetStreamableHandler returns an HTTP handler for Streamable HTTP connections.
@@ -107,6 +258,20 @@ func (s *Server) GetSSEHandler() http.Handler {
 // with SSE and stdio modes.
 func (s *Server) GetStreamableHandler() http.Handler {
 	return mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		s.logHTTPSession("streamable", r)
 		return s.mcpServer
 	}, nil) // nil for default StreamableHTTPOptions
 }
+
+// logHTTPSession logs a new HTTP session with the client's address, the name of
+// its bearer token or its OAuth subject and, over mutual TLS, its verified
+// certificate identity
+func (s *Server) logHTTPSession(transport string, r *http.Request) {
+	s.logger.Info("MCP HTTP session starting",
+		zap.String("transport", transport),
+		zap.String("addr", r.RemoteAddr),
+		zap.String("token", middleware.TokenName(r)),
+		zap.String("subject", middleware.OAuthSubject(r)),
+		zap.String("client_identity", middleware.ClientIdentity(r)),
+	)
+}
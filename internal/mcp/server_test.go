@@ -38,7 +38,7 @@ func TestNewServer(t *testing.T) {
 			tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
 			// 创建 MCP Server
-			server := NewServer(tmdbClient, logger)
+			server := NewServer(tmdbClient, &config.Config{}, logger)
 
 			// 验证 server 不为 nil
 			require.NotNil(t, server, "Server should not be nil")
@@ -63,7 +63,7 @@ func TestServerInfo(t *testing.T) {
 	}
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 注意：由于 MCP SDK 的 Server 结构体可能不直接暴露 ServerInfo，
 	// 我们主要验证 server 能正确创建
@@ -83,7 +83,7 @@ func TestNewServer_WithValidDependencies(t *testing.T) {
 	}
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 验证所有依赖都正确设置
 	require.NotNil(t, server, "Server should be created successfully")
@@ -102,7 +102,7 @@ func TestServer_Run_WithInMemoryTransport(t *testing.T) {
 	}
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 创建 InMemoryTransport
 	clientTransport, serverTransport := mcpsdk.NewInMemoryTransports()
@@ -144,7 +144,7 @@ func TestServer_Run_Logger(t *testing.T) {
 	}
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 创建 InMemoryTransport
 	_, serverTransport := mcpsdk.NewInMemoryTransports()
@@ -171,7 +171,7 @@ func TestServer_Run_ContextCancellation(t *testing.T) {
 	}
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 创建 InMemoryTransport
 	_, serverTransport := mcpsdk.NewInMemoryTransports()
@@ -211,7 +211,7 @@ func TestSearchToolRegistration(t *testing.T) {
 	}
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 创建 InMemoryTransport
 	clientTransport, serverTransport := mcpsdk.NewInMemoryTransports()
@@ -278,7 +278,7 @@ func TestServer_GetSSEHandler(t *testing.T) {
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
 	// 创建 MCP Server
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 获取 SSE handler
 	handler := server.GetSSEHandler()
@@ -297,7 +297,7 @@ func TestServer_GetSSEHandler_Multiple(t *testing.T) {
 	}
 	tmdbClient := tmdb.NewClient(tmdbConfig, logger)
 
-	server := NewServer(tmdbClient, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
 
 	// 多次调用 GetSSEHandler
 	handler1 := server.GetSSEHandler()
@@ -310,3 +310,112 @@ func TestServer_GetSSEHandler_Multiple(t *testing.T) {
 	// 注意：每次调用都会创建一个新的 SSE handler 实例
 	// 这是预期行为，因为 NewSSEHandler 每次都创建新的 handler
 }
+
+// connectTestClient 通过 InMemoryTransport 连接测试 client
+func connectTestClient(t *testing.T, server *Server, opts *mcpsdk.ClientOptions) *mcpsdk.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+
+	clientTransport, serverTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.mcpServer.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "test-client", Version: "1.0.0"}, opts)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		clientSession.Close()
+		serverSession.Close()
+	})
+	return clientSession
+}
+
+// listToolNames 返回当前暴露的工具名
+func listToolNames(t *testing.T, session *mcpsdk.ClientSession) []string {
+	t.Helper()
+	result, err := session.ListTools(context.Background(), &mcpsdk.ListToolsParams{})
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(result.Tools))
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+// TestToolAnnotations 测试所有工具都带有只读注解和标题
+func TestToolAnnotations(t *testing.T) {
+	logger := zap.NewNop()
+	tmdbClient := tmdb.NewClient(config.TMDBConfig{APIKey: "test_api_key", RateLimit: 40}, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
+
+	session := connectTestClient(t, server, nil)
+	result, err := session.ListTools(context.Background(), &mcpsdk.ListToolsParams{})
+	require.NoError(t, err)
+	require.Len(t, result.Tools, 8)
+
+	for _, tool := range result.Tools {
+		require.NotNil(t, tool.Annotations, "tool %s should have annotations", tool.Name)
+		assert.True(t, tool.Annotations.ReadOnlyHint, "tool %s should be read-only", tool.Name)
+		assert.True(t, tool.Annotations.IdempotentHint, "tool %s should be idempotent", tool.Name)
+		require.NotNil(t, tool.Annotations.OpenWorldHint)
+		assert.True(t, *tool.Annotations.OpenWorldHint)
+		assert.NotEmpty(t, tool.Title)
+		assert.Equal(t, tool.Title, tool.Annotations.Title)
+	}
+}
+
+// TestToolOutputSchemas 测试返回类型化结果的工具发布 outputSchema
+func TestToolOutputSchemas(t *testing.T) {
+	logger := zap.NewNop()
+	tmdbClient := tmdb.NewClient(config.TMDBConfig{APIKey: "test_api_key", RateLimit: 40}, logger)
+	server := NewServer(tmdbClient, &config.Config{}, logger)
+
+	session := connectTestClient(t, server, nil)
+	result, err := session.ListTools(context.Background(), &mcpsdk.ListToolsParams{})
+	require.NoError(t, err)
+
+	typed := map[string]bool{"search": true, "get_details_batch": true, "get_trending": true, "get_recommendations": true}
+	for _, tool := range result.Tools {
+		if typed[tool.Name] {
+			assert.NotNil(t, tool.OutputSchema, "tool %s should publish an output schema", tool.Name)
+			delete(typed, tool.Name)
+		}
+	}
+	assert.Empty(t, typed, "typed tools missing from the tool list")
+}
+
+// TestApplyToolsConfig 测试 tools.enabled/tools.disabled 以及重载时的 list_changed 通知
+func TestApplyToolsConfig(t *testing.T) {
+	logger := zap.NewNop()
+	tmdbClient := tmdb.NewClient(config.TMDBConfig{APIKey: "test_api_key", RateLimit: 40}, logger)
+	server := NewServer(tmdbClient, &config.Config{
+		Tools: config.ToolsConfig{Disabled: []string{"get_trending"}},
+	}, logger)
+
+	listChanged := make(chan struct{}, 10)
+	session := connectTestClient(t, server, &mcpsdk.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *mcpsdk.ToolListChangedRequest) {
+			listChanged <- struct{}{}
+		},
+	})
+
+	names := listToolNames(t, session)
+	assert.Len(t, names, 7)
+	assert.NotContains(t, names, "get_trending")
+
+	// 模拟配置重载：仅暴露 search 和 get_details
+	server.ApplyToolsConfig(config.ToolsConfig{Enabled: []string{"search", "get_details"}})
+
+	select {
+	case <-listChanged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected notifications/tools/list_changed after reload")
+	}
+	assert.ElementsMatch(t, []string{"search", "get_details"}, listToolNames(t, session))
+
+	// 重新启用全部工具
+	server.ApplyToolsConfig(config.ToolsConfig{})
+	assert.Eventually(t, func() bool { return len(listToolNames(t, session)) == 8 }, 2*time.Second, 20*time.Millisecond)
+}
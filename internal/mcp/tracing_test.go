@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/XDwanj/tmdb-mcp/internal/tracing"
+)
+
+// TestTracing_ToolCallToTMDB tests the span tree of a tool call continuing the client's trace
+func TestTracing_ToolCallToTMDB(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server, _ := newOfflineServer(t)
+	session := connectTestClient(t, server, nil)
+
+	result, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Meta:      mcpsdk.Meta{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		Name:      "get_details",
+		Arguments: map[string]any{"media_type": "movie", "id": 27205},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	mcpSpan, ok := spans["MCP tools/call get_details"]
+	require.True(t, ok, "missing MCP span")
+	callSpan, ok := spans["TMDB /movie/{id}"]
+	require.True(t, ok, "missing TMDB call span")
+	httpSpan, ok := spans["GET /movie/{id}"]
+	require.True(t, ok, "missing HTTP span")
+	waitSpan, ok := spans["ratelimit.wait"]
+	require.True(t, ok, "missing rate limit span")
+
+	// 延续客户端的 trace
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", mcpSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", mcpSpan.Parent().SpanID().String())
+
+	assert.Equal(t, mcpSpan.SpanContext().SpanID(), callSpan.Parent().SpanID())
+	assert.Equal(t, callSpan.SpanContext().SpanID(), httpSpan.Parent().SpanID())
+	assert.Equal(t, callSpan.SpanContext().SpanID(), waitSpan.Parent().SpanID())
+
+	assert.Contains(t, callSpan.Attributes(), tracing.AttrTMDBCache.String("disabled"))
+	assert.Contains(t, httpSpan.Attributes(), tracing.AttrHTTPStatus.Int(200))
+}
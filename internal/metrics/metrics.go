@@ -0,0 +1,192 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+// All metrics live in a dedicated registry (with the Go runtime and process
+// collectors) under the tmdb_mcp namespace, so the endpoint only exposes
+// this server's telemetry.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is the prefix of all metric names
+const namespace = "tmdb_mcp"
+
+// Registry holds all tmdb-mcp metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// MCP 请求
+var (
+	// MCPRequests counts MCP method calls by method, tool (tools/call only) and outcome
+	// outcome: success, tool_error (CallToolResult.IsError), error, cancelled
+	MCPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mcp_requests_total",
+		Help:      "MCP method calls by method, tool and outcome.",
+	}, []string{"method", "tool", "outcome"})
+
+	// MCPRequestDuration observes MCP method call latency
+	MCPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mcp_request_duration_seconds",
+		Help:      "MCP method call latency by method and tool.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "tool"})
+
+	// ActiveSessions is the number of connected MCP sessions per transport (stdio, sse, streamable)
+	ActiveSessions = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Connected MCP sessions by transport.",
+	}, []string{"transport"})
+)
+
+// TMDB 请求
+var (
+	// TMDBRequests counts TMDB API requests by endpoint template and status code
+	// ("error" when no response was received)
+	TMDBRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tmdb_requests_total",
+		Help:      "TMDB API requests by endpoint and status.",
+	}, []string{"endpoint", "status"})
+
+	// TMDBRequestDuration observes TMDB API latency (including retries' individual attempts)
+	TMDBRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tmdb_request_duration_seconds",
+		Help:      "TMDB API request latency by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// TMDBRetries counts retried TMDB API requests by endpoint template
+	TMDBRetries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tmdb_retries_total",
+		Help:      "Retried TMDB API requests by endpoint.",
+	}, []string{"endpoint"})
+
+	// CoalescedRequests counts calls that shared an identical in-flight TMDB request
+	CoalescedRequests = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tmdb_coalesced_requests_total",
+		Help:      "TMDB calls served by an identical in-flight request instead of a new one.",
+	})
+
+	// TMDBKeyRequests counts TMDB API requests per key of the tmdb.api_keys pool
+	// by status code ("error" when no response was received)
+	TMDBKeyRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tmdb_key_requests_total",
+		Help:      "TMDB API requests per pooled API key and status.",
+	}, []string{"key", "status"})
+
+	// TMDBKeyHealthy is 1 for pooled API keys in use and 0 for keys rejected by TMDB
+	TMDBKeyHealthy = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tmdb_key_healthy",
+		Help:      "Whether a pooled TMDB API key is healthy (1) or was rejected with 401 (0).",
+	}, []string{"key"})
+
+	// CircuitBreakerState is the state of the TMDB circuit breaker (0 closed, 1 open, 2 half-open)
+	CircuitBreakerState = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tmdb_circuit_breaker_state",
+		Help:      "State of the TMDB circuit breaker: 0 closed, 1 open, 2 half-open.",
+	})
+
+	// CircuitBreakerRejected counts requests failed immediately by the open circuit breaker
+	CircuitBreakerRejected = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tmdb_circuit_breaker_rejected_total",
+		Help:      "TMDB requests failed immediately because the circuit breaker was open.",
+	})
+
+	// StaleResponses counts expired cache entries served while TMDB was unavailable, by cache layer
+	StaleResponses = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stale_responses_total",
+		Help:      "Expired cached responses served while TMDB was unavailable, by cache layer.",
+	}, []string{"layer"})
+
+	// ClientKeyClients is the number of per-key TMDB clients for client-supplied credentials
+	ClientKeyClients = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tmdb_client_key_clients",
+		Help:      "TMDB clients kept for credentials supplied by HTTP clients.",
+	})
+)
+
+// 限流与缓存
+var (
+	// RateLimitWait observes how long requests waited for a rate-limit token, by
+	// scheduling class (interactive, background)
+	RateLimitWait = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ratelimit_wait_seconds",
+		Help:      "Time spent waiting for a TMDB rate-limit token, by scheduling class.",
+		Buckets:   []float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"class"})
+
+	// RateLimitQueueDepth is the number of requests currently waiting for a token, by scheduling class
+	RateLimitQueueDepth = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ratelimit_queue_depth",
+		Help:      "Requests currently waiting for a TMDB rate-limit token, by scheduling class.",
+	}, []string{"class"})
+
+	// RateLimitPauses counts pauses of a rate limiter requested by TMDB, by reason
+	// (retry_after: a 429 response, headers: X-RateLimit-Remaining reached 0)
+	RateLimitPauses = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ratelimit_pauses_total",
+		Help:      "Rate limiter pauses requested by TMDB, by reason.",
+	}, []string{"reason"})
+
+	// CacheHits counts response cache hits by layer (memory, disk)
+	CacheHits = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "TMDB response cache hits by layer.",
+	}, []string{"layer"})
+
+	// CacheMisses counts cacheable requests not found in any cache layer
+	CacheMisses = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "Cacheable TMDB requests not found in any cache layer.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Endpoint returns the endpoint template of a TMDB API path for use as a label,
+// replacing numeric IDs so that label cardinality stays bounded
+// e.g. "/movie/27205/recommendations" -> "/movie/{id}/recommendations"
+func Endpoint(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
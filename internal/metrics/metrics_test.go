@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEndpoint tests that numeric IDs are replaced by a placeholder
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/movie/27205", "/movie/{id}"},
+		{"/tv/1396/recommendations", "/tv/{id}/recommendations"},
+		{"/search/multi", "/search/multi"},
+		{"/trending/movie/day", "/trending/movie/day"},
+		{"/", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Endpoint(tt.path))
+		})
+	}
+}
+
+// TestHandler tests that the handler serves the registry in the text format
+func TestHandler(t *testing.T) {
+	CacheMisses.Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "tmdb_mcp_cache_misses_total")
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,112 @@
+// Package progress reports MCP progress notifications for long-running tool calls.
+// A Tracker is attached to the request context when the caller supplies a progress
+// token, so that deeper layers (rate limiter, retries, batch fetching) can report
+// what they are waiting on without knowing about the MCP session.
+package progress
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// contextKey is used for context values (避免 key 冲突)
+type contextKey string
+
+// trackerKey is the context key for the progress tracker
+const trackerKey contextKey = "progress_tracker"
+
+// Tracker sends notifications/progress for a single request
+// All methods are safe for concurrent use and are no-ops on a nil Tracker
+type Tracker struct {
+	session *mcp.ServerSession
+	token   any
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	progress float64
+	total    float64
+}
+
+// NewTracker creates a tracker for the request identified by token
+func NewTracker(session *mcp.ServerSession, token any, logger *zap.Logger) *Tracker {
+	return &Tracker{
+		session: session,
+		token:   token,
+		logger:  logger,
+	}
+}
+
+// WithTracker returns a copy of ctx carrying the tracker
+func WithTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey, t)
+}
+
+// FromContext returns the tracker stored in ctx, or nil if none
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey).(*Tracker)
+	return t
+}
+
+// Notify reports an informational event on the tracker stored in ctx
+func Notify(ctx context.Context, message string) {
+	FromContext(ctx).Notify(ctx, message)
+}
+
+// SetTotal sets the total number of work units, e.g. the number of items in a batch
+func (t *Tracker) SetTotal(total int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = float64(total)
+}
+
+// Advance reports that one unit of work has completed
+func (t *Tracker) Advance(ctx context.Context, message string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress = math.Floor(t.progress) + 1
+	t.send(ctx, message)
+}
+
+// Notify reports an informational event such as a rate-limit wait or a retry
+// The MCP spec requires progress to increase with every notification: without a
+// total each event counts as one step; with a total the value moves halfway
+// towards the next unit so that it never overtakes completed work
+func (t *Tracker) Notify(ctx context.Context, message string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.total > 0 {
+		t.progress += (math.Floor(t.progress) + 1 - t.progress) / 2
+	} else {
+		t.progress++
+	}
+	t.send(ctx, message)
+}
+
+// send delivers the current progress; callers must hold t.mu
+func (t *Tracker) send(ctx context.Context, message string) {
+	err := t.session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: t.token,
+		Progress:      t.progress,
+		Total:         t.total,
+		Message:       message,
+	})
+	if err != nil {
+		t.logger.Debug("Failed to send progress notification",
+			zap.Any("progress_token", t.token),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,112 @@
+package progress
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// progressRecorder collects progress notifications received by a test client
+type progressRecorder struct {
+	mu            sync.Mutex
+	notifications []*mcp.ProgressNotificationParams
+}
+
+func (r *progressRecorder) handle(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications = append(r.notifications, req.Params)
+}
+
+func (r *progressRecorder) snapshot() []*mcp.ProgressNotificationParams {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*mcp.ProgressNotificationParams(nil), r.notifications...)
+}
+
+// connectSession connects an in-memory client and returns the server side session
+func connectSession(t *testing.T, recorder *progressRecorder) *mcp.ServerSession {
+	t.Helper()
+	ctx := context.Background()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: recorder.handle,
+	})
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		clientSession.Close()
+		serverSession.Close()
+	})
+	return serverSession
+}
+
+// TestTracker_NilIsNoop tests that a missing tracker is safe to use
+func TestTracker_NilIsNoop(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Nil(t, FromContext(ctx))
+	assert.NotPanics(t, func() {
+		Notify(ctx, "queued")
+		var tracker *Tracker
+		tracker.SetTotal(3)
+		tracker.Advance(ctx, "done")
+	})
+}
+
+// TestTracker_ProgressIncreases tests that every notification increases progress
+func TestTracker_ProgressIncreases(t *testing.T) {
+	recorder := &progressRecorder{}
+	session := connectSession(t, recorder)
+
+	tracker := NewTracker(session, "token-1", zap.NewNop())
+	ctx := WithTracker(context.Background(), tracker)
+	require.Same(t, tracker, FromContext(ctx))
+
+	tracker.SetTotal(2)
+	Notify(ctx, "Queued for TMDB rate limit")
+	Notify(ctx, "Retrying TMDB request (retry 1 of 3)")
+	tracker.Advance(ctx, "Fetched 1 of 2 items")
+	tracker.Advance(ctx, "Fetched 2 of 2 items")
+
+	require.Eventually(t, func() bool { return len(recorder.snapshot()) == 4 }, time.Second, 10*time.Millisecond)
+
+	notifications := recorder.snapshot()
+	assert.Equal(t, []float64{0.5, 0.75, 1, 2}, []float64{
+		notifications[0].Progress, notifications[1].Progress, notifications[2].Progress, notifications[3].Progress,
+	})
+	for _, n := range notifications {
+		assert.Equal(t, "token-1", n.ProgressToken)
+		assert.Equal(t, float64(2), n.Total)
+	}
+	assert.Equal(t, "Fetched 2 of 2 items", notifications[3].Message)
+}
+
+// TestTracker_WithoutTotal tests step counting when the total is unknown
+func TestTracker_WithoutTotal(t *testing.T) {
+	recorder := &progressRecorder{}
+	session := connectSession(t, recorder)
+
+	ctx := WithTracker(context.Background(), NewTracker(session, 42, zap.NewNop()))
+	Notify(ctx, "Queued for TMDB rate limit")
+	Notify(ctx, "Retrying TMDB request (retry 1 of 3)")
+
+	require.Eventually(t, func() bool { return len(recorder.snapshot()) == 2 }, time.Second, 10*time.Millisecond)
+
+	notifications := recorder.snapshot()
+	assert.Equal(t, float64(1), notifications[0].Progress)
+	assert.Equal(t, float64(2), notifications[1].Progress)
+	assert.Zero(t, notifications[1].Total)
+}
@@ -1,22 +1,44 @@
 // Package ratelimit provides rate limiting functionality for TMDB API requests.
 // It uses the Token Bucket algorithm via golang.org/x/time/rate to ensure
 // requests respect TMDB's rate limits (default: 40 requests per 10 seconds).
+// When requests have to queue, tokens go to interactive requests before
+// background ones and to sessions in turn, rather than in arrival order.
+// With adaptive rate limiting, TMDB's feedback (429 Retry-After, rate-limit
+// headers) pauses all callers and the rate then ramps back up.
 package ratelimit
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+	"github.com/XDwanj/tmdb-mcp/internal/progress"
+	"github.com/XDwanj/tmdb-mcp/internal/sessionlog"
+	"github.com/XDwanj/tmdb-mcp/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 )
 
+// rampStartFactor is the fraction of the configured rate used right after a pause
+const rampStartFactor = 0.25
+
 // Limiter wraps golang.org/x/time/rate.Limiter to control TMDB API request rate
 type Limiter struct {
 	rateLimiter *rate.Limiter
+	scheduler   *scheduler
 	logger      *zap.Logger
 	rateLimit   int
+	limit       rate.Limit // 配置的速率
+	adaptive    bool
+	rampUp      time.Duration
+
+	mu          sync.Mutex
+	pausedUntil time.Time // TMDB 要求暂停到此时间
+	rampFrom    time.Time // 最近一次暂停结束的时间（逐步恢复速率的起点）
 }
 
 // NewLimiter creates a new rate limiter with the specified configuration
@@ -37,26 +59,144 @@ func NewLimiter(cfg config.TMDBConfig, logger *zap.Logger) *Limiter {
 		zap.String("component", "rate_limiter"),
 	)
 
-	return &Limiter{
+	l := &Limiter{
 		rateLimiter: rateLimiter,
 		logger:      logger,
 		rateLimit:   cfg.RateLimit,
+		limit:       rate.Every(perRequestInterval),
+		adaptive:    cfg.AdaptiveRateLimit.Enabled,
+		rampUp:      cfg.AdaptiveRateLimit.RampUp,
+	}
+	l.scheduler = &scheduler{limiter: rateLimiter, hold: l.hold}
+	return l
+}
+
+// PauseUntil makes every caller wait until the given time, as requested by TMDB
+// (a 429 Retry-After or an X-RateLimit-Reset); afterwards the rate ramps up again
+// reason labels the pause in metrics and logs. It is a no-op without adaptive rate limiting
+func (l *Limiter) PauseUntil(until time.Time, reason string) {
+	if !l.adaptive {
+		return
+	}
+	l.mu.Lock()
+	extended := until.After(l.pausedUntil)
+	if extended {
+		l.pausedUntil = until
+		l.rampFrom = until
+	}
+	l.mu.Unlock()
+
+	if extended {
+		metrics.RateLimitPauses.WithLabelValues(reason).Inc()
+		l.logger.Warn("Rate limiter paused by TMDB",
+			zap.Time("until", until),
+			zap.String("reason", reason),
+			zap.String("component", "rate_limiter"),
+		)
+	}
+}
+
+// waitPause blocks while the limiter is paused
+func (l *Limiter) waitPause(ctx context.Context) error {
+	notified := false
+	for {
+		l.mu.Lock()
+		wait := time.Until(l.pausedUntil)
+		l.mu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+		if !notified {
+			progress.Notify(ctx, fmt.Sprintf("TMDB asked to slow down, waiting %s", wait.Round(time.Second)))
+			notified = true
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// hold returns how long queued requests must still wait for a pause; once the
+// pause is over it adjusts the rate for the ramp-up
+func (l *Limiter) hold(now time.Time) time.Duration {
+	if !l.adaptive {
+		return 0
+	}
+	l.mu.Lock()
+	wait := l.pausedUntil.Sub(now)
+	l.mu.Unlock()
+	if wait > 0 {
+		return wait
+	}
+	l.adjust(now)
+	return 0
+}
+
+// adjust sets the rate for the ramp-up after a pause: from rampStartFactor of
+// the configured rate back to the full rate over rampUp. The burst shrinks with
+// the rate, so tokens saved up during the pause are not spent at once
+func (l *Limiter) adjust(now time.Time) {
+	l.mu.Lock()
+	factor := 1.0
+	if !l.rampFrom.IsZero() {
+		if elapsed := now.Sub(l.rampFrom); elapsed < l.rampUp {
+			factor = rampStartFactor + (1-rampStartFactor)*float64(elapsed)/float64(l.rampUp)
+		} else {
+			l.rampFrom = time.Time{}
+		}
+	}
+	l.mu.Unlock()
+
+	limit := l.limit * rate.Limit(factor)
+	burst := max(1, int(float64(l.rateLimit)*factor))
+	if l.rateLimiter.Limit() != limit {
+		l.rateLimiter.SetLimitAt(now, limit)
+	}
+	if l.rateLimiter.Burst() != burst {
+		l.rateLimiter.SetBurstAt(now, burst)
 	}
 }
 
 // Wait blocks until a token is available or context is cancelled
 func (l *Limiter) Wait(ctx context.Context) error {
 	start := time.Now()
+	class := PriorityFrom(ctx).String()
 
-	// Wait for token from rate limiter (blocks if no tokens available)
-	err := l.rateLimiter.Wait(ctx)
+	ctx, span := tracing.Tracer().Start(ctx, "ratelimit.wait")
+	defer span.End()
+
+	// Wait for any pause requested by TMDB first (waitPause notifies the caller)
+	// The wait is aborted as soon as ctx is cancelled (e.g. notifications/cancelled)
+	metrics.RateLimitQueueDepth.WithLabelValues(class).Inc()
+	err := l.waitPause(ctx)
+	if err == nil {
+		if l.adaptive {
+			l.adjust(time.Now())
+		}
+
+		// Take a token, or queue by class and session when none is available
+		w, ok := l.scheduler.acquire(ctx)
+		span.SetAttributes(tracing.AttrRateLimitQueued.Bool(!ok))
+		if !ok {
+			// Tell the caller we are queued
+			progress.Notify(ctx, "Queued for TMDB rate limit")
+			err = l.scheduler.wait(ctx, w)
+		}
+	}
+	metrics.RateLimitQueueDepth.WithLabelValues(class).Dec()
 
 	// Calculate wait time
 	elapsed := time.Since(start)
+	metrics.RateLimitWait.WithLabelValues(class).Observe(elapsed.Seconds())
 
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		// Log warning when wait is cancelled (e.g., context.Canceled)
-		l.logger.Warn("Rate limiter wait cancelled",
+		sessionlog.Logger(ctx, l.logger).Warn("Rate limiter wait cancelled",
 			zap.Duration("wait_duration", elapsed),
 			zap.Error(err),
 			zap.String("component", "rate_limiter"),
@@ -67,9 +207,10 @@ func (l *Limiter) Wait(ctx context.Context) error {
 	// Log only if we actually had to wait (avoid log noise)
 	// Use 1ms threshold to filter out instant returns
 	if elapsed > time.Millisecond {
-		l.logger.Debug("Rate limiter wait completed",
+		sessionlog.Logger(ctx, l.logger).Debug("Rate limiter wait completed",
 			zap.Duration("wait_duration", elapsed),
 			zap.Int("rate_limit", l.rateLimit),
+			zap.String("class", class),
 			zap.String("component", "rate_limiter"),
 		)
 	}
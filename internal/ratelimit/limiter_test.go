@@ -231,3 +231,58 @@ func TestLimiter_WaitCancelled(t *testing.T) {
 		assert.Contains(t, fields, "component", "Should include component field")
 	}
 }
+
+// TestLimiter_PauseUntil tests that a pause requested by TMDB blocks all callers and the rate ramps up afterwards
+func TestLimiter_PauseUntil(t *testing.T) {
+	cfg := config.TMDBConfig{
+		RateLimit: 40,
+		AdaptiveRateLimit: config.AdaptiveRateLimitConfig{
+			Enabled: true,
+			RampUp:  time.Minute,
+		},
+	}
+	limiter := NewLimiter(cfg, zap.NewNop())
+	ctx := context.Background()
+
+	limiter.PauseUntil(time.Now().Add(200*time.Millisecond), "retry_after")
+	// 更早的暂停不会缩短当前暂停
+	limiter.PauseUntil(time.Now().Add(50*time.Millisecond), "retry_after")
+
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(ctx))
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond, "Wait should block until the pause ends")
+
+	// 暂停结束后以 25% 的速率与 burst 重新开始
+	assert.InDelta(t, 1.0, float64(limiter.rateLimiter.Limit()), 0.1)
+	assert.Equal(t, 10, limiter.rateLimiter.Burst())
+
+	// 爬升结束后恢复配置的速率
+	limiter.adjust(time.Now().Add(time.Minute))
+	assert.Equal(t, limiter.limit, limiter.rateLimiter.Limit())
+	assert.Equal(t, 40, limiter.rateLimiter.Burst())
+}
+
+// TestLimiter_PauseUntil_Disabled tests that pauses are ignored without adaptive rate limiting
+func TestLimiter_PauseUntil_Disabled(t *testing.T) {
+	limiter := NewLimiter(config.TMDBConfig{RateLimit: 40}, zap.NewNop())
+	limiter.PauseUntil(time.Now().Add(time.Hour), "retry_after")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, limiter.Wait(ctx))
+	assert.Equal(t, 40, limiter.rateLimiter.Burst())
+}
+
+// TestLimiter_PauseUntil_Cancelled tests that a paused Wait returns when ctx is cancelled
+func TestLimiter_PauseUntil_Cancelled(t *testing.T) {
+	cfg := config.TMDBConfig{
+		RateLimit:         40,
+		AdaptiveRateLimit: config.AdaptiveRateLimitConfig{Enabled: true, RampUp: time.Second},
+	}
+	limiter := NewLimiter(cfg, zap.NewNop())
+	limiter.PauseUntil(time.Now().Add(time.Hour), "headers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
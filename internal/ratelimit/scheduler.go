@@ -0,0 +1,247 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Priority is the scheduling class of a rate-limited request
+type Priority int
+
+const (
+	// PriorityInteractive is the class of regular tool calls (the default)
+	PriorityInteractive Priority = iota
+	// PriorityBackground is the class of bulk work such as get_details_batch items
+	PriorityBackground
+)
+
+// String returns the class name used in metrics
+func (p Priority) String() string {
+	if p == PriorityBackground {
+		return "background"
+	}
+	return "interactive"
+}
+
+// backgroundShare gives one token in backgroundShare to background requests while
+// interactive requests are also queued, so that bulk work is slowed down but never starved
+const backgroundShare = 4
+
+// contextKey is used for context values (避免 key 冲突)
+type contextKey string
+
+const (
+	// priorityKey is the context key for the scheduling class
+	priorityKey contextKey = "ratelimit_priority"
+	// sessionKey is the context key for the session requests are queued under
+	sessionKey contextKey = "ratelimit_session"
+)
+
+// WithPriority returns a copy of ctx whose rate-limited requests use class p
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey, p)
+}
+
+// PriorityFrom returns the scheduling class of ctx (PriorityInteractive by default)
+func PriorityFrom(ctx context.Context) Priority {
+	if p, _ := ctx.Value(priorityKey).(Priority); p == PriorityBackground {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// WithSession returns a copy of ctx whose rate-limited requests are queued under
+// the given session ID; sessions of the same class are served in turn
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey, id)
+}
+
+// sessionFrom returns the session ID of ctx ("" when none)
+func sessionFrom(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey).(string)
+	return id
+}
+
+// waiter is a request queued for a token
+type waiter struct {
+	class   Priority
+	session string
+	ready   chan struct{} // 获得令牌时关闭
+	granted bool
+}
+
+// classQueue holds the waiters of one class, one FIFO queue per session
+// Sessions are served round robin, so a session queueing many requests (e.g. a
+// 50-item batch) delays other sessions by at most one token per turn
+type classQueue struct {
+	sessions []string // 有等待请求的会话，按轮转顺序
+	waiters  map[string][]*waiter
+	next     int // 下一个被服务的会话在 sessions 中的位置
+	size     int
+}
+
+// push appends w to its session's queue
+func (q *classQueue) push(w *waiter) {
+	if q.waiters == nil {
+		q.waiters = make(map[string][]*waiter)
+	}
+	if len(q.waiters[w.session]) == 0 {
+		q.sessions = append(q.sessions, w.session)
+	}
+	q.waiters[w.session] = append(q.waiters[w.session], w)
+	q.size++
+}
+
+// pop removes and returns the first waiter of the next session in turn
+func (q *classQueue) pop() *waiter {
+	if q.size == 0 {
+		return nil
+	}
+	if q.next >= len(q.sessions) {
+		q.next = 0
+	}
+	session := q.sessions[q.next]
+	queue := q.waiters[session]
+	w := queue[0]
+	if len(queue) == 1 {
+		delete(q.waiters, session)
+		q.sessions = append(q.sessions[:q.next], q.sessions[q.next+1:]...)
+	} else {
+		q.waiters[session] = queue[1:]
+		q.next++
+	}
+	q.size--
+	return w
+}
+
+// remove drops a waiter that gave up (its context was cancelled)
+func (q *classQueue) remove(w *waiter) {
+	queue := q.waiters[w.session]
+	for i, queued := range queue {
+		if queued != w {
+			continue
+		}
+		q.size--
+		if len(queue) > 1 {
+			q.waiters[w.session] = append(queue[:i:i], queue[i+1:]...)
+			return
+		}
+		delete(q.waiters, w.session)
+		for j, session := range q.sessions {
+			if session == w.session {
+				q.sessions = append(q.sessions[:j], q.sessions[j+1:]...)
+				if j < q.next {
+					q.next--
+				}
+				break
+			}
+		}
+		return
+	}
+}
+
+// scheduler hands out the tokens of a rate.Limiter to queued requests by class
+// and session instead of in arrival order
+type scheduler struct {
+	limiter *rate.Limiter
+	// hold is called before each token is handed out and returns how long to
+	// hold all queued requests (a pause requested by TMDB)
+	hold func(now time.Time) time.Duration
+
+	mu          sync.Mutex
+	queues      [2]classQueue // 按 Priority 索引
+	grants      int           // 两类都在排队时已发放的令牌数，用于 backgroundShare
+	dispatching bool
+}
+
+// acquire takes a token immediately when nothing is queued; otherwise it queues
+// the request and returns the waiter to wait on
+func (s *scheduler) acquire(ctx context.Context) (*waiter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.pending() && s.limiter.Allow() {
+		return nil, true
+	}
+
+	w := &waiter{class: PriorityFrom(ctx), session: sessionFrom(ctx), ready: make(chan struct{})}
+	s.queues[w.class].push(w)
+	if !s.dispatching {
+		s.dispatching = true
+		go s.dispatch()
+	}
+	return w, false
+}
+
+// wait blocks until w is granted a token or ctx is cancelled
+func (s *scheduler) wait(ctx context.Context, w *waiter) error {
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		// 取消与发放同时发生：令牌已属于该请求
+		return nil
+	}
+	s.queues[w.class].remove(w)
+	return ctx.Err()
+}
+
+// dispatch grants tokens to queued requests as the limiter refills; it runs
+// while requests are queued
+func (s *scheduler) dispatch() {
+	for {
+		if wait := s.hold(time.Now()); wait > 0 {
+			time.Sleep(wait)
+			continue
+		}
+		// 令牌不足时按当前速率休眠到下一个令牌可用
+		if tokens := s.limiter.Tokens(); tokens < 1 {
+			time.Sleep(time.Duration((1 - tokens) / float64(s.limiter.Limit()) * float64(time.Second)))
+		}
+
+		s.mu.Lock()
+		if !s.pending() {
+			s.dispatching = false
+			s.mu.Unlock()
+			return
+		}
+		if s.limiter.Allow() {
+			w := s.next()
+			w.granted = true
+			close(w.ready)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// pending reports whether any request is queued; s.mu must be held
+func (s *scheduler) pending() bool {
+	return s.queues[PriorityInteractive].size+s.queues[PriorityBackground].size > 0
+}
+
+// next removes and returns the waiter to serve next; s.mu must be held
+// Interactive requests go first, except that background requests get one token
+// in backgroundShare while both classes are queued
+func (s *scheduler) next() *waiter {
+	interactive := &s.queues[PriorityInteractive]
+	background := &s.queues[PriorityBackground]
+
+	queue := interactive
+	switch {
+	case interactive.size == 0:
+		queue = background
+	case background.size > 0 && s.grants%backgroundShare == backgroundShare-1:
+		queue = background
+	}
+	if interactive.size > 0 && background.size > 0 {
+		s.grants++
+	}
+	return queue.pop()
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// newTestScheduler creates a scheduler granting one token every 10ms, with no token left
+func newTestScheduler() *scheduler {
+	limiter := rate.NewLimiter(rate.Every(10*time.Millisecond), 1)
+	limiter.Allow()
+	return &scheduler{limiter: limiter, hold: func(time.Time) time.Duration { return 0 }}
+}
+
+// enqueue queues a request named name and reports it on order once it is granted
+func enqueue(t *testing.T, s *scheduler, ctx context.Context, name string, order chan<- string) {
+	t.Helper()
+	w, ok := s.acquire(ctx)
+	require.False(t, ok, "no token should be available")
+	go func() {
+		if s.wait(ctx, w) == nil {
+			order <- name
+		}
+	}()
+}
+
+// collect returns the first n names reported on order
+func collect(t *testing.T, order <-chan string, n int) []string {
+	t.Helper()
+	var names []string
+	for range n {
+		select {
+		case name := <-order:
+			names = append(names, name)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of %d requests granted: %v", len(names), n, names)
+		}
+	}
+	return names
+}
+
+// TestScheduler_InteractiveFirst tests that interactive requests are served before queued background ones
+func TestScheduler_InteractiveFirst(t *testing.T) {
+	s := newTestScheduler()
+	order := make(chan string, 8)
+	background := WithPriority(context.Background(), PriorityBackground)
+
+	enqueue(t, s, background, "b1", order)
+	enqueue(t, s, background, "b2", order)
+	enqueue(t, s, background, "b3", order)
+	enqueue(t, s, context.Background(), "i1", order)
+
+	assert.Equal(t, []string{"i1", "b1", "b2", "b3"}, collect(t, order, 4))
+}
+
+// TestScheduler_BackgroundShare tests that background requests are not starved by interactive ones
+func TestScheduler_BackgroundShare(t *testing.T) {
+	s := newTestScheduler()
+	order := make(chan string, 16)
+	background := WithPriority(context.Background(), PriorityBackground)
+
+	enqueue(t, s, background, "b", order)
+	enqueue(t, s, background, "b", order)
+	for range 8 {
+		enqueue(t, s, context.Background(), "i", order)
+	}
+
+	assert.Equal(t, []string{"i", "i", "i", "b", "i", "i", "i", "b", "i", "i"}, collect(t, order, 10))
+}
+
+// TestScheduler_SessionFairness tests that sessions of the same class are served in turn
+func TestScheduler_SessionFairness(t *testing.T) {
+	s := newTestScheduler()
+	order := make(chan string, 8)
+	batch := WithSession(context.Background(), "batch")
+	other := WithSession(context.Background(), "other")
+
+	for range 4 {
+		enqueue(t, s, batch, "batch", order)
+	}
+	enqueue(t, s, other, "other", order)
+
+	assert.Equal(t, []string{"batch", "other", "batch", "batch", "batch"}, collect(t, order, 5))
+}
+
+// TestScheduler_Cancel tests that a cancelled request leaves the queue
+func TestScheduler_Cancel(t *testing.T) {
+	s := newTestScheduler()
+	s.limiter.SetLimit(rate.Every(100 * time.Millisecond))
+	order := make(chan string, 4)
+
+	ctx, cancel := context.WithCancel(WithSession(context.Background(), "a"))
+	w, ok := s.acquire(ctx)
+	require.False(t, ok)
+	enqueue(t, s, WithSession(context.Background(), "a"), "second", order)
+
+	cancel()
+	assert.ErrorIs(t, s.wait(ctx, w), context.Canceled)
+	assert.Equal(t, []string{"second"}, collect(t, order, 1))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assert.False(t, s.pending())
+}
+
+// TestPriorityFrom tests the default and unknown scheduling classes
+func TestPriorityFrom(t *testing.T) {
+	assert.Equal(t, PriorityInteractive, PriorityFrom(context.Background()))
+	assert.Equal(t, PriorityBackground, PriorityFrom(WithPriority(context.Background(), PriorityBackground)))
+	assert.Equal(t, PriorityInteractive, PriorityFrom(WithPriority(context.Background(), Priority(7))))
+	assert.Equal(t, "background", PriorityBackground.String())
+}
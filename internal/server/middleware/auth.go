@@ -1,12 +1,18 @@
 package middleware
 
 import (
-	"crypto/subtle"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/server/tokens"
 )
 
 // authMiddleware validates Bearer Token
@@ -23,41 +29,222 @@ func AuthMiddleware(token string, next http.Handler) http.Handler {
 	})
 }
 
-// AuthMiddlewareWithLogger 采用常量时间比较并输出结构化安全日志
+// AuthMiddlewareWithLogger authenticates bearer tokens against the token store
+// and puts the matching token in the request context (see TokenName)
+// JSON-RPC tools/call messages posted by the client are checked against the
+// token's allowed tools (403) and rate limit (429 with Retry-After)
 // 失败时返回统一 JSON 错误体，避免泄露敏感信息
-func AuthMiddlewareWithLogger(logger *zap.Logger, token string, next http.Handler) http.Handler {
+// 经 ClientCertMiddleware 校验且受信任的客户端证书可代替 bearer token
+func AuthMiddlewareWithLogger(logger *zap.Logger, store *tokens.Store, next http.Handler) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		got := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
-		want := token
+		if trustedClient(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		// 将长度不匹配情况也走 ConstantTimeCompare，避免时序侧信道
-		// 若长度不同，拼接到相同长度再比较（比较结果必然不等）
-		paddedGot := got
-		paddedWant := want
-		if len(paddedGot) < len(paddedWant) {
-			paddedGot = paddedGot + strings.Repeat(" ", len(paddedWant)-len(paddedGot))
-		} else if len(paddedWant) < len(paddedGot) {
-			paddedWant = paddedWant + strings.Repeat(" ", len(paddedGot)-len(paddedWant))
+		token, err := store.Authenticate(bearerToken(r))
+		if err != nil {
+			message := "missing or invalid bearer token"
+			if errors.Is(err, tokens.ErrTokenExpired) {
+				message = "bearer token expired"
+			}
+			writeJSONError(w, http.StatusUnauthorized, "unauthorized", message)
+			logAuthFailed(logger, r, token, err)
+			return
 		}
+		serveWithToken(w, r, logger, token, next)
+	})
+}
 
-		if subtle.ConstantTimeCompare([]byte(paddedGot), []byte(paddedWant)) != 1 {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			_ = json.NewEncoder(w).Encode(map[string]string{
-				"error":   "unauthorized",
-				"message": "missing or invalid bearer token",
-			})
-			if logger != nil {
-				logger.Warn("auth failed",
-					zap.String("event", "auth_failed"),
-					zap.String("addr", r.RemoteAddr),
-					zap.String("path", r.URL.Path),
-					zap.String("method", r.Method),
-				)
+// serveWithToken authorizes the tool calls of a request authenticated with a
+// static token and passes it on with the token in its context
+func serveWithToken(w http.ResponseWriter, r *http.Request, logger *zap.Logger, token *tokens.Token, next http.Handler) {
+	if r.Method == http.MethodPost {
+		tools, err := peekToolCalls(r)
+		if err != nil {
+			writePeekError(w, err)
+			return
+		}
+		for _, tool := range tools {
+			if !authorizeToolCall(w, r, logger, token, tool) {
+				return
 			}
+		}
+	}
+	next.ServeHTTP(w, r.WithContext(tokens.WithToken(r.Context(), token)))
+}
+
+// bearerToken returns the bearer token of the Authorization header, or ""
+func bearerToken(r *http.Request) string {
+	return strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+}
+
+// logAuthFailed logs a rejected request; token is the matched (expired) static token, if any
+func logAuthFailed(logger *zap.Logger, r *http.Request, token *tokens.Token, err error) {
+	fields := []zap.Field{
+		zap.String("event", "auth_failed"),
+		zap.String("addr", r.RemoteAddr),
+		zap.String("client_identity", ClientIdentity(r)),
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	}
+	if token != nil {
+		fields = append(fields, zap.String("token", token.Name), zap.Error(err))
+	}
+	logger.Warn("auth failed", fields...)
+}
+
+// authorizeToolCall checks a tools/call against the token's allowed tools and
+// rate limit, writing the error response when the call is refused
+func authorizeToolCall(w http.ResponseWriter, r *http.Request, logger *zap.Logger, token *tokens.Token, tool string) bool {
+	fields := []zap.Field{
+		zap.String("token", token.Name),
+		zap.String("tool", tool),
+		zap.String("addr", r.RemoteAddr),
+		zap.String("session_id", sessionID(r)),
+	}
+	if !token.AllowsTool(tool) {
+		writeJSONError(w, http.StatusForbidden, "forbidden", "token is not allowed to call tool "+tool)
+		logger.Warn("tool call denied", append(fields, zap.String("event", "tool_denied"))...)
+		return false
+	}
+	if ok, retryAfter := token.AllowCall(); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		writeJSONError(w, http.StatusTooManyRequests, "rate_limited", "token rate limit exceeded")
+		logger.Warn("tool call rate limited", append(fields, zap.String("event", "token_rate_limited"), zap.Duration("retry_after", retryAfter))...)
+		return false
+	}
+	logger.Info("tool call authorized", fields...)
+	return true
+}
+
+// AdminMiddleware restricts next to callers allowed to change server-wide settings:
+// static tokens with admin set (including server.sse.token) and trusted client
+// certificates. It must run after authentication; other callers get 403
+func AdminMiddleware(logger *zap.Logger, next http.Handler) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trustedClient(r) {
+			next.ServeHTTP(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+		if token := tokens.FromContext(r.Context()); token != nil && token.Admin() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeJSONError(w, http.StatusForbidden, "forbidden", "token is not allowed to change server settings")
+		logger.Warn("admin request denied",
+			zap.String("event", "admin_denied"),
+			zap.String("token", TokenName(r)),
+			zap.String("client_identity", ClientIdentity(r)),
+			zap.String("addr", r.RemoteAddr),
+			zap.String("path", r.URL.Path),
+			zap.String("method", r.Method),
+		)
+	})
+}
+
+// TokenName returns the name of the bearer token that authenticated the request,
+// or "" when it was authenticated otherwise (trusted client certificate)
+func TokenName(r *http.Request) string {
+	if t := tokens.FromContext(r.Context()); t != nil {
+		return t.Name
+	}
+	return ""
+}
+
+// maxPeekBody bounds the request body read to find tools/call messages
+const maxPeekBody = 4 << 20
+
+var (
+	// errBodyTooLarge is returned by peekToolCalls for bodies over maxPeekBody
+	errBodyTooLarge = errors.New("request body too large")
+	// errInvalidJSONRPC is returned by peekToolCalls for bodies that are not JSON-RPC messages
+	errInvalidJSONRPC = errors.New("request body is not a JSON-RPC message")
+)
+
+// rpcMessage is the part of a JSON-RPC message needed to authorize tool calls
+type rpcMessage struct {
+	Method string `json:"method"`
+	Params struct {
+		Name string `json:"name"`
+	} `json:"params"`
+}
+
+// peekToolCalls returns the tools called by the JSON-RPC message (or batch) in
+// the request body, restoring the body for the next handler
+// Bodies that cannot be checked (over maxPeekBody, or not JSON-RPC) are errors
+// rather than "no tool calls", so that they cannot bypass tool scoping
+func peekToolCalls(r *http.Request) ([]string, error) {
+	if r.Body == nil {
+		return nil, errInvalidJSONRPC
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxPeekBody {
+		return nil, errBodyTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var messages []rpcMessage
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &messages); err != nil || len(messages) == 0 {
+			return nil, errInvalidJSONRPC
+		}
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		var message rpcMessage
+		if err := json.Unmarshal(trimmed, &message); err != nil {
+			return nil, errInvalidJSONRPC
+		}
+		messages = append(messages, message)
+	default:
+		return nil, errInvalidJSONRPC
+	}
+
+	var tools []string
+	for _, m := range messages {
+		if m.Method == "tools/call" {
+			tools = append(tools, m.Params.Name)
+		}
+	}
+	return tools, nil
+}
+
+// writePeekError writes the error response for a body peekToolCalls could not check
+func writePeekError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "payload_too_large", "request body exceeds 4 MiB")
+	case errors.Is(err, errInvalidJSONRPC):
+		writeJSONError(w, http.StatusBadRequest, "bad_request", "request body is not a valid JSON-RPC message")
+	default:
+		writeJSONError(w, http.StatusBadRequest, "bad_request", "failed to read request body")
+	}
+}
+
+// sessionID returns the MCP session of the request (streamable header or SSE query)
+func sessionID(r *http.Request) string {
+	if id := r.Header.Get("Mcp-Session-Id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("sessionid")
+}
+
+// writeJSONError writes the uniform JSON error body
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":   code,
+		"message": message,
 	})
 }
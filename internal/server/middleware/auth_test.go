@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/server/tokens"
+)
+
+// TestAuthMiddlewareWithLogger tests named tokens, allowed tools and rate limits
+func TestAuthMiddlewareWithLogger(t *testing.T) {
+	store := tokens.NewStore(config.SSEConfig{
+		Tokens: []config.TokenConfig{
+			{Name: "alice", Hash: config.HashToken("alice-secret"), AllowedTools: []string{"search"}, RateLimit: 1},
+		},
+	}, zap.NewNop())
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	var tokenName, body string
+	handler := AuthMiddlewareWithLogger(zap.New(core), store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenName = TokenName(r)
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	call := func(secret, payload string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/mcp/stream", strings.NewReader(payload))
+		r.Header.Set("Authorization", "Bearer "+secret)
+		r.Header.Set("Mcp-Session-Id", "session-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := call("wrong", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// 非工具调用不消耗额度，请求体原样传递
+	rec = call("alice-secret", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice", tokenName)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, body)
+
+	rec = call("alice-secret", `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_details"}}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = call("alice-secret", `[{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search"}}]`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = call("alice-secret", `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"search"}}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// 日志记录令牌名称和会话，而不是令牌内容
+	authorized := logs.FilterMessage("tool call authorized").All()
+	require.Len(t, authorized, 1)
+	fields := authorized[0].ContextMap()
+	assert.Equal(t, "alice", fields["token"])
+	assert.Equal(t, "search", fields["tool"])
+	assert.Equal(t, "session-1", fields["session_id"])
+	for _, entry := range logs.All() {
+		for _, value := range entry.ContextMap() {
+			if text, ok := value.(string); ok {
+				assert.NotContains(t, text, "alice-secret")
+			}
+		}
+	}
+}
+
+// TestAdminMiddleware tests that only admin tokens and trusted certificates reach admin endpoints
+func TestAdminMiddleware(t *testing.T) {
+	store := tokens.NewStore(config.SSEConfig{
+		Token: "legacy-secret",
+		Tokens: []config.TokenConfig{
+			{Name: "alice", Hash: config.HashToken("alice-secret"), AllowedTools: []string{"search"}},
+			{Name: "ops", Hash: config.HashToken("ops-secret"), Admin: true},
+		},
+	}, zap.NewNop())
+	handler := AuthMiddlewareWithLogger(zap.NewNop(), store, AdminMiddleware(zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	put := func(r *http.Request) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	withToken := func(secret string) *http.Request {
+		r := httptest.NewRequest(http.MethodPut, "/logging/level", strings.NewReader(`{"level":"debug"}`))
+		r.Header.Set("Authorization", "Bearer "+secret)
+		return r
+	}
+
+	assert.Equal(t, http.StatusForbidden, put(withToken("alice-secret")))
+	assert.Equal(t, http.StatusOK, put(withToken("ops-secret")))
+	assert.Equal(t, http.StatusOK, put(withToken("legacy-secret")))
+	assert.Equal(t, http.StatusUnauthorized, put(withToken("wrong")))
+
+	// 受信任的客户端证书无需令牌
+	r := httptest.NewRequest(http.MethodPut, "/logging/level", strings.NewReader(`{"level":"debug"}`))
+	r = r.WithContext(context.WithValue(r.Context(), clientIdentityKey{}, clientIdentity{name: "ops.example.com", trusted: true}))
+	assert.Equal(t, http.StatusOK, put(r))
+}
+
+// TestAuthMiddlewareWithLogger_UncheckableBody tests that tool calls that cannot be
+// checked against the token's allowed tools are rejected instead of passed through
+func TestAuthMiddlewareWithLogger_UncheckableBody(t *testing.T) {
+	store := tokens.NewStore(config.SSEConfig{
+		Tokens: []config.TokenConfig{
+			{Name: "alice", Hash: config.HashToken("alice-secret"), AllowedTools: []string{"search"}},
+		},
+	}, zap.NewNop())
+	reached := false
+	handler := AuthMiddlewareWithLogger(zap.NewNop(), store, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		reached = true
+	}))
+
+	send := func(payload string) *httptest.ResponseRecorder {
+		reached = false
+		r := httptest.NewRequest(http.MethodPost, "/mcp/stream", strings.NewReader(payload))
+		r.Header.Set("Authorization", "Bearer alice-secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// 填充到超过上限的 tools/call
+	oversized := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_details","arguments":{"pad":"` +
+		strings.Repeat("x", maxPeekBody) + `"}}}`
+	rec := send(oversized)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.False(t, reached)
+
+	// 无法解析的 tools/call
+	for _, payload := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_details"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":["get_details"]}}`,
+		`[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_details"}},`,
+		``,
+	} {
+		rec = send(payload)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, payload)
+		assert.False(t, reached, payload)
+	}
+
+	rec = send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, reached)
+}
+
+// TestAuthMiddlewareWithLogger_Expired tests that expired tokens are rejected by name
+func TestAuthMiddlewareWithLogger_Expired(t *testing.T) {
+	store := tokens.NewStore(config.SSEConfig{
+		Tokens: []config.TokenConfig{
+			{Name: "old", Hash: config.HashToken("old-secret"), ExpiresAt: time.Now().Add(-time.Minute)},
+		},
+	}, zap.NewNop())
+	core, logs := observer.New(zapcore.InfoLevel)
+	handler := AuthMiddlewareWithLogger(zap.New(core), store, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/mcp/sse", nil)
+	r.Header.Set("Authorization", "Bearer old-secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "expired")
+	require.Equal(t, 1, logs.FilterMessage("auth failed").Len())
+	assert.Equal(t, "old", logs.FilterMessage("auth failed").All()[0].ContextMap()["token"])
+}
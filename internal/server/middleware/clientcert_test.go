@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/server/tokens"
+)
+
+// requestWithClientCert returns a request carrying a verified client certificate for cn
+func requestWithClientCert(cn string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/mcp/stream", nil)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}, DNSNames: []string{cn + ".vpn.internal"}}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return r
+}
+
+// TestClientCertMiddleware tests allowlisting and trusted client certificates
+func TestClientCertMiddleware(t *testing.T) {
+	var identity string
+	protected := AuthMiddlewareWithLogger(zap.NewNop(), tokens.NewStore(config.SSEConfig{Token: "secret-token"}, zap.NewNop()), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = ClientIdentity(r)
+	}))
+
+	tests := []struct {
+		name     string
+		allowed  []string
+		trust    bool
+		request  *http.Request
+		status   int
+		identity string
+	}{
+		{"allowed and trusted", []string{"agent-1"}, true, requestWithClientCert("agent-1"), http.StatusOK, "agent-1"},
+		{"allowed by SAN", []string{"agent-1.vpn.internal"}, true, requestWithClientCert("agent-1"), http.StatusOK, "agent-1"},
+		{"not allowed", []string{"agent-1"}, true, requestWithClientCert("agent-2"), http.StatusForbidden, ""},
+		{"not trusted needs token", nil, false, requestWithClientCert("agent-1"), http.StatusUnauthorized, ""},
+		{"no certificate needs token", nil, true, httptest.NewRequest(http.MethodGet, "/mcp/stream", nil), http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity = ""
+			rec := httptest.NewRecorder()
+			ClientCertMiddleware(zap.NewNop(), tt.allowed, tt.trust, protected).ServeHTTP(rec, tt.request)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.identity, identity)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+)
+
+// defaultClientKeyHeader is checked in disabled mode when no header is configured
+const defaultClientKeyHeader = "X-TMDB-API-Key"
+
+// ClientKeyMiddleware reads TMDB credentials (v3 API key or v4 read access token)
+// supplied by the client in the configured header and puts them in the request
+// context, so the session's TMDB calls use a per-key client (see tmdb.WithCredentials)
+// Policy by cfg.Mode: disabled rejects requests carrying the header with 403,
+// required rejects requests without it with 400, optional falls back to the
+// server's key; malformed values are rejected with 400
+// The header is removed before the request is passed on and its value is never
+// logged, only a fingerprint
+func ClientKeyMiddleware(logger *zap.Logger, cfg config.ClientKeysConfig, next http.Handler) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	header := cfg.Header
+	if header == "" {
+		header = defaultClientKeyHeader
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(header)
+		r.Header.Del(header)
+
+		reject := func(status int, code, message string) {
+			writeJSONError(w, status, code, message)
+			logger.Warn("client TMDB key rejected",
+				zap.String("event", "client_key_rejected"),
+				zap.String("addr", r.RemoteAddr),
+				zap.String("path", r.URL.Path),
+				zap.String("reason", message),
+			)
+		}
+
+		switch {
+		case value == "" && cfg.Mode == "required":
+			reject(http.StatusBadRequest, "bad_request", "missing "+header+" header")
+			return
+		case value == "":
+			next.ServeHTTP(w, r)
+			return
+		case !cfg.Enabled():
+			reject(http.StatusForbidden, "forbidden", "client-supplied TMDB keys are disabled")
+			return
+		}
+
+		creds, err := tmdb.ParseCredentials(value)
+		if err != nil {
+			reject(http.StatusBadRequest, "bad_request", "invalid "+header+" header: "+err.Error())
+			return
+		}
+		logger.Debug("client TMDB key accepted",
+			zap.String("tmdb_key", creds.Fingerprint()),
+			zap.String("addr", r.RemoteAddr),
+			zap.String("session_id", sessionID(r)),
+		)
+		next.ServeHTTP(w, r.WithContext(tmdb.WithCredentials(r.Context(), creds)))
+	})
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+)
+
+// TestClientKeyMiddleware tests the client key policies
+func TestClientKeyMiddleware(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name   string
+		mode   string
+		value  string
+		status int
+		passed bool
+	}{
+		{"disabled without header", "disabled", "", http.StatusOK, false},
+		{"disabled with header", "disabled", key, http.StatusForbidden, false},
+		{"optional without header", "optional", "", http.StatusOK, false},
+		{"optional with key", "optional", key, http.StatusOK, true},
+		{"required without header", "required", "", http.StatusBadRequest, false},
+		{"required with key", "required", key, http.StatusOK, true},
+		{"invalid key", "optional", "not-a-key", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var passed bool
+			var header string
+			handler := ClientKeyMiddleware(zap.NewNop(), config.ClientKeysConfig{Mode: tt.mode, Header: "X-TMDB-API-Key"},
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, passed = tmdb.CredentialsFromContext(r.Context())
+					header = r.Header.Get("X-TMDB-API-Key")
+				}))
+			r := httptest.NewRequest(http.MethodPost, "/mcp/stream", nil)
+			if tt.value != "" {
+				r.Header.Set("X-TMDB-API-Key", tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.passed, passed)
+			assert.Empty(t, header, "header must not reach the MCP handler")
+			if tt.value != "" {
+				assert.NotContains(t, rec.Body.String(), tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/server/oauth"
+	"github.com/XDwanj/tmdb-mcp/internal/server/tokens"
+)
+
+// OAuthMiddleware authenticates OAuth 2.1 access tokens with verifier and puts the
+// principal in the request context (see OAuthSubject)
+// Failures carry a WWW-Authenticate challenge pointing at the protected resource
+// metadata: 401 with invalid_token for missing or invalid tokens, 403 with
+// insufficient_scope when a required scope is missing
+// When static is not nil, static bearer tokens from the store are accepted as
+// well, with their allowed tools and rate limits (see AuthMiddlewareWithLogger)
+// 经 ClientCertMiddleware 校验且受信任的客户端证书可代替 bearer token
+func OAuthMiddleware(logger *zap.Logger, verifier *oauth.Verifier, static *tokens.Store, next http.Handler) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trustedClient(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		secret := bearerToken(r)
+		if secret == "" {
+			w.Header().Set("WWW-Authenticate", verifier.Challenge("", ""))
+			writeJSONError(w, http.StatusUnauthorized, "unauthorized", "missing bearer token")
+			logAuthFailed(logger, r, nil, nil)
+			return
+		}
+
+		if static != nil {
+			token, err := static.Authenticate(secret)
+			if err == nil {
+				serveWithToken(w, r, logger, token, next)
+				return
+			}
+			if errors.Is(err, tokens.ErrTokenExpired) {
+				w.Header().Set("WWW-Authenticate", verifier.Challenge("invalid_token", "bearer token expired"))
+				writeJSONError(w, http.StatusUnauthorized, "unauthorized", "bearer token expired")
+				logAuthFailed(logger, r, token, err)
+				return
+			}
+		}
+
+		principal, err := verifier.Verify(r.Context(), secret)
+		if err != nil {
+			status, code := http.StatusUnauthorized, "invalid_token"
+			if errors.Is(err, oauth.ErrInsufficientScope) {
+				status, code = http.StatusForbidden, "insufficient_scope"
+			}
+			w.Header().Set("WWW-Authenticate", verifier.Challenge(code, err.Error()))
+			writeJSONError(w, status, code, err.Error())
+			fields := []zap.Field{
+				zap.String("event", "auth_failed"),
+				zap.String("addr", r.RemoteAddr),
+				zap.String("path", r.URL.Path),
+				zap.String("method", r.Method),
+				zap.Error(err),
+			}
+			if principal != nil {
+				fields = append(fields, zap.String("subject", principal.Subject), zap.String("client_id", principal.ClientID))
+			}
+			logger.Warn("auth failed", fields...)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			tools, err := peekToolCalls(r)
+			if err != nil {
+				writePeekError(w, err)
+				return
+			}
+			for _, tool := range tools {
+				logger.Info("tool call authorized",
+					zap.String("subject", principal.Subject),
+					zap.String("client_id", principal.ClientID),
+					zap.String("tool", tool),
+					zap.String("addr", r.RemoteAddr),
+					zap.String("session_id", sessionID(r)),
+				)
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(oauth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// OAuthSubject returns the subject of the OAuth access token that authenticated
+// the request, or "" when it was authenticated otherwise
+func OAuthSubject(r *http.Request) string {
+	if p := oauth.FromContext(r.Context()); p != nil {
+		return p.Subject
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/server/oauth"
+	"github.com/XDwanj/tmdb-mcp/internal/server/tokens"
+)
+
+// TestOAuthMiddleware tests challenges, JWT access tokens and static tokens
+func TestOAuthMiddleware(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	set, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "k1", Use: "sig"}}})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, set, 0600))
+
+	verifier, err := oauth.NewVerifier(config.OAuthConfig{
+		Enabled:              true,
+		Resource:             "https://mcp.example.com",
+		AuthorizationServers: []string{"https://auth.example.com"},
+		JWKSFile:             path,
+		RequiredScopes:       []string{"tmdb:read"},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "k1"))
+	require.NoError(t, err)
+	issue := func(scope string) string {
+		raw, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Issuer:   "https://auth.example.com",
+			Subject:  "alice@example.com",
+			Audience: jwt.Audience{"https://mcp.example.com"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}).Claims(map[string]any{"scope": scope}).Serialize()
+		require.NoError(t, err)
+		return raw
+	}
+
+	static := tokens.NewStore(config.SSEConfig{Token: "static-secret"}, zap.NewNop())
+	metadataURL := `resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource"`
+
+	tests := []struct {
+		name      string
+		static    *tokens.Store
+		token     string
+		status    int
+		challenge string
+		subject   string
+		tokenName string
+	}{
+		{"no token", nil, "", http.StatusUnauthorized, `Bearer scope="tmdb:read", ` + metadataURL, "", ""},
+		{"invalid token", nil, "garbage", http.StatusUnauthorized, `Bearer error="invalid_token"`, "", ""},
+		{"insufficient scope", nil, issue("profile"), http.StatusForbidden, `Bearer error="insufficient_scope"`, "", ""},
+		{"valid token", nil, issue("tmdb:read"), http.StatusOK, "", "alice@example.com", ""},
+		{"static token rejected", nil, "static-secret", http.StatusUnauthorized, `error="invalid_token"`, "", ""},
+		{"static token allowed", static, "static-secret", http.StatusOK, "", "", tokens.LegacyName},
+		{"jwt with static allowed", static, issue("tmdb:read"), http.StatusOK, "", "alice@example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject, tokenName string
+			handler := OAuthMiddleware(zap.NewNop(), verifier, tt.static, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				subject, tokenName = OAuthSubject(r), TokenName(r)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/mcp/stream", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Contains(t, rec.Header().Get("WWW-Authenticate"), tt.challenge)
+			if tt.status != http.StatusOK {
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), metadataURL)
+			}
+			assert.Equal(t, tt.subject, subject)
+			assert.Equal(t, tt.tokenName, tokenName)
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+const (
+	// fileCheckInterval is how often a JWKS file is checked for changes
+	fileCheckInterval = 5 * time.Second
+	// urlRefreshInterval is how often a JWKS URL is fetched again
+	urlRefreshInterval = 15 * time.Minute
+	// urlMinRefreshInterval bounds refetches triggered by unknown key IDs,
+	// so tokens with made-up kids cannot flood the authorization server
+	urlMinRefreshInterval = 30 * time.Second
+	// maxJWKSSize bounds the JWKS document
+	maxJWKSSize = 1 << 20
+)
+
+// keySource provides the signing keys of the authorization server from a JWKS file or URL
+// Keys are loaded outside mu (one load at a time, shared by concurrent requests),
+// so a slow or unreachable authorization server does not block tokens whose key
+// is already known
+type keySource struct {
+	file   string
+	url    string
+	client *http.Client
+	logger *zap.Logger
+	group  singleflight.Group
+
+	mu        sync.Mutex
+	keys      jose.JSONWebKeySet
+	loaded    time.Time // 最近一次成功加载
+	attempted time.Time // 最近一次尝试加载（文件：检查变更）
+	modTime   time.Time
+	size      int64
+}
+
+// newKeySource creates the key source of cfg without loading it
+func newKeySource(cfg config.OAuthConfig, logger *zap.Logger) *keySource {
+	return &keySource{
+		file:   cfg.JWKSFile,
+		url:    cfg.JWKSURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+}
+
+// lookup returns the signature keys matching kid (all signature keys when kid is empty)
+// An unknown kid triggers a refetch of a JWKS URL, as the authorization server
+// may have rotated its keys; a known kid is served at once and a due periodic
+// refetch runs in the background
+func (k *keySource) lookup(ctx context.Context, kid string) []jose.JSONWebKey {
+	k.mu.Lock()
+	keys := k.matchLocked(kid)
+	due := k.dueLocked(len(keys) == 0)
+	k.mu.Unlock()
+	if !due {
+		return keys
+	}
+
+	if len(keys) > 0 && k.url != "" {
+		go k.refresh(context.WithoutCancel(ctx))
+		return keys
+	}
+	k.refresh(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.matchLocked(kid)
+}
+
+// load loads the key set, returning the error
+func (k *keySource) load(ctx context.Context) error {
+	k.mu.Lock()
+	k.attempted = time.Now()
+	k.mu.Unlock()
+	return k.reload(ctx)
+}
+
+// dueLocked reports whether the key set should be reloaded now, and if so records
+// the attempt so that concurrent requests do not reload it as well
+// force (unknown kid) refetches a URL early, still no more often than
+// urlMinRefreshInterval, so tokens with made-up kids cannot trigger a fetch each
+func (k *keySource) dueLocked(force bool) bool {
+	now := time.Now()
+	since := now.Sub(k.attempted)
+	switch {
+	case k.file != "":
+		if since < fileCheckInterval {
+			return false
+		}
+		k.attempted = now
+		info, err := os.Stat(k.file)
+		return err == nil && (!info.ModTime().Equal(k.modTime) || info.Size() != k.size)
+	case force:
+		if since < urlMinRefreshInterval {
+			return false
+		}
+	default:
+		if !k.loaded.IsZero() && now.Sub(k.loaded) < urlRefreshInterval {
+			return false
+		}
+		if since < urlMinRefreshInterval {
+			return false
+		}
+	}
+	k.attempted = now
+	return true
+}
+
+// refresh reloads the key set, keeping the previous keys when it fails
+func (k *keySource) refresh(ctx context.Context) {
+	if err := k.reload(ctx); err != nil && ctx.Err() == nil {
+		k.logger.Warn("Failed to reload JWKS, keeping previous keys", zap.Error(err))
+	}
+}
+
+// reload reads the key set from the file or URL and swaps it in
+// Concurrent callers share one load; a caller whose ctx ends stops waiting for it
+func (k *keySource) reload(ctx context.Context) error {
+	ch := k.group.DoChan("jwks", func() (any, error) {
+		// 不随单个请求取消：结果会被其他请求复用
+		return nil, k.loadKeys(context.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loadKeys reads and parses the key set without holding mu, then swaps it in
+func (k *keySource) loadKeys(ctx context.Context) error {
+	var data []byte
+	var info os.FileInfo
+	var err error
+	if k.file != "" {
+		if info, err = os.Stat(k.file); err != nil {
+			return fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+		if data, err = os.ReadFile(k.file); err != nil {
+			return fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+	} else if data, err = k.fetch(ctx); err != nil {
+		return err
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	if len(keys.Keys) == 0 {
+		return fmt.Errorf("JWKS contains no keys")
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loaded = time.Now()
+	if info != nil {
+		k.modTime, k.size = info.ModTime(), info.Size()
+	}
+	k.mu.Unlock()
+	k.logger.Info("JWKS loaded", zap.Int("keys", len(keys.Keys)), zap.String("source", k.source()))
+	return nil
+}
+
+// fetch downloads the JWKS document
+func (k *keySource) fetch(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, k.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// matchLocked returns the signature keys with the given kid
+func (k *keySource) matchLocked(kid string) []jose.JSONWebKey {
+	var keys []jose.JSONWebKey
+	for _, key := range k.keys.Keys {
+		if key.Use == "enc" || (kid != "" && key.KeyID != kid) {
+			continue
+		}
+		keys = append(keys, key.Public())
+	}
+	return keys
+}
+
+// source describes where keys are loaded from
+func (k *keySource) source() string {
+	if k.file != "" {
+		return k.file
+	}
+	return k.url
+}
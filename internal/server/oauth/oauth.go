@@ -0,0 +1,249 @@
+// Package oauth makes the HTTP endpoints an OAuth 2.1 protected resource, as
+// required by the MCP authorization specification.
+// Access tokens are JWTs issued by an external authorization server; the
+// Verifier checks their signature against the server's JWKS (a local file or a
+// URL) and their issuer, audience, lifetime and scopes. Clients discover the
+// authorization server through the protected resource metadata (RFC 9728)
+// advertised in WWW-Authenticate challenges.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// MetadataPath is where the protected resource metadata is served
+const MetadataPath = "/.well-known/oauth-protected-resource"
+
+// ErrInvalidToken is returned for access tokens that are malformed, not signed by
+// a known key, expired, or issued for another issuer or audience
+var ErrInvalidToken = errors.New("invalid access token")
+
+// ErrInsufficientScope is returned for valid access tokens missing a required scope
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// clockLeeway tolerates clock skew between this server and the authorization server
+const clockLeeway = 30 * time.Second
+
+// signatureAlgorithms are the accepted JWS algorithms (asymmetric only)
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// Principal is the identity carried by a verified access token
+type Principal struct {
+	Subject  string
+	ClientID string // client_id 或 azp 声明
+	Scopes   []string
+	Expiry   time.Time
+}
+
+// Verifier verifies access tokens and describes the protected resource
+type Verifier struct {
+	cfg    config.OAuthConfig
+	keys   *keySource
+	logger *zap.Logger
+}
+
+// NewVerifier creates a verifier for cfg
+// A JWKS file must be readable at startup; a JWKS URL that cannot be fetched yet
+// is retried when tokens arrive, so the server can start before the authorization server
+func NewVerifier(cfg config.OAuthConfig, logger *zap.Logger) (*Verifier, error) {
+	keys := newKeySource(cfg, logger)
+	if err := keys.load(context.Background()); err != nil {
+		if cfg.JWKSFile != "" {
+			return nil, err
+		}
+		logger.Warn("Failed to fetch JWKS, retrying on first request", zap.String("jwks_url", cfg.JWKSURL), zap.Error(err))
+	}
+	return &Verifier{cfg: cfg, keys: keys, logger: logger}, nil
+}
+
+// Verify checks raw and returns the principal it identifies
+// Errors wrap ErrInvalidToken or ErrInsufficientScope
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Principal, error) {
+	token, err := jwt.ParseSigned(raw, signatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var claims jwt.Claims
+	var extra extraClaims
+	verified := false
+	for _, key := range v.keys.lookup(ctx, token.Headers[0].KeyID) {
+		if token.Claims(key.Key, &claims, &extra) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: signature not verified by any known key", ErrInvalidToken)
+	}
+
+	if claims.Expiry == nil {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalidToken)
+	}
+	expected := jwt.Expected{
+		Issuer:      v.cfg.ExpectedIssuer(),
+		AnyAudience: v.cfg.ExpectedAudience(),
+		Time:        time.Now(),
+	}
+	if err := claims.ValidateWithLeeway(expected, clockLeeway); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, claimError(err))
+	}
+
+	principal := &Principal{
+		Subject:  claims.Subject,
+		ClientID: extra.ClientID,
+		Scopes:   append(extra.Scope, extra.Scp...),
+		Expiry:   claims.Expiry.Time(),
+	}
+	if principal.ClientID == "" {
+		principal.ClientID = extra.AZP
+	}
+	for _, scope := range v.cfg.RequiredScopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return principal, fmt.Errorf("%w: missing scope %s", ErrInsufficientScope, scope)
+		}
+	}
+	return principal, nil
+}
+
+// claimError describes a claim validation failure without echoing token contents
+func claimError(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrExpired):
+		return "token expired"
+	case errors.Is(err, jwt.ErrNotValidYet), errors.Is(err, jwt.ErrIssuedInTheFuture):
+		return "token not valid yet"
+	case errors.Is(err, jwt.ErrInvalidIssuer):
+		return "unexpected issuer"
+	case errors.Is(err, jwt.ErrInvalidAudience):
+		return "token not issued for this resource"
+	default:
+		return "invalid claims"
+	}
+}
+
+// extraClaims are the non-registered claims read from access tokens
+type extraClaims struct {
+	Scope    scopeList `json:"scope"` // RFC 9068: 空格分隔的字符串
+	Scp      scopeList `json:"scp"`   // 部分授权服务器使用的数组形式
+	ClientID string    `json:"client_id"`
+	AZP      string    `json:"azp"`
+}
+
+// scopeList decodes scopes given as a space-separated string or an array
+type scopeList []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *scopeList) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = strings.Fields(text)
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// Metadata returns the protected resource metadata (RFC 9728)
+func (v *Verifier) Metadata() *oauthex.ProtectedResourceMetadata {
+	scopes := v.cfg.ScopesSupported
+	if len(scopes) == 0 {
+		scopes = v.cfg.RequiredScopes
+	}
+	return &oauthex.ProtectedResourceMetadata{
+		Resource:               v.cfg.Resource,
+		AuthorizationServers:   v.cfg.AuthorizationServers,
+		ScopesSupported:        scopes,
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "TMDB MCP Server",
+	}
+}
+
+// MetadataURL returns the URL of the metadata document for the configured resource,
+// inserting the well-known path between host and path as in RFC 9728 section 3.1
+func (v *Verifier) MetadataURL() string {
+	u, err := url.Parse(v.cfg.Resource)
+	if err != nil {
+		return v.cfg.Resource + MetadataPath
+	}
+	u.Path = MetadataPath + strings.TrimSuffix(u.Path, "/")
+	u.RawPath, u.RawQuery, u.Fragment = "", "", ""
+	return u.String()
+}
+
+// MetadataHandler serves the protected resource metadata
+// It is public (no token) and allows cross-origin reads for browser-based clients
+func (v *Verifier) MetadataHandler() http.Handler {
+	body, _ := json.Marshal(v.Metadata())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write(body)
+	})
+}
+
+// Challenge returns the WWW-Authenticate header value for a failed request (RFC 6750)
+// code is "" when no token was sent, otherwise invalid_token or insufficient_scope
+func (v *Verifier) Challenge(code, description string) string {
+	params := []string{}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", strings.NewReplacer(`"`, "", `\`, "").Replace(description)))
+	}
+	if len(v.cfg.RequiredScopes) > 0 && code != "invalid_token" {
+		params = append(params, fmt.Sprintf("scope=%q", strings.Join(v.cfg.RequiredScopes, " ")))
+	}
+	params = append(params, fmt.Sprintf("resource_metadata=%q", v.MetadataURL()))
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// principalKey stores the verified principal in the request context
+type principalKey struct{}
+
+// WithPrincipal returns ctx carrying the verified principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the verified principal of ctx, or nil
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,261 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// testKey is a signing key of the test authorization server
+type testKey struct {
+	kid     string
+	private *ecdsa.PrivateKey
+}
+
+// newTestKey generates an ES256 signing key
+func newTestKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return testKey{kid: kid, private: private}
+}
+
+// jwks returns the public JWKS document of keys
+func jwks(t *testing.T, keys ...testKey) []byte {
+	t.Helper()
+	set := jose.JSONWebKeySet{}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{Key: &k.private.PublicKey, KeyID: k.kid, Algorithm: string(jose.ES256), Use: "sig"})
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+// sign issues an access token with claims and extra claims
+func (k testKey) sign(t *testing.T, claims jwt.Claims, extra map[string]any) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: k.private},
+		(&jose.SignerOptions{}).WithType("at+jwt").WithHeader("kid", k.kid))
+	require.NoError(t, err)
+	raw, err := jwt.Signed(signer).Claims(claims).Claims(extra).Serialize()
+	require.NoError(t, err)
+	return raw
+}
+
+// testConfig returns an OAuth configuration using the JWKS file at path
+func testConfig(path string) config.OAuthConfig {
+	return config.OAuthConfig{
+		Enabled:              true,
+		Resource:             "https://mcp.example.com",
+		AuthorizationServers: []string{"https://auth.example.com"},
+		JWKSFile:             path,
+		RequiredScopes:       []string{"tmdb:read"},
+	}
+}
+
+// validClaims returns claims accepted by testConfig
+func validClaims() jwt.Claims {
+	now := time.Now()
+	return jwt.Claims{
+		Issuer:   "https://auth.example.com",
+		Subject:  "alice",
+		Audience: jwt.Audience{"https://mcp.example.com"},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+// TestVerifier_Verify tests issuer, audience, expiry, scope and signature checks
+func TestVerifier_Verify(t *testing.T) {
+	key := newTestKey(t, "key-1")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(t, key), 0600))
+
+	verifier, err := NewVerifier(testConfig(path), zap.NewNop())
+	require.NoError(t, err)
+	scopes := map[string]any{"scope": "tmdb:read profile", "client_id": "claude"}
+
+	principal, err := verifier.Verify(context.Background(), key.sign(t, validClaims(), scopes))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+	assert.Equal(t, "claude", principal.ClientID)
+	assert.Equal(t, []string{"tmdb:read", "profile"}, principal.Scopes)
+
+	// scp 数组形式同样可以
+	_, err = verifier.Verify(context.Background(), key.sign(t, validClaims(), map[string]any{"scp": []string{"tmdb:read"}}))
+	assert.NoError(t, err)
+
+	expired := validClaims()
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.Audience{"https://other.example.com"}
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://evil.example.com"
+	noExpiry := validClaims()
+	noExpiry.Expiry = nil
+
+	tests := []struct {
+		name   string
+		token  string
+		err    error
+		reason string
+	}{
+		{"expired", key.sign(t, expired, scopes), ErrInvalidToken, "token expired"},
+		{"wrong audience", key.sign(t, wrongAudience, scopes), ErrInvalidToken, "not issued for this resource"},
+		{"wrong issuer", key.sign(t, wrongIssuer, scopes), ErrInvalidToken, "unexpected issuer"},
+		{"no expiry", key.sign(t, noExpiry, scopes), ErrInvalidToken, "no expiry"},
+		{"unknown key", newTestKey(t, "key-1").sign(t, validClaims(), scopes), ErrInvalidToken, "signature"},
+		{"malformed", "not-a-jwt", ErrInvalidToken, "malformed"},
+		{"missing scope", key.sign(t, validClaims(), map[string]any{"scope": "profile"}), ErrInsufficientScope, "tmdb:read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), tt.token)
+			assert.ErrorIs(t, err, tt.err)
+			assert.ErrorContains(t, err, tt.reason)
+		})
+	}
+}
+
+// TestVerifier_JWKSURL tests fetching keys from a URL and refetching on key rotation
+func TestVerifier_JWKSURL(t *testing.T) {
+	oldKey, newKey := newTestKey(t, "old"), newTestKey(t, "new")
+	var document atomic.Value
+	document.Store(jwks(t, oldKey))
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	cfg := testConfig("")
+	cfg.JWKSURL = server.URL
+	verifier, err := NewVerifier(cfg, zap.NewNop())
+	require.NoError(t, err)
+	scopes := map[string]any{"scope": "tmdb:read"}
+
+	_, err = verifier.Verify(context.Background(), oldKey.sign(t, validClaims(), scopes))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// 授权服务器轮换密钥：未知 kid 触发重新获取
+	document.Store(jwks(t, newKey))
+	verifier.keys.attempted = time.Now().Add(-urlMinRefreshInterval)
+	_, err = verifier.Verify(context.Background(), newKey.sign(t, validClaims(), scopes))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// 重新获取有频率限制
+	_, err = verifier.Verify(context.Background(), newTestKey(t, "made-up").sign(t, validClaims(), scopes))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+// TestVerifier_JWKSURL_SlowIssuer tests that a stalled JWKS fetch only delays
+// tokens whose key is unknown, and that concurrent unknown kids share one fetch
+func TestVerifier_JWKSURL_SlowIssuer(t *testing.T) {
+	key := newTestKey(t, "known")
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		w.Write(jwks(t, key))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := testConfig("")
+	cfg.JWKSURL = server.URL
+	verifier, err := NewVerifier(cfg, zap.NewNop())
+	require.NoError(t, err)
+	scopes := map[string]any{"scope": "tmdb:read"}
+
+	// 未知 kid 触发的获取被阻塞
+	verifier.keys.mu.Lock()
+	verifier.keys.attempted = time.Now().Add(-urlMinRefreshInterval)
+	verifier.keys.mu.Unlock()
+	unknown := newTestKey(t, "unknown").sign(t, validClaims(), scopes)
+	for range 3 {
+		go verifier.Verify(context.Background(), unknown)
+	}
+	require.Eventually(t, func() bool { return fetches.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	// 已知 kid 不等待
+	done := make(chan error, 1)
+	go func() {
+		_, err := verifier.Verify(context.Background(), key.sign(t, validClaims(), scopes))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("verifying a token with a known kid waited for the JWKS fetch")
+	}
+
+	// 等待中的请求随自身 context 结束
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	verifier.keys.mu.Lock()
+	verifier.keys.attempted = time.Now().Add(-urlMinRefreshInterval)
+	verifier.keys.mu.Unlock()
+	_, err = verifier.Verify(ctx, unknown)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+// TestVerifier_Metadata tests the protected resource metadata and challenges
+func TestVerifier_Metadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(t, newTestKey(t, "key-1")), 0600))
+	cfg := testConfig(path)
+	cfg.Resource = "https://example.com/tmdb/"
+
+	verifier, err := NewVerifier(cfg, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/.well-known/oauth-protected-resource/tmdb", verifier.MetadataURL())
+
+	rec := httptest.NewRecorder()
+	verifier.MetadataHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetadataPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	var metadata map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &metadata))
+	assert.Equal(t, "https://example.com/tmdb/", metadata["resource"])
+	assert.Equal(t, []any{"https://auth.example.com"}, metadata["authorization_servers"])
+	assert.Equal(t, []any{"tmdb:read"}, metadata["scopes_supported"])
+
+	assert.Equal(t,
+		`Bearer scope="tmdb:read", resource_metadata="https://example.com/.well-known/oauth-protected-resource/tmdb"`,
+		verifier.Challenge("", ""))
+	assert.Equal(t,
+		`Bearer error="invalid_token", error_description="token expired", resource_metadata="https://example.com/.well-known/oauth-protected-resource/tmdb"`,
+		verifier.Challenge("invalid_token", `token "expired"`))
+}
+
+// TestNewVerifier_MissingFile tests that an unreadable JWKS file fails at startup
+func TestNewVerifier_MissingFile(t *testing.T) {
+	_, err := NewVerifier(testConfig(filepath.Join(t.TempDir(), "missing.json")), zap.NewNop())
+	assert.Error(t, err)
+}
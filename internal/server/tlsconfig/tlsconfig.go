@@ -0,0 +1,165 @@
+// Package tlsconfig builds the TLS configuration of the HTTP server from
+// server.sse.tls. Certificate, key and client CA files are reloaded when they
+// change on disk (checked at most every reloadCheckInterval during handshakes),
+// so rotated certificates are picked up without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// reloadCheckInterval is how often handshakes check the files for changes
+const reloadCheckInterval = 5 * time.Second
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader serves the current TLS configuration, reloading it when files change
+type Reloader struct {
+	cfg    config.TLSConfig
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	current *tls.Config
+	stamps  map[string]fileStamp
+	checked time.Time
+}
+
+// New loads the certificate (and client CA bundle) described by cfg
+func New(cfg config.TLSConfig, logger *zap.Logger) (*Reloader, error) {
+	r := &Reloader{cfg: cfg, logger: logger}
+	current, stamps, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current, r.stamps, r.checked = current, stamps, time.Now()
+	return r, nil
+}
+
+// TLSConfig returns the configuration for http.Server.TLSConfig
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         minVersion(r.cfg.MinVersion),
+		NextProtos:         []string{"h2", "http/1.1"},
+		GetConfigForClient: r.configForClient,
+	}
+}
+
+// configForClient returns the current configuration, reloading it if files changed
+func (r *Reloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= reloadCheckInterval {
+		r.checked = time.Now()
+		if r.changed() {
+			r.reload()
+		}
+	}
+	return r.current, nil
+}
+
+// changed reports whether any file differs from the loaded version
+func (r *Reloader) changed() bool {
+	for _, path := range r.files() {
+		stamp, err := stat(path)
+		if err != nil || stamp != r.stamps[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// reload replaces the current configuration, keeping the old one on failure
+// (e.g. when the certificate has been written but the key not yet)
+func (r *Reloader) reload() {
+	current, stamps, err := r.load()
+	if err != nil {
+		r.logger.Error("Failed to reload TLS certificate, keeping the current one", zap.Error(err))
+		return
+	}
+	r.current, r.stamps = current, stamps
+	r.logger.Info("TLS certificate reloaded",
+		zap.String("cert_file", r.cfg.CertFile),
+		zap.Time("not_after", current.Certificates[0].Leaf.NotAfter),
+	)
+}
+
+// load reads the files and builds the per-connection configuration
+func (r *Reloader) load() (*tls.Config, map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	for _, path := range r.files() {
+		stamp, err := stat(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		stamps[path] = stamp
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:   minVersion(r.cfg.MinVersion),
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in client CA bundle %s", r.cfg.ClientCAFile)
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		if r.cfg.ClientAuth == "optional" {
+			// 未提供证书的客户端仍可使用 bearer token
+			tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	return tlsCfg, stamps, nil
+}
+
+// files returns the files the configuration is built from
+func (r *Reloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// stat returns the current stamp of path (following symlinks, as used by Kubernetes secrets)
+func stat(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// minVersion maps server.sse.tls.min_version to a TLS version (default 1.2)
+func minVersion(version string) uint16 {
+	if version == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
@@ -0,0 +1,174 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// testCA is a certificate authority issuing test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA creates a self-signed CA
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for cn signed by the CA
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to name in dir and returns its path
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+// TestReloader_ReloadsChangedCertificate tests that a rotated certificate is served without a restart
+func TestReloader_ReloadsChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "server", 10, x509.ExtKeyUsageServerAuth)
+	cfg := config.TLSConfig{
+		Enabled:    true,
+		CertFile:   writeFile(t, dir, "tls.crt", certPEM),
+		KeyFile:    writeFile(t, dir, "tls.key", keyPEM),
+		MinVersion: "1.3",
+	}
+
+	r, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	current, err := r.configForClient(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), current.Certificates[0].Leaf.SerialNumber.Int64())
+	assert.Equal(t, uint16(tls.VersionTLS13), current.MinVersion)
+
+	// 轮换证书；旧的修改时间可能相同，因此显式推后
+	certPEM, keyPEM = ca.issue(t, "server", 11, x509.ExtKeyUsageServerAuth)
+	writeFile(t, dir, "tls.crt", certPEM)
+	writeFile(t, dir, "tls.key", keyPEM)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(cfg.CertFile, later, later))
+	r.checked = time.Time{}
+
+	current, err = r.configForClient(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), current.Certificates[0].Leaf.SerialNumber.Int64())
+}
+
+// TestReloader_KeepsCertificateOnInvalidFiles tests that a broken rotation keeps the old certificate
+func TestReloader_KeepsCertificateOnInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "server", 10, x509.ExtKeyUsageServerAuth)
+	cfg := config.TLSConfig{
+		Enabled:    true,
+		CertFile:   writeFile(t, dir, "tls.crt", certPEM),
+		KeyFile:    writeFile(t, dir, "tls.key", keyPEM),
+		MinVersion: "1.2",
+	}
+	r, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	writeFile(t, dir, "tls.crt", []byte("not a certificate"))
+	r.checked = time.Time{}
+
+	current, err := r.configForClient(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), current.Certificates[0].Leaf.SerialNumber.Int64())
+}
+
+// TestReloader_MutualTLS tests that client certificates are required and verified
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "server", 10, x509.ExtKeyUsageServerAuth)
+	r, err := New(config.TLSConfig{
+		Enabled:      true,
+		CertFile:     writeFile(t, dir, "tls.crt", certPEM),
+		KeyFile:      writeFile(t, dir, "tls.key", keyPEM),
+		MinVersion:   "1.2",
+		ClientCAFile: writeFile(t, dir, "ca.crt", ca.pem),
+		ClientAuth:   "require",
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}))
+	server.TLS = r.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCertPEM, clientKeyPEM := ca.issue(t, "agent-1", 20, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}}}}
+	resp, err := withCert.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	withoutCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = withoutCert.Get(server.URL)
+	assert.Error(t, err)
+}
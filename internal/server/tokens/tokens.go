@@ -0,0 +1,197 @@
+// Package tokens authenticates HTTP clients with named bearer tokens.
+// Tokens are configured under server.sse.tokens with only the SHA-256 hash of each
+// secret stored at rest; each token may expire, be limited to a set of tools and
+// carry its own tool-call rate limit; admin tokens may also change server-wide
+// settings. The legacy single server.sse.token is accepted as an admin token
+// named "default". The Store can be updated in place
+// (on SIGHUP) without restarting the server or resetting rate limit state.
+package tokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// LegacyName is the name of the token configured with server.sse.token
+const LegacyName = "default"
+
+// ErrInvalidToken is returned when a secret matches no configured token
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrTokenExpired is returned when a secret matches a token past its expiry
+var ErrTokenExpired = errors.New("token expired")
+
+// Token is a configured access token
+type Token struct {
+	Name      string
+	ExpiresAt time.Time // 零值表示永不过期
+
+	hash      [sha256.Size]byte
+	allowed   map[string]bool // 为空表示不限制
+	rateLimit int             // 每分钟工具调用次数，0 表示不限制
+	limiter   *rate.Limiter
+	admin     bool
+}
+
+// Admin reports whether the token may change server-wide settings (e.g. the log level)
+func (t *Token) Admin() bool {
+	return t.admin
+}
+
+// AllowsTool reports whether the token may call the named tool
+func (t *Token) AllowsTool(name string) bool {
+	return len(t.allowed) == 0 || t.allowed[name]
+}
+
+// AllowCall consumes one tool call from the token's rate limit
+// When the limit is exhausted it returns false and how long until the next call is allowed
+func (t *Token) AllowCall() (bool, time.Duration) {
+	if t.limiter == nil {
+		return true, 0
+	}
+	reservation := t.limiter.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// Store holds the configured tokens
+type Store struct {
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	tokens []*Token
+}
+
+// NewStore creates a store with the tokens of cfg
+func NewStore(cfg config.SSEConfig, logger *zap.Logger) *Store {
+	s := &Store{logger: logger}
+	s.Update(cfg)
+	return s
+}
+
+// Update replaces the configured tokens
+// Tokens keeping their name and rate limit keep their rate limiter state,
+// so reloading cannot be used to reset a client's budget
+func (s *Store) Update(cfg config.SSEConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[string]*Token, len(s.tokens))
+	for _, t := range s.tokens {
+		previous[t.Name] = t
+	}
+
+	tokens := make([]*Token, 0, len(cfg.Tokens)+1)
+	if cfg.Token != "" {
+		// 单一令牌不受工具限制，保持原有的管理权限
+		tokens = append(tokens, &Token{Name: LegacyName, hash: sha256.Sum256([]byte(cfg.Token)), admin: true})
+	}
+	for _, tc := range cfg.Tokens {
+		hash, err := decodeHash(tc.Hash)
+		if err != nil {
+			// 配置校验已拒绝无效哈希，这里仅防御
+			s.logger.Warn("Skipping token with invalid hash", zap.String("token", tc.Name), zap.Error(err))
+			continue
+		}
+		t := &Token{
+			Name:      tc.Name,
+			ExpiresAt: tc.ExpiresAt,
+			hash:      hash,
+			rateLimit: tc.RateLimit,
+			admin:     tc.Admin,
+		}
+		if len(tc.AllowedTools) > 0 {
+			t.allowed = make(map[string]bool, len(tc.AllowedTools))
+			for _, tool := range tc.AllowedTools {
+				t.allowed[tool] = true
+			}
+		}
+		if t.rateLimit > 0 {
+			if old, ok := previous[t.Name]; ok && old.rateLimit == t.rateLimit {
+				t.limiter = old.limiter
+			} else {
+				t.limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(t.rateLimit)), t.rateLimit)
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	s.tokens = tokens
+}
+
+// Authenticate returns the token matching secret
+// Every configured hash is compared in constant time, so the response time
+// does not reveal which (or whether any) token matched
+func (s *Store) Authenticate(secret string) (*Token, error) {
+	if secret == "" {
+		return nil, ErrInvalidToken
+	}
+	hash := sha256.Sum256([]byte(secret))
+
+	s.mu.RLock()
+	var match *Token
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare(hash[:], t.hash[:]) == 1 {
+			match = t
+		}
+	}
+	s.mu.RUnlock()
+
+	if match == nil {
+		return nil, ErrInvalidToken
+	}
+	if !match.ExpiresAt.IsZero() && !time.Now().Before(match.ExpiresAt) {
+		return match, ErrTokenExpired
+	}
+	return match, nil
+}
+
+// Names returns the names of the configured tokens in sorted order
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeHash decodes a "sha256:<hex>" token hash
+func decodeHash(value string) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	if err := config.ValidateTokenHash(value); err != nil {
+		return hash, err
+	}
+	_, err := hex.Decode(hash[:], []byte(strings.TrimPrefix(value, config.TokenHashPrefix)))
+	return hash, err
+}
+
+// tokenKey stores the authenticated token in the request context
+type tokenKey struct{}
+
+// WithToken returns ctx carrying the authenticated token
+func WithToken(ctx context.Context, t *Token) context.Context {
+	return context.WithValue(ctx, tokenKey{}, t)
+}
+
+// FromContext returns the authenticated token of ctx, or nil
+func FromContext(ctx context.Context) *Token {
+	t, _ := ctx.Value(tokenKey{}).(*Token)
+	return t
+}
@@ -0,0 +1,116 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// testConfig returns an SSE configuration with a legacy token and three named tokens
+func testConfig() config.SSEConfig {
+	return config.SSEConfig{
+		Token: "legacy-secret",
+		Tokens: []config.TokenConfig{
+			{Name: "alice", Hash: config.HashToken("alice-secret"), AllowedTools: []string{"search"}},
+			{Name: "bob", Hash: config.HashToken("bob-secret"), RateLimit: 2, Admin: true},
+			{Name: "old", Hash: config.HashToken("old-secret"), ExpiresAt: time.Now().Add(-time.Hour)},
+		},
+	}
+}
+
+// TestStore_Authenticate tests matching secrets to named tokens
+func TestStore_Authenticate(t *testing.T) {
+	store := NewStore(testConfig(), zap.NewNop())
+	assert.Equal(t, []string{"alice", "bob", "default", "old"}, store.Names())
+
+	token, err := store.Authenticate("alice-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", token.Name)
+
+	assert.False(t, token.Admin())
+
+	token, err = store.Authenticate("legacy-secret")
+	require.NoError(t, err)
+	assert.Equal(t, LegacyName, token.Name)
+	assert.True(t, token.Admin(), "the single legacy token is an admin token")
+
+	token, err = store.Authenticate("bob-secret")
+	require.NoError(t, err)
+	assert.True(t, token.Admin())
+
+	_, err = store.Authenticate("wrong")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = store.Authenticate("")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// 过期令牌仍返回名称，便于记录日志
+	token, err = store.Authenticate("old-secret")
+	assert.ErrorIs(t, err, ErrTokenExpired)
+	require.NotNil(t, token)
+	assert.Equal(t, "old", token.Name)
+}
+
+// TestToken_Limits tests allowed tools and per-token rate limits
+func TestToken_Limits(t *testing.T) {
+	store := NewStore(testConfig(), zap.NewNop())
+
+	alice, err := store.Authenticate("alice-secret")
+	require.NoError(t, err)
+	assert.True(t, alice.AllowsTool("search"))
+	assert.False(t, alice.AllowsTool("get_details"))
+	ok, _ := alice.AllowCall()
+	assert.True(t, ok, "no rate limit configured")
+
+	bob, err := store.Authenticate("bob-secret")
+	require.NoError(t, err)
+	assert.True(t, bob.AllowsTool("get_details"), "empty allowed_tools allows every tool")
+	for i := 0; i < 2; i++ {
+		ok, _ := bob.AllowCall()
+		assert.True(t, ok)
+	}
+	ok, retryAfter := bob.AllowCall()
+	assert.False(t, ok)
+	assert.Greater(t, retryAfter, 20*time.Second)
+}
+
+// TestStore_Update tests reloading tokens while keeping rate limit state
+func TestStore_Update(t *testing.T) {
+	cfg := testConfig()
+	store := NewStore(cfg, zap.NewNop())
+
+	bob, err := store.Authenticate("bob-secret")
+	require.NoError(t, err)
+	bob.AllowCall()
+	bob.AllowCall()
+
+	// 轮换 alice 的令牌，移除 legacy 令牌
+	cfg.Token = ""
+	cfg.Tokens[0].Hash = config.HashToken("alice-rotated")
+	store.Update(cfg)
+
+	_, err = store.Authenticate("alice-secret")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = store.Authenticate("legacy-secret")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = store.Authenticate("alice-rotated")
+	assert.NoError(t, err)
+
+	// 重新加载不会重置 bob 的额度
+	bob, err = store.Authenticate("bob-secret")
+	require.NoError(t, err)
+	ok, _ := bob.AllowCall()
+	assert.False(t, ok)
+
+	// 修改限额则使用新的限流器
+	cfg.Tokens[1].RateLimit = 10
+	store.Update(cfg)
+	bob, err = store.Authenticate("bob-secret")
+	require.NoError(t, err)
+	ok, _ = bob.AllowCall()
+	assert.True(t, ok)
+}
@@ -0,0 +1,113 @@
+// Package sessionlog forwards selected log entries (retries, rate-limit waits,
+// TMDB errors) to the MCP session that triggered them as notifications/message.
+// The session is attached to the request context, so that deeper layers can log
+// through Logger without knowing about the MCP session.
+package sessionlog
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// contextKey is used for context values (避免 key 冲突)
+type contextKey string
+
+// sessionKey is the context key for the MCP session receiving forwarded log entries
+const sessionKey contextKey = "mcp_session"
+
+// sessionLoggerName is reported as the "logger" field of notifications/message
+const sessionLoggerName = "tmdb-mcp"
+
+// WithSession returns a copy of ctx carrying the MCP session that log entries
+// written through Logger are forwarded to
+func WithSession(ctx context.Context, session *mcp.ServerSession) context.Context {
+	return context.WithValue(ctx, sessionKey, session)
+}
+
+// Logger returns a logger that writes to base and, if ctx carries an MCP session,
+// also forwards each entry to that session as notifications/message
+// The session filters entries by the level requested via logging/setLevel, so
+// forwarding is independent of the process-wide level
+func Logger(ctx context.Context, base *zap.Logger) *zap.Logger {
+	session, ok := ctx.Value(sessionKey).(*mcp.ServerSession)
+	if !ok || session == nil {
+		return base
+	}
+	// 转发不应因请求被取消而失败（例如记录"等待已取消"）
+	forward := &sessionCore{ctx: context.WithoutCancel(ctx), session: session}
+	return base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, forward)
+	}))
+}
+
+// sessionCore is a zapcore.Core that sends entries to an MCP session
+type sessionCore struct {
+	ctx     context.Context
+	session *mcp.ServerSession
+	fields  []zapcore.Field
+}
+
+// Enabled always returns true; ServerSession.Log applies the session's level
+func (c *sessionCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+// With adds structured context to the core
+func (c *sessionCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+	return &clone
+}
+
+// Check adds the core to the checked entry
+func (c *sessionCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+// Write encodes the entry fields and sends them as a logging notification
+func (c *sessionCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	data := enc.Fields
+	data["message"] = entry.Message
+
+	// 发送失败（例如会话已关闭）不影响本地日志
+	_ = c.session.Log(c.ctx, &mcp.LoggingMessageParams{
+		Level:  mcpLevel(entry.Level),
+		Logger: sessionLoggerName,
+		Data:   data,
+	})
+	return nil
+}
+
+// Sync is a no-op; notifications are sent synchronously
+func (c *sessionCore) Sync() error {
+	return nil
+}
+
+// mcpLevel maps a zap level to the corresponding MCP logging level
+func mcpLevel(level zapcore.Level) mcp.LoggingLevel {
+	switch level {
+	case zapcore.DebugLevel:
+		return "debug"
+	case zapcore.InfoLevel:
+		return "info"
+	case zapcore.WarnLevel:
+		return "warning"
+	case zapcore.ErrorLevel:
+		return "error"
+	case zapcore.FatalLevel:
+		return "emergency"
+	default:
+		// DPanic / Panic
+		return "critical"
+	}
+}
@@ -0,0 +1,106 @@
+package sessionlog
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// messageRecorder collects logging notifications received by a test client
+type messageRecorder struct {
+	mu       sync.Mutex
+	messages []*mcp.LoggingMessageParams
+}
+
+func (r *messageRecorder) handle(_ context.Context, req *mcp.LoggingMessageRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, req.Params)
+}
+
+func (r *messageRecorder) snapshot() []*mcp.LoggingMessageParams {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*mcp.LoggingMessageParams(nil), r.messages...)
+}
+
+// connect connects an in-memory client and returns both sessions
+func connect(t *testing.T, recorder *messageRecorder) (*mcp.ServerSession, *mcp.ClientSession) {
+	t.Helper()
+	ctx := context.Background()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, &mcp.ClientOptions{
+		LoggingMessageHandler: recorder.handle,
+	})
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		clientSession.Close()
+		serverSession.Close()
+	})
+	return serverSession, clientSession
+}
+
+// TestLogger_WithoutSession tests that the base logger is returned unchanged
+func TestLogger_WithoutSession(t *testing.T) {
+	base := zap.NewNop()
+	assert.Same(t, base, Logger(context.Background(), base))
+}
+
+// TestLogger_ForwardsBySessionLevel tests forwarding filtered by logging/setLevel
+func TestLogger_ForwardsBySessionLevel(t *testing.T) {
+	recorder := &messageRecorder{}
+	serverSession, clientSession := connect(t, recorder)
+	require.NoError(t, clientSession.SetLoggingLevel(context.Background(), &mcp.SetLoggingLevelParams{Level: "warning"}))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := WithSession(context.Background(), serverSession)
+	log := Logger(ctx, zap.New(core)).With(zap.String("component", "tmdb_client"))
+
+	log.Debug("Rate limiter wait completed")
+	log.Warn("Retrying TMDB API request", zap.Int("attempt", 1))
+
+	require.Eventually(t, func() bool { return len(recorder.snapshot()) == 1 }, time.Second, 10*time.Millisecond)
+
+	msg := recorder.snapshot()[0]
+	assert.Equal(t, mcp.LoggingLevel("warning"), msg.Level)
+	assert.Equal(t, "tmdb-mcp", msg.Logger)
+	data, ok := msg.Data.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "Retrying TMDB API request", data["message"])
+	assert.Equal(t, "tmdb_client", data["component"])
+	assert.Equal(t, float64(1), data["attempt"])
+
+	// 本地日志仍按进程级别过滤
+	assert.Equal(t, 1, logs.Len())
+}
+
+// TestLogger_DebugForwardedBelowProcessLevel tests that a session can receive debug
+// entries while the process-wide level stays at info
+func TestLogger_DebugForwardedBelowProcessLevel(t *testing.T) {
+	recorder := &messageRecorder{}
+	serverSession, clientSession := connect(t, recorder)
+	require.NoError(t, clientSession.SetLoggingLevel(context.Background(), &mcp.SetLoggingLevelParams{Level: "debug"}))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := WithSession(context.Background(), serverSession)
+	Logger(ctx, zap.New(core)).Debug("Rate limiter wait completed")
+
+	require.Eventually(t, func() bool { return len(recorder.snapshot()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, mcp.LoggingLevel("debug"), recorder.snapshot()[0].Level)
+	assert.Zero(t, logs.Len())
+}
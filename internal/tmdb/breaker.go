@@ -0,0 +1,208 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/cassette"
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+)
+
+// ErrCircuitOpen is returned without contacting TMDB while the circuit breaker is open
+var ErrCircuitOpen = errors.New("TMDB is unavailable (circuit breaker open)")
+
+// breakerState is the state of the circuit breaker
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// String returns the state name used in logs
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// outcome classifies a finished request for the circuit breaker
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota // TMDB 正常应答（包括 4xx）
+	outcomeFailure                // 网络错误或 5xx（ErrorTypeNetwork/ErrorTypeServer）
+	outcomeIgnored                // 调用方取消等与 TMDB 状态无关的结果
+)
+
+// requestOutcome classifies the result of a request made with ctx
+// A call that ran out of tmdb.retry.timeout is a failure; other cancellations,
+// exhausted key pools and requests missing from a replayed cassette are ignored
+func requestOutcome(ctx context.Context, resp *resty.Response, err error) outcome {
+	if err != nil {
+		if (ctx.Err() != nil && !callTimedOut(ctx)) || errors.Is(err, ErrNoHealthyKey) || errors.Is(err, cassette.ErrNoRecording) {
+			return outcomeIgnored
+		}
+		return outcomeFailure
+	}
+	if resp != nil && resp.StatusCode() >= 500 {
+		return outcomeFailure
+	}
+	return outcomeSuccess
+}
+
+// breaker is a circuit breaker around TMDB requests (tmdb.circuit_breaker)
+// It opens after threshold consecutive failures, so that calls fail immediately
+// instead of waiting through timeouts and retries during a TMDB outage; after
+// openTimeout a single probe request is let through (half-open) and its outcome
+// closes or reopens the breaker. A nil breaker lets every request through
+type breaker struct {
+	threshold   int
+	openTimeout time.Duration
+	maxStale    time.Duration // 不可用时可返回的缓存最多过期多久
+	logger      *zap.Logger
+	now         func() time.Time // 便于测试替换时间
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int // 连续失败次数
+	openedAt time.Time
+	probing  bool // 半开状态下探测请求是否在进行中
+}
+
+// newBreaker creates the circuit breaker of cfg, or nil when it is disabled
+func newBreaker(cfg config.CircuitBreakerConfig, logger *zap.Logger) *breaker {
+	if !cfg.Enabled {
+		return nil
+	}
+	metrics.CircuitBreakerState.Set(float64(breakerClosed))
+	return &breaker{
+		threshold:   cfg.FailureThreshold,
+		openTimeout: cfg.OpenTimeout,
+		maxStale:    cfg.MaxStale,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// allow reports whether a request may be sent to TMDB
+// While open it fails with ErrCircuitOpen until openTimeout has passed; then
+// one probe request is allowed at a time until the breaker closes or reopens
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if wait := b.openTimeout - b.now().Sub(b.openedAt); wait > 0 {
+			metrics.CircuitBreakerRejected.Inc()
+			return fmt.Errorf("%w, retrying in %s", ErrCircuitOpen, wait.Round(time.Second))
+		}
+		b.setState(breakerHalfOpen)
+		b.logger.Info("TMDB circuit breaker half-open, probing TMDB")
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			metrics.CircuitBreakerRejected.Inc()
+			return fmt.Errorf("%w, probing TMDB", ErrCircuitOpen)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a request allowed by allow
+func (b *breaker) record(o outcome) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch o {
+	case outcomeIgnored:
+		// 探测请求被取消时允许下一个请求继续探测
+		b.probing = false
+	case outcomeSuccess:
+		if b.state != breakerClosed {
+			b.logger.Info("TMDB circuit breaker closed, TMDB is available again")
+		}
+		b.setState(breakerClosed)
+		b.failures = 0
+		b.probing = false
+	case outcomeFailure:
+		b.failures++
+		if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+			b.setState(breakerOpen)
+			b.openedAt = b.now()
+			b.probing = false
+			b.logger.Warn("TMDB circuit breaker open, failing TMDB requests fast",
+				zap.Int("consecutive_failures", b.failures),
+				zap.Duration("open_timeout", b.openTimeout),
+			)
+		}
+	}
+}
+
+// setState changes the state and its metric; b.mu must be held
+func (b *breaker) setState(state breakerState) {
+	b.state = state
+	metrics.CircuitBreakerState.Set(float64(state))
+}
+
+// staleKey is the context key for the stale marker of a tool call
+const staleKey contextKey = "stale_marker"
+
+// staleMarker records that stale cached data was served during a tool call
+type staleMarker struct {
+	mu        sync.Mutex
+	stale     bool
+	expiredAt time.Time // 所返回的过期数据中最早的过期时间
+}
+
+// WithStaleMarker returns a copy of ctx that records whether requests made with
+// it were answered with stale cached data because TMDB was unavailable (see StaleSince)
+func WithStaleMarker(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staleKey, &staleMarker{})
+}
+
+// StaleSince reports whether stale cached data was served to requests made with
+// ctx (see WithStaleMarker), and since when the oldest of it has been expired
+func StaleSince(ctx context.Context) (time.Time, bool) {
+	m, ok := ctx.Value(staleKey).(*staleMarker)
+	if !ok {
+		return time.Time{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.expiredAt, m.stale
+}
+
+// markStale records on ctx that data expired at expiredAt was served
+func markStale(ctx context.Context, expiredAt time.Time) {
+	m, ok := ctx.Value(staleKey).(*staleMarker)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.stale || expiredAt.Before(m.expiredAt) {
+		m.expiredAt = expiredAt
+	}
+	m.stale = true
+}
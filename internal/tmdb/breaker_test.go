@@ -0,0 +1,195 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/cassette"
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// TestBreaker_States tests opening, failing fast, half-open probing and closing
+func TestBreaker_States(t *testing.T) {
+	b := newBreaker(config.CircuitBreakerConfig{Enabled: true, FailureThreshold: 2, OpenTimeout: 30 * time.Second}, zap.NewNop())
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	// 成功会重置连续失败计数
+	require.NoError(t, b.allow())
+	b.record(outcomeFailure)
+	b.record(outcomeSuccess)
+	b.record(outcomeFailure)
+	require.NoError(t, b.allow())
+	b.record(outcomeFailure)
+	assert.Equal(t, breakerOpen, b.state)
+
+	err := b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Contains(t, err.Error(), "retrying in 30s")
+
+	// 超时后只放行一个探测请求
+	now = now.Add(30 * time.Second)
+	require.NoError(t, b.allow())
+	assert.Equal(t, breakerHalfOpen, b.state)
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	// 探测被取消：下一个请求继续探测
+	b.record(outcomeIgnored)
+	require.NoError(t, b.allow())
+
+	// 探测失败：重新打开
+	b.record(outcomeFailure)
+	assert.Equal(t, breakerOpen, b.state)
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	// 探测成功：关闭
+	now = now.Add(30 * time.Second)
+	require.NoError(t, b.allow())
+	b.record(outcomeSuccess)
+	assert.Equal(t, breakerClosed, b.state)
+	assert.NoError(t, b.allow())
+}
+
+// TestBreaker_Disabled tests that a disabled breaker lets every request through
+func TestBreaker_Disabled(t *testing.T) {
+	b := newBreaker(config.CircuitBreakerConfig{}, zap.NewNop())
+	assert.Nil(t, b)
+	for range 10 {
+		b.record(outcomeFailure)
+	}
+	assert.NoError(t, b.allow())
+}
+
+// TestRequestOutcome tests which results count as TMDB failures
+func TestRequestOutcome(t *testing.T) {
+	response := func(status int) *resty.Response {
+		return &resty.Response{RawResponse: &http.Response{StatusCode: status}}
+	}
+	ctx := context.Background()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	assert.Equal(t, outcomeSuccess, requestOutcome(ctx, response(200), nil))
+	assert.Equal(t, outcomeSuccess, requestOutcome(ctx, response(404), nil))
+	assert.Equal(t, outcomeSuccess, requestOutcome(ctx, response(429), nil))
+	assert.Equal(t, outcomeFailure, requestOutcome(ctx, response(503), nil))
+	assert.Equal(t, outcomeFailure, requestOutcome(ctx, response(504), nil))
+	assert.Equal(t, outcomeFailure, requestOutcome(ctx, nil, errors.New("connection refused")))
+	assert.Equal(t, outcomeIgnored, requestOutcome(cancelled, nil, context.Canceled))
+	assert.Equal(t, outcomeIgnored, requestOutcome(ctx, nil, ErrNoHealthyKey))
+	assert.Equal(t, outcomeIgnored, requestOutcome(ctx, nil, fmt.Errorf("GET /movie/1: %w", cassette.ErrNoRecording)))
+}
+
+// TestClient_CircuitBreaker_ServesStale tests failing fast and serving expired cache entries during an outage
+func TestClient_CircuitBreaker_ServesStale(t *testing.T) {
+	var requests int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+	}))
+	defer server.Close()
+
+	cacheCfg := testCacheConfig
+	cacheCfg.TTL.Details = 10 * time.Millisecond
+	cfg := config.TMDBConfig{
+		APIKey:    "test-api-key",
+		Language:  "en-US",
+		RateLimit: 40,
+		Cache:     cacheCfg,
+		CircuitBreaker: config.CircuitBreakerConfig{
+			Enabled:          true,
+			FailureThreshold: 1,
+			OpenTimeout:      time.Minute,
+			MaxStale:         time.Hour,
+		},
+	}
+	client := NewClient(cfg, zap.NewNop())
+	client.httpClient.SetBaseURL(server.URL).SetRetryCount(0)
+
+	_, err := client.GetMovieDetails(context.Background(), 27205, nil)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+
+	// TMDB 故障：返回过期缓存并标记，熔断器打开
+	failing.Store(true)
+	ctx := WithStaleMarker(context.Background())
+	details, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Inception", details.Title)
+	_, stale := StaleSince(ctx)
+	assert.True(t, stale)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// 熔断器打开：不再请求 TMDB，仍返回过期缓存
+	details, err = client.GetMovieDetails(WithStaleMarker(context.Background()), 27205, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Inception", details.Title)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// 没有缓存时快速失败
+	_, err = client.GetMovieDetails(context.Background(), 157336, nil)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// 未经熔断器的调用不会被标记为过期
+	_, stale = StaleSince(context.Background())
+	assert.False(t, stale)
+}
+
+// TestClient_CircuitBreaker_CassetteReplay tests that requests missing from a replayed
+// cassette do not open the breaker for recorded ones
+func TestClient_CircuitBreaker_CassetteReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.TMDBConfig{
+		APIKey:         "test-api-key",
+		Language:       "en-US",
+		RateLimit:      40,
+		BaseURL:        server.URL,
+		CircuitBreaker: config.CircuitBreakerConfig{Enabled: true, FailureThreshold: 1, OpenTimeout: time.Minute},
+	}
+
+	// 录制一个请求
+	recorder, err := cassette.NewRecorder(dir, http.DefaultTransport)
+	require.NoError(t, err)
+	client := NewClient(cfg, zap.NewNop())
+	client.SetTransport(recorder)
+	_, err = client.GetMovieDetails(context.Background(), 27205, nil)
+	require.NoError(t, err)
+
+	// 回放：未录制的请求失败，但不会打开熔断器
+	player, err := cassette.NewPlayer(dir)
+	require.NoError(t, err)
+	client = NewClient(cfg, zap.NewNop())
+	client.SetTransport(player)
+	for range 3 {
+		_, err = client.GetMovieDetails(context.Background(), 157336, nil)
+		require.ErrorIs(t, err, cassette.ErrNoRecording)
+	}
+	details, err := client.GetMovieDetails(context.Background(), 27205, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Inception", details.Title)
+}
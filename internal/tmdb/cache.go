@@ -0,0 +1,328 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/XDwanj/tmdb-mcp/internal/cache"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+	"github.com/XDwanj/tmdb-mcp/internal/sessionlog"
+	"github.com/XDwanj/tmdb-mcp/internal/tracing"
+)
+
+// get executes a GET request within a span covering cache lookups, rate-limit waits
+// and every HTTP attempt, so a slow call shows where its time went
+// The whole call, retries included, is bounded by tmdb.retry.timeout
+// Calls carrying client-supplied credentials are handed to the per-key client
+func (c *Client) get(req *resty.Request, endpoint string) (*resty.Response, error) {
+	if client := c.clientFor(req.Context()); client != c {
+		return client.get(client.rebind(req), endpoint)
+	}
+
+	// 重试不超过单次调用的总时限（tmdb.retry.timeout）
+	ctx, cancel := c.retry.withCallTimeout(req.Context())
+	defer cancel()
+	ctx, span := tracing.Tracer().Start(ctx, "TMDB "+metrics.Endpoint(endpoint),
+		trace.WithAttributes(tracing.AttrTMDBEndpoint.String(metrics.Endpoint(endpoint))),
+	)
+	defer span.End()
+	req.SetContext(ctx)
+
+	resp, err := c.cachedGet(req, endpoint)
+	if err != nil && callTimedOut(ctx) {
+		err = &TMDBError{
+			StatusMessage: fmt.Sprintf("TMDB did not respond within %s", c.retry.timeout),
+			ErrorType:     ErrorTypeNetwork,
+			Retryable:     true,
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if resp != nil {
+		span.SetAttributes(tracing.AttrHTTPStatus.Int(resp.StatusCode()))
+	}
+	return resp, err
+}
+
+// rebind copies req, built on another client, onto c
+func (c *Client) rebind(req *resty.Request) *resty.Request {
+	return c.httpClient.R().
+		SetContext(req.Context()).
+		SetQueryParamsFromValues(req.QueryParam).
+		SetResult(req.Result)
+}
+
+// cachedGet executes a GET request, serving it from the response cache when possible
+// Lookup order: memory cache, then the on-disk cache; a stale disk record with
+// ETag/Last-Modified is revalidated with a conditional request (304 reuses its body)
+// Cache hits skip rate limiting and the API call counter; only successful
+// responses are stored, using the TTL of the endpoint's category
+// Requests that reach TMDB are coalesced with identical in-flight requests (see fetch);
+// conditional requests only with those carrying the same validators
+// When TMDB is unavailable (network error, 5xx or open circuit breaker), an
+// expired response still in the cache is served instead (see serveStale)
+func (c *Client) cachedGet(req *resty.Request, endpoint string) (*resty.Response, error) {
+	span := trace.SpanFromContext(req.Context())
+	key := c.cacheKey(endpoint, req.QueryParam)
+	ttl := c.cacheTTL(endpoint)
+	if (c.cache == nil && c.disk == nil) || ttl <= 0 {
+		span.SetAttributes(tracing.AttrTMDBCache.String("disabled"))
+		return c.fetch(req, endpoint, key)
+	}
+
+	if c.cache != nil {
+		if body, ok := c.cache.Get(key); ok {
+			if err := json.Unmarshal(body, req.Result); err == nil {
+				atomic.AddUint64(&c.cacheHits, 1)
+				metrics.CacheHits.WithLabelValues("memory").Inc()
+				span.SetAttributes(tracing.AttrTMDBCache.String("memory_hit"))
+				c.logger.Debug("TMDB cache hit", zap.String("key", key))
+				return cachedResponse(req, body), nil
+			}
+			// 缓存内容无法解析时回退到真实请求
+		}
+	}
+
+	var stale *cache.Record
+	if c.disk != nil {
+		if record, ok := c.disk.Get(key); ok {
+			if record.Fresh(time.Now()) {
+				if err := json.Unmarshal(record.Body, req.Result); err == nil {
+					atomic.AddUint64(&c.cacheHits, 1)
+					metrics.CacheHits.WithLabelValues("disk").Inc()
+					span.SetAttributes(tracing.AttrTMDBCache.String("disk_hit"))
+					c.logger.Debug("TMDB disk cache hit", zap.String("key", key))
+					if c.cache != nil {
+						c.cache.Set(key, record.Body, time.Until(record.ExpiresAt))
+					}
+					return cachedResponse(req, record.Body), nil
+				}
+			} else if record.Revalidatable() {
+				stale = record
+			}
+		}
+	}
+	atomic.AddUint64(&c.cacheMisses, 1)
+	metrics.CacheMisses.Inc()
+	span.SetAttributes(tracing.AttrTMDBCache.String("miss"))
+
+	// 过期记录带有校验信息时发送条件请求
+	// 条件请求只与校验信息相同的请求合并：没有该记录的调用方无法使用 304
+	flightKey := key
+	if stale != nil {
+		if stale.ETag != "" {
+			req.SetHeader("If-None-Match", stale.ETag)
+		}
+		if stale.LastModified != "" {
+			req.SetHeader("If-Modified-Since", stale.LastModified)
+		}
+		flightKey = key + "\x00" + stale.ETag + "\x00" + stale.LastModified
+	}
+
+	resp, err := c.fetch(req, endpoint, flightKey)
+	if c.breaker != nil && (errors.Is(err, ErrCircuitOpen) || requestOutcome(req.Context(), resp, err) == outcomeFailure) {
+		if staleResp, ok := c.serveStale(req, key); ok {
+			return staleResp, nil
+		}
+	}
+	if err != nil {
+		return resp, err
+	}
+
+	if stale != nil && resp.StatusCode() == http.StatusNotModified {
+		if err := json.Unmarshal(stale.Body, req.Result); err == nil {
+			c.logger.Debug("TMDB disk cache revalidated", zap.String("key", key))
+			span.SetAttributes(tracing.AttrTMDBCache.String("revalidated"))
+			c.store(key, stale.Body, stale.ETag, stale.LastModified, ttl)
+			return cachedResponse(req, stale.Body), nil
+		}
+	}
+
+	if resp.IsSuccess() {
+		c.store(key, resp.Body(), resp.Header().Get("ETag"), resp.Header().Get("Last-Modified"), ttl)
+	}
+	return resp, nil
+}
+
+// fetch executes req against TMDB, sharing the response with identical in-flight requests
+// Only the first caller for key sends the request (and spends a rate-limit token);
+// concurrent callers wait for it and decode the shared body into their own result
+// If the leading request was cancelled by its caller, waiting callers whose context
+// is still alive issue the request themselves
+// While the circuit breaker is open, fetch fails with ErrCircuitOpen without a request
+func (c *Client) fetch(req *resty.Request, endpoint, key string) (*resty.Response, error) {
+	// req 的 context 会在请求钩子中被替换，这里先保存调用方的 context
+	ctx := req.Context()
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	leader := false
+	ch := c.inflight.DoChan(key, func() (any, error) {
+		leader = true
+		resp, err := req.Get(endpoint)
+		c.breaker.record(requestOutcome(ctx, resp, err))
+		return resp, err
+	})
+
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	resp, _ := res.Val.(*resty.Response)
+	if leader {
+		return resp, res.Err
+	}
+
+	if res.Err != nil {
+		if errors.Is(res.Err, context.Canceled) || errors.Is(res.Err, context.DeadlineExceeded) {
+			if ctx.Err() == nil {
+				return c.fetch(req, endpoint, key)
+			}
+		}
+		return nil, res.Err
+	}
+
+	atomic.AddUint64(&c.coalesced, 1)
+	metrics.CoalescedRequests.Inc()
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrTMDBCoalesced.Bool(true))
+	c.logger.Debug("TMDB request coalesced with in-flight request", zap.String("key", key))
+	return sharedResponse(req, resp), nil
+}
+
+// serveStale decodes an expired cached response for key into req's result when
+// it expired less than tmdb.circuit_breaker.max_stale ago, and marks the call as
+// stale (see WithStaleMarker)
+func (c *Client) serveStale(req *resty.Request, key string) (*resty.Response, bool) {
+	if c.breaker.maxStale <= 0 {
+		return nil, false
+	}
+	ctx := req.Context()
+	layer := ""
+	var body []byte
+	var expiredAt time.Time
+	if c.cache != nil {
+		if value, expires, ok := c.cache.GetStale(key); ok && json.Unmarshal(value, req.Result) == nil {
+			layer, body, expiredAt = "memory", value, expires
+		}
+	}
+	if layer == "" && c.disk != nil {
+		if record, ok := c.disk.Get(key); ok && time.Since(record.ExpiresAt) < c.breaker.maxStale &&
+			json.Unmarshal(record.Body, req.Result) == nil {
+			layer, body, expiredAt = "disk", record.Body, record.ExpiresAt
+		}
+	}
+	if layer == "" {
+		return nil, false
+	}
+
+	metrics.StaleResponses.WithLabelValues(layer).Inc()
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrTMDBCache.String("stale"))
+	markStale(ctx, expiredAt)
+	sessionlog.Logger(ctx, c.logger).Warn("TMDB unavailable, serving stale cached response",
+		zap.String("key", key),
+		zap.String("layer", layer),
+		zap.Time("expired_at", expiredAt),
+	)
+	return cachedResponse(req, body), true
+}
+
+// store saves a response body in the memory and disk caches
+func (c *Client) store(key string, body []byte, etag, lastModified string, ttl time.Duration) {
+	if c.cache != nil {
+		c.cache.Set(key, body, ttl)
+	}
+	if c.disk != nil {
+		now := time.Now()
+		err := c.disk.Set(key, &cache.Record{
+			Body:         body,
+			ETag:         etag,
+			LastModified: lastModified,
+			StoredAt:     now,
+			ExpiresAt:    now.Add(ttl),
+		})
+		if err != nil {
+			c.logger.Warn("Failed to write TMDB disk cache", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// cacheKey builds the cache key from the endpoint and normalized query parameters
+// api_key is excluded; the default language is included when the request does not
+// set one, so that changing tmdb.language does not serve stale translations
+func (c *Client) cacheKey(endpoint string, params url.Values) string {
+	normalized := url.Values{}
+	for key, values := range params {
+		if key == "api_key" {
+			continue
+		}
+		normalized[key] = values
+	}
+	if normalized.Get("language") == "" && c.language != "" {
+		normalized.Set("language", c.language)
+	}
+	// Encode 按 key 排序，保证相同参数得到相同的 key
+	return endpoint + "?" + normalized.Encode()
+}
+
+// cacheTTL returns the cache TTL for the category of endpoint
+func (c *Client) cacheTTL(endpoint string) time.Duration {
+	segments := strings.Split(strings.Trim(endpoint, "/"), "/")
+	switch {
+	case segments[0] == "configuration" || segments[0] == "genre":
+		return c.cacheTTLs.Configuration
+	case segments[0] == "trending":
+		return c.cacheTTLs.Trending
+	case segments[0] == "search":
+		return c.cacheTTLs.Search
+	case segments[0] == "discover":
+		return c.cacheTTLs.Discover
+	case len(segments) == 3 && segments[2] == "recommendations":
+		return c.cacheTTLs.Recommendations
+	case len(segments) == 2 && (segments[0] == "movie" || segments[0] == "tv" || segments[0] == "person"):
+		return c.cacheTTLs.Details
+	}
+	return 0
+}
+
+// sharedResponse builds a response for req from the response of a coalesced request
+// The body is decoded into req's own result (or error) value, as resty does for the leader
+func sharedResponse(req *resty.Request, shared *resty.Response) *resty.Response {
+	resp := &resty.Response{Request: req, RawResponse: shared.RawResponse}
+	body := shared.Body()
+	if shared.IsSuccess() && req.Result != nil {
+		json.Unmarshal(body, req.Result)
+	} else if shared.IsError() && req.Error != nil {
+		json.Unmarshal(body, req.Error)
+	}
+	return resp.SetBody(body)
+}
+
+// cachedResponse builds a successful response for a cache hit
+func cachedResponse(req *resty.Request, body []byte) *resty.Response {
+	resp := &resty.Response{
+		Request: req,
+		RawResponse: &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+		},
+	}
+	return resp.SetBody(body)
+}
@@ -0,0 +1,293 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// testCacheConfig is a cache configuration with the default TTLs
+var testCacheConfig = config.CacheConfig{
+	Enabled:    true,
+	MaxEntries: 100,
+	TTL: config.CacheTTLConfig{
+		Details:         24 * time.Hour,
+		Configuration:   7 * 24 * time.Hour,
+		Trending:        time.Hour,
+		Search:          15 * time.Minute,
+		Discover:        time.Hour,
+		Recommendations: 24 * time.Hour,
+	},
+}
+
+// createCachedTestClient creates a client with the response cache enabled
+func createCachedTestClient(t *testing.T, baseURL string) *Client {
+	cfg := config.TMDBConfig{
+		APIKey:    "test-api-key",
+		Language:  "en-US",
+		RateLimit: 40,
+		Cache:     testCacheConfig,
+	}
+	client := NewClient(cfg, zap.NewNop())
+	client.httpClient.SetBaseURL(baseURL)
+	return client
+}
+
+// TestClient_Cache_HitSkipsRequest tests that repeated requests are served from the cache
+func TestClient_Cache_HitSkipsRequest(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+	}))
+	defer server.Close()
+
+	client := createCachedTestClient(t, server.URL)
+	ctx := context.Background()
+
+	first, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+	second, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, "Inception", second.Title)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, uint64(1), client.GetCallCount())
+	assert.Equal(t, uint64(1), client.GetCacheHitCount())
+	assert.Equal(t, uint64(1), client.GetCacheMissCount())
+
+	// 不同语言是不同的缓存条目
+	zh := "zh-CN"
+	_, err = client.GetMovieDetails(ctx, 27205, &zh)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+// TestClient_Cache_ErrorsNotCached tests that error responses are not cached
+func TestClient_Cache_ErrorsNotCached(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status_code":34,"status_message":"The resource you requested could not be found."}`))
+	}))
+	defer server.Close()
+
+	client := createCachedTestClient(t, server.URL)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		details, err := client.GetMovieDetails(ctx, 1, nil)
+		require.NoError(t, err)
+		assert.Nil(t, details)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, uint64(0), client.GetCacheHitCount())
+}
+
+// TestClient_Cache_Disabled tests that counts stay at zero without a cache
+func TestClient_Cache_Disabled(t *testing.T) {
+	client := NewClient(config.TMDBConfig{APIKey: "test-api-key", RateLimit: 40}, zap.NewNop())
+
+	assert.Nil(t, client.cache)
+	assert.Equal(t, uint64(0), client.GetCacheHitCount())
+	assert.Equal(t, uint64(0), client.GetCacheMissCount())
+}
+
+// TestClient_CacheKey tests key normalization
+func TestClient_CacheKey(t *testing.T) {
+	client := createCachedTestClient(t, "http://localhost")
+
+	key := client.cacheKey("/search/multi", url.Values{
+		"query":   {"Inception"},
+		"page":    {"1"},
+		"api_key": {"secret"},
+	})
+
+	assert.Equal(t, "/search/multi?language=en-US&page=1&query=Inception", key)
+	assert.NotContains(t, key, "secret")
+}
+
+// TestClient_CacheTTL tests TTL selection by endpoint category
+func TestClient_CacheTTL(t *testing.T) {
+	client := createCachedTestClient(t, "http://localhost")
+
+	tests := []struct {
+		endpoint string
+		expected time.Duration
+	}{
+		{"/movie/27205", 24 * time.Hour},
+		{"/person/6193", 24 * time.Hour},
+		{"/tv/1396/recommendations", 24 * time.Hour},
+		{"/configuration", 7 * 24 * time.Hour},
+		{"/genre/movie/list", 7 * 24 * time.Hour},
+		{"/trending/movie/day", time.Hour},
+		{"/search/multi", 15 * time.Minute},
+		{"/discover/tv", time.Hour},
+		{"/unknown", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			assert.Equal(t, tt.expected, client.cacheTTL(tt.endpoint))
+		})
+	}
+}
+
+// createDiskCachedTestClient creates a client with only the disk cache enabled in dir
+func createDiskCachedTestClient(t *testing.T, baseURL, dir string) *Client {
+	cacheCfg := testCacheConfig
+	cacheCfg.Enabled = false
+	cacheCfg.Disk = config.DiskCacheConfig{Enabled: true, Path: dir, MaxSizeMB: 10}
+	cfg := config.TMDBConfig{
+		APIKey:    "test-api-key",
+		Language:  "en-US",
+		RateLimit: 40,
+		Cache:     cacheCfg,
+	}
+	client := NewClient(cfg, zap.NewNop())
+	require.NotNil(t, client.disk)
+	client.httpClient.SetBaseURL(baseURL)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestClient_DiskCache_PersistsAcrossClients tests that responses survive a restart
+func TestClient_DiskCache_PersistsAcrossClients(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	first := createDiskCachedTestClient(t, server.URL, dir)
+	_, err := first.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+	require.NoError(t, first.Close())
+
+	// 新的 client 模拟进程重启
+	second := createDiskCachedTestClient(t, server.URL, dir)
+	details, err := second.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Inception", details.Title)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, uint64(1), second.GetCacheHitCount())
+	assert.Equal(t, uint64(0), second.GetCallCount())
+}
+
+// TestClient_DiskCache_RevalidatesWithETag tests conditional requests for stale records
+func TestClient_DiskCache_RevalidatesWithETag(t *testing.T) {
+	var conditional int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+	}))
+	defer server.Close()
+
+	client := createDiskCachedTestClient(t, server.URL, t.TempDir())
+	ctx := context.Background()
+
+	_, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+
+	// 将记录标记为过期
+	keys, err := client.disk.Keys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	key := keys[0]
+	record, ok := client.disk.Get(key)
+	require.True(t, ok)
+	assert.Equal(t, `"v1"`, record.ETag)
+	record.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, client.disk.Set(key, record))
+
+	details, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Inception", details.Title)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conditional))
+
+	// 304 之后记录重新变为新鲜
+	record, ok = client.disk.Get(key)
+	require.True(t, ok)
+	assert.True(t, record.Fresh(time.Now()))
+}
+
+// TestClient_DiskCache_ConditionalNotCoalesced tests that a caller without the stale
+// record does not share the 304 of an in-flight conditional request
+func TestClient_DiskCache_ConditionalNotCoalesced(t *testing.T) {
+	var requests, conditional int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&conditional, 1)
+			<-release
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if n == 1 {
+			w.Header().Set("ETag", `"v1"`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+	}))
+	defer server.Close()
+
+	client := createDiskCachedTestClient(t, server.URL, t.TempDir())
+	ctx := context.Background()
+
+	_, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+	keys, err := client.disk.Keys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	record, ok := client.disk.Get(keys[0])
+	require.True(t, ok)
+	record.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, client.disk.Set(keys[0], record))
+
+	// 第一个调用发送条件请求并阻塞
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.GetMovieDetails(ctx, 27205, nil)
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&conditional) == 1 }, time.Second, time.Millisecond)
+
+	// 第二个调用没有过期记录，必须得到完整响应而不是共享的 304
+	_, err = client.disk.Purge(false)
+	require.NoError(t, err)
+	details, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Inception", details.Title)
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.Equal(t, uint64(0), client.GetCoalescedCount())
+}
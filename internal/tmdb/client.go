@@ -2,15 +2,25 @@ package tmdb
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/XDwanj/tmdb-mcp/internal/cache"
 	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+	"github.com/XDwanj/tmdb-mcp/internal/progress"
 	"github.com/XDwanj/tmdb-mcp/internal/ratelimit"
+	"github.com/XDwanj/tmdb-mcp/internal/sessionlog"
 	"github.com/XDwanj/tmdb-mcp/pkg/version"
 )
 
@@ -23,33 +33,104 @@ const (
 )
 
 const (
-	// baseURL is the TMDB API v3 base URL
-	baseURL = "https://api.themoviedb.org/3"
+	// defaultBaseURL is the TMDB API v3 base URL used when tmdb.base_url is not set
+	defaultBaseURL = "https://api.themoviedb.org/3"
+
+	// defaultImageBaseURL is the TMDB image base URL used when tmdb.image_base_url is not set
+	defaultImageBaseURL = "https://image.tmdb.org/t/p/"
+
+	// imageSize is the image size used for poster and profile URLs in tool responses
+	imageSize = "w500"
 
 	// defaultTimeout is the default HTTP request timeout
 	defaultTimeout = 10 * time.Second
 
 	// performanceThreshold is the response time threshold for performance alerts
 	performanceThreshold = 1 * time.Second
+
+	// defaultRetryAfter is the pause after a 429 response without Retry-After
+	defaultRetryAfter = 10 * time.Second
+
+	// maxRateLimitPause bounds pauses requested by TMDB, in case of bogus headers
+	maxRateLimitPause = 5 * time.Minute
+
+	// diskLockTimeout bounds the wait for the disk cache file lock held by another process
+	// bbolt locks the file for the lifetime of the process (read-only opens too), so a
+	// second process on the same path runs without the disk cache after this wait
+	diskLockTimeout = 1 * time.Second
 )
 
 // Client is the TMDB API client
 type Client struct {
-	httpClient  *resty.Client
-	apiKey      string
-	language    string
-	logger      *zap.Logger
-	rateLimiter *ratelimit.Limiter
-	callCounter *uint64 // API 调用计数器(指针以支持 atomic 操作)
+	httpClient   *resty.Client
+	creds        Credentials
+	keys         *keyPool    // tmdb.api_keys 的 key 池（未配置时为 nil，使用 creds）
+	clients      *clientPool // 客户端自带 key 的客户端池（未启用时为 nil）
+	language     string
+	imageBaseURL string
+	logger       *zap.Logger
+	rateLimiter  *ratelimit.Limiter // 使用 key 池时为 nil（每个 key 有自己的限流器）
+	callCounter  *uint64            // API 调用计数器(指针以支持 atomic 操作)
+	cache        *cache.LRU         // 内存响应缓存（未启用时为 nil）
+	disk         *cache.DiskStore   // 磁盘响应缓存（未启用时为 nil）
+	cacheTTLs    config.CacheTTLConfig
+	cacheHits    uint64 // 缓存命中计数(atomic)
+	cacheMisses  uint64 // 缓存未命中计数(atomic)
+	inflight     singleflight.Group
+	coalesced    uint64       // 合并到进行中请求的调用数(atomic)
+	breaker      *breaker     // 熔断器（未启用时为 nil）；客户端自带 key 的客户端共用
+	retry        *retryPolicy // 重试策略与单次调用的总时限
 }
 
 // NewClient creates a new TMDB API client with configured Resty client
+// When tmdb.client_keys is enabled, calls whose context carries client-supplied
+// credentials (see WithCredentials) go through a per-key client instead
 func NewClient(cfg config.TMDBConfig, logger *zap.Logger) *Client {
+	c := newClient(cfg, configCredentials(cfg), logger)
+	if cfg.ClientKeys.Enabled() {
+		c.clients = newClientPool(cfg, logger)
+		c.clients.breaker = c.breaker
+		logger.Debug("TMDB client keys enabled",
+			zap.String("mode", cfg.ClientKeys.Mode),
+			zap.Int("max_clients", cfg.ClientKeys.MaxClients),
+			zap.Duration("idle_timeout", cfg.ClientKeys.IdleTimeout),
+		)
+	}
+	return c
+}
+
+// newClient creates a client authenticating with creds, or with the keys of
+// cfg.APIKeys when configured
+func newClient(cfg config.TMDBConfig, creds Credentials, logger *zap.Logger) *Client {
 	// 构建 User-Agent
 	userAgent := fmt.Sprintf("tmdb-mcp/%s", version.Version)
 
+	// TMDB 地址（测试或离线环境可指向 faketmdb）
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	imageBaseURL := cfg.ImageBaseURL
+	if imageBaseURL == "" {
+		imageBaseURL = defaultImageBaseURL
+	}
+	// 指标中的 endpoint 标签不含 base URL 的路径前缀（如 /3）
+	basePath := ""
+	if parsed, err := url.Parse(baseURL); err == nil {
+		basePath = strings.TrimSuffix(parsed.Path, "/")
+	}
+
 	// Create rate limiter first (需要在 middleware 中使用)
-	rateLimiter := ratelimit.NewLimiter(cfg, logger)
+	// 配置了 key 池时，每次请求选择一个 key 并使用该 key 的限流器
+	var keys *keyPool
+	var rateLimiter *ratelimit.Limiter
+	if len(cfg.APIKeys) > 0 {
+		keys = newKeyPool(cfg, logger)
+	} else {
+		rateLimiter = ratelimit.NewLimiter(cfg, logger)
+	}
+
+	policy := newRetryPolicy(cfg.Retry)
 
 	// 初始化 API 调用计数器(在创建 httpClient 之前,以便在 middleware 中引用)
 	var counter uint64 = 0
@@ -61,24 +142,30 @@ func NewClient(cfg config.TMDBConfig, logger *zap.Logger) *Client {
 		SetHeader("User-Agent", userAgent).
 		OnBeforeRequest(func(c *resty.Client, req *resty.Request) error {
 			// 1. 统一处理 rate limiting (阻塞等待)
-			if err := rateLimiter.Wait(req.Context()); err != nil {
-				logger.Error("rate limit wait failed", zap.Error(err))
+			if keys != nil {
+				key, err := keys.acquire(attemptParent(req.Context()))
+				if err != nil {
+					sessionlog.Logger(req.Context(), logger).Error("rate limit wait failed", zap.Error(err))
+					return fmt.Errorf("rate limit wait failed: %w", err)
+				}
+				req.SetContext(withAttemptKey(req.Context(), key))
+			} else if err := rateLimiter.Wait(attemptParent(req.Context())); err != nil {
+				sessionlog.Logger(req.Context(), logger).Error("rate limit wait failed", zap.Error(err))
 				return fmt.Errorf("rate limit wait failed: %w", err)
 			}
 
-			// 2. 自动添加 API Key
-			req.SetQueryParam("api_key", cfg.APIKey)
-
-			// 3. language 参数仅在请求中未显式设置时使用配置默认值
+			// 2. language 参数仅在请求中未显式设置时使用配置默认值
 			if req.QueryParam.Get("language") == "" && cfg.Language != "" {
 				req.SetQueryParam("language", cfg.Language)
 			}
 			return nil
 		}).
-		// 统一处理请求开始日志和计时
+		// 统一处理请求开始日志、计时和 tracing
 		OnBeforeRequest(func(c *resty.Client, req *resty.Request) error {
-			// 记录请求开始时间(存储在 context 中)
-			ctx := context.WithValue(req.Context(), startTimeKey, time.Now())
+			// 每次尝试（包括重试）都是一个 span；记录请求开始时间(存储在 context 中)
+			ctx := startAttempt(req, basePath)
+			ctx = withAttemptKey(ctx, attemptKey(req.Context()))
+			ctx = context.WithValue(ctx, startTimeKey, time.Now())
 			req.SetContext(ctx)
 
 			// 记录请求开始日志
@@ -93,12 +180,24 @@ func NewClient(cfg config.TMDBConfig, logger *zap.Logger) *Client {
 			// 递增 API 调用计数器(线程安全)
 			atomic.AddUint64(&counter, 1)
 
+			// 自适应限流：TMDB 的限流反馈暂停本次请求所用限流器的所有调用方
+			limiter := rateLimiter
+			if key := attemptKey(resp.Request.Context()); key != nil {
+				limiter = key.limiter
+			}
+			observeRateLimit(limiter, resp, cfg.AdaptiveRateLimit.Headers)
+
 			// 从 context 获取开始时间
 			responseTime := time.Duration(0)
 			if startTime, ok := resp.Request.Context().Value(startTimeKey).(time.Time); ok {
 				responseTime = time.Since(startTime)
 			}
 
+			endAttempt(resp.Request.Context(), resp.StatusCode(), nil)
+			endpoint := endpointLabel(resp.Request.URL, basePath)
+			metrics.TMDBRequests.WithLabelValues(endpoint, strconv.Itoa(resp.StatusCode())).Inc()
+			metrics.TMDBRequestDuration.WithLabelValues(endpoint).Observe(responseTime.Seconds())
+
 			if resp.IsSuccess() {
 				logger.Info("TMDB API request succeeded",
 					zap.String("method", resp.Request.Method),
@@ -109,7 +208,7 @@ func NewClient(cfg config.TMDBConfig, logger *zap.Logger) *Client {
 
 				// 性能阈值告警
 				if responseTime > performanceThreshold {
-					logger.Warn("TMDB API request exceeded performance threshold",
+					sessionlog.Logger(resp.Request.Context(), logger).Warn("TMDB API request exceeded performance threshold",
 						zap.String("method", resp.Request.Method),
 						zap.String("url", resp.Request.URL),
 						zap.Int("status_code", resp.StatusCode()),
@@ -117,6 +216,14 @@ func NewClient(cfg config.TMDBConfig, logger *zap.Logger) *Client {
 						zap.Duration("threshold", performanceThreshold),
 					)
 				}
+			} else if resp.IsError() {
+				// 错误响应由各接口的 handleError 转换为 TMDBError，这里仅记录并转发给会话
+				sessionlog.Logger(resp.Request.Context(), logger).Warn("TMDB API request returned error status",
+					zap.String("method", resp.Request.Method),
+					zap.String("url", resp.Request.URL),
+					zap.Int("status_code", resp.StatusCode()),
+					zap.Duration("response_time", responseTime),
+				)
 			}
 			return nil
 		}).
@@ -127,63 +234,148 @@ func NewClient(cfg config.TMDBConfig, logger *zap.Logger) *Client {
 			if startTime, ok := req.Context().Value(startTimeKey).(time.Time); ok {
 				responseTime = time.Since(startTime)
 			}
+			endAttempt(req.Context(), 0, err)
+			metrics.TMDBRequests.WithLabelValues(endpointLabel(req.URL, basePath), "error").Inc()
 
-			logger.Error("TMDB API request failed",
+			sessionlog.Logger(req.Context(), logger).Error("TMDB API request failed",
 				zap.String("method", req.Method),
 				zap.String("url", req.URL),
 				zap.Duration("response_time", responseTime),
 				zap.Error(err),
 			)
 		}).
-		// 配置重试机制
-		SetRetryCount(3).
-		SetRetryWaitTime(1 * time.Second).
-		SetRetryMaxWaitTime(10 * time.Second).
-		// 自定义重试条件：仅对 429/500/502/503 重试
+		// 配置重试机制（tmdb.retry）：等待时间由 policy 计算，resty 只做上下限裁剪
+		SetRetryCount(policy.retries()).
+		SetRetryWaitTime(time.Nanosecond).
+		SetRetryMaxWaitTime(maxRateLimitPause).
+		// 使用 key 池时 429 换用其他 key 重试，无需等待 Retry-After
+		SetRetryAfter(func(_ *resty.Client, res *resty.Response) (time.Duration, error) {
+			return policy.wait(res, keys == nil), nil
+		}).
+		AddRetryCondition(func(res *resty.Response, err error) bool {
+			return policy.shouldRetry(res, err, keys == nil)
+		}).
+		// key 池：被拒绝（401）的 key 已标记为不可用，换用其他 key 重试
 		AddRetryCondition(func(res *resty.Response, err error) bool {
-			// 网络错误或 nil response，由 Resty 默认条件处理
-			if err != nil || res == nil {
+			if keys == nil || err != nil || res == nil || res.StatusCode() != http.StatusUnauthorized {
 				return false
 			}
-
-			// 仅对特定状态码重试
-			statusCode := res.StatusCode()
-			return statusCode == 429 || statusCode == 500 || statusCode == 502 || statusCode == 503
+			return pinnedKey(res.Request.Context()) == nil && keys.hasHealthy()
 		}).
 		// 添加重试钩子：记录重试日志
 		AddRetryHook(func(res *resty.Response, err error) {
 			statusCode := 0
 			endpoint := ""
+			attempt := 0
+			ctx := context.Background()
 			if res != nil {
+				ctx = res.Request.Context()
 				statusCode = res.StatusCode()
 				endpoint = res.Request.URL
+				attempt = res.Request.Attempt
 			}
+			metrics.TMDBRetries.WithLabelValues(endpointLabel(endpoint, basePath)).Inc()
 
-			logger.Warn("Retrying TMDB API request",
+			sessionlog.Logger(ctx, logger).Warn("Retrying TMDB API request",
 				zap.String("endpoint", endpoint),
 				zap.Int("status_code", statusCode),
+				zap.Int("attempt", attempt),
 				zap.Error(err),
 			)
+
+			// 通知调用方正在重试（如果请求携带了 progress token）
+			progress.Notify(ctx, fmt.Sprintf("Retrying TMDB request (retry %d of %d)", attempt, policy.retries()))
 		})
 
 	logger.Debug("TMDB client initialized",
 		zap.String("base_url", baseURL),
+		zap.String("image_base_url", imageBaseURL),
 		zap.String("language", cfg.Language),
 		zap.String("user_agent", userAgent),
-		zap.Int("retry_count", 3),
+		zap.Int("retry_max_attempts", policy.maxAttempts),
+		zap.Duration("retry_timeout", policy.timeout),
 	)
 
 	logger.Debug("Rate Limiter integrated to TMDB Client",
 		zap.String("component", "tmdb_client"),
 	)
 
-	return &Client{
-		httpClient:  httpClient,
-		apiKey:      cfg.APIKey,
-		language:    cfg.Language,
-		logger:      logger,
-		rateLimiter: rateLimiter,
-		callCounter: &counter,
+	// 创建响应缓存（可选）
+	var responseCache *cache.LRU
+	if cfg.Cache.Enabled {
+		responseCache = cache.NewLRU(cfg.Cache.MaxEntries)
+		logger.Debug("TMDB response cache enabled",
+			zap.Int("max_entries", cfg.Cache.MaxEntries),
+			zap.Duration("details_ttl", cfg.Cache.TTL.Details),
+			zap.Duration("search_ttl", cfg.Cache.TTL.Search),
+			zap.Duration("trending_ttl", cfg.Cache.TTL.Trending),
+		)
+	}
+
+	// 打开磁盘缓存（可选）；失败时仅记录警告，继续使用内存缓存
+	var diskCache *cache.DiskStore
+	if cfg.Cache.Disk.Enabled {
+		store, err := cache.OpenDiskStore(cfg.Cache.Disk.Path, int64(cfg.Cache.Disk.MaxSizeMB)<<20, diskLockTimeout)
+		if err != nil {
+			logger.Warn("TMDB disk cache unavailable, continuing without it",
+				zap.String("path", cfg.Cache.Disk.Path),
+				zap.Error(err),
+			)
+		} else {
+			diskCache = store
+			logger.Debug("TMDB disk cache enabled",
+				zap.String("path", store.Path()),
+				zap.Int("max_size_mb", cfg.Cache.Disk.MaxSizeMB),
+			)
+		}
+	}
+
+	// 熔断器（可选）；内存缓存保留过期条目，以便 TMDB 不可用时返回
+	breaker := newBreaker(cfg.CircuitBreaker, logger)
+	if breaker != nil {
+		if responseCache != nil {
+			responseCache.KeepStale(cfg.CircuitBreaker.MaxStale)
+		}
+		logger.Debug("TMDB circuit breaker enabled",
+			zap.Int("failure_threshold", cfg.CircuitBreaker.FailureThreshold),
+			zap.Duration("open_timeout", cfg.CircuitBreaker.OpenTimeout),
+			zap.Duration("max_stale", cfg.CircuitBreaker.MaxStale),
+		)
+	}
+
+	c := &Client{
+		httpClient:   httpClient,
+		creds:        creds,
+		keys:         keys,
+		language:     cfg.Language,
+		imageBaseURL: imageBaseURL,
+		logger:       logger,
+		rateLimiter:  rateLimiter,
+		callCounter:  &counter,
+		cache:        responseCache,
+		disk:         diskCache,
+		cacheTTLs:    cfg.Cache.TTL,
+		breaker:      breaker,
+		retry:        policy,
+	}
+
+	// 凭据由 transport 添加，请求 URL（及日志）中不含 api_key
+	httpClient.SetTransport(c.authenticated(httpClient.GetClient().Transport))
+	return c
+}
+
+// observeRateLimit pauses limiter when resp is a 429 (until Retry-After) or, when
+// headers is set, when its X-RateLimit-Remaining is 0 (until X-RateLimit-Reset)
+func observeRateLimit(limiter *ratelimit.Limiter, resp *resty.Response, headers bool) {
+	now := time.Now()
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		limiter.PauseUntil(now.Add(retryAfter(resp.Header(), now)), "retry_after")
+		return
+	}
+	if headers {
+		if reset, ok := rateLimitReset(resp.Header(), now); ok {
+			limiter.PauseUntil(reset, "headers")
+		}
 	}
 }
 
@@ -192,8 +384,120 @@ func (c *Client) GetCallCount() uint64 {
 	return atomic.LoadUint64(c.callCounter)
 }
 
-// Ping tests the TMDB API Key validity by calling the /configuration endpoint
+// GetCacheHitCount returns the number of requests served from the memory or disk cache (thread-safe)
+func (c *Client) GetCacheHitCount() uint64 {
+	return atomic.LoadUint64(&c.cacheHits)
+}
+
+// GetCacheMissCount returns the number of cacheable requests not found in any cache (thread-safe)
+func (c *Client) GetCacheMissCount() uint64 {
+	return atomic.LoadUint64(&c.cacheMisses)
+}
+
+// GetCoalescedCount returns the number of calls that shared an identical in-flight
+// request instead of calling TMDB, i.e. the API calls saved by coalescing (thread-safe)
+func (c *Client) GetCoalescedCount() uint64 {
+	return atomic.LoadUint64(&c.coalesced)
+}
+
+// SetTransport replaces the HTTP transport used for TMDB requests,
+// e.g. to record or replay traffic
+// Credentials are still added on top of transport, and per-key clients use it too
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.SetTransport(c.authenticated(transport))
+	if c.clients != nil {
+		c.clients.setTransport(transport)
+	}
+}
+
+// authenticated wraps transport to add the client's credentials, or those of
+// the pooled key acquired for each request
+func (c *Client) authenticated(transport http.RoundTripper) http.RoundTripper {
+	if c.keys != nil {
+		return &keyTransport{pool: c.keys, next: transport}
+	}
+	return &authTransport{creds: c.creds, next: transport}
+}
+
+// KeyUsage returns the usage of each key of the tmdb.api_keys pool, or nil when
+// a single credential is configured
+func (c *Client) KeyUsage() []KeyUsage {
+	if c.keys == nil {
+		return nil
+	}
+	return c.keys.usage()
+}
+
+// clientFor returns the client serving calls made with ctx: the per-key client
+// for client-supplied credentials, otherwise c
+func (c *Client) clientFor(ctx context.Context) *Client {
+	if c.clients == nil {
+		return c
+	}
+	creds, ok := CredentialsFromContext(ctx)
+	if !ok {
+		return c
+	}
+	return c.clients.get(creds)
+}
+
+// Close releases resources held by the client, such as the disk cache file lock
+func (c *Client) Close() error {
+	if c.disk == nil {
+		return nil
+	}
+	return c.disk.Close()
+}
+
+// endpointLabel returns the metrics endpoint label for a request URL
+func endpointLabel(rawURL, basePath string) string {
+	if rawURL == "" {
+		return "unknown"
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "unknown"
+	}
+	return metrics.Endpoint(strings.TrimPrefix(parsed.Path, basePath))
+}
+
+// imageURL returns the absolute URL of an image path such as a poster_path,
+// or an empty string when the path is empty
+func (c *Client) imageURL(path string) string {
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(c.imageBaseURL, "/") + "/" + imageSize + path
+}
+
+// Credentials returns the kind and fingerprint of the server's TMDB credentials
+func (c *Client) Credentials() (kind, fingerprint string) {
+	return c.creds.Kind(), c.creds.Fingerprint()
+}
+
+// Ping tests the validity of the TMDB credentials by calling the /configuration endpoint
+// With a key pool every key is checked; rejected keys are marked unhealthy and
+// Ping fails only when no key works
 func (c *Client) Ping(ctx context.Context) error {
+	if c.keys == nil {
+		return c.ping(ctx, c.creds)
+	}
+
+	var errs []error
+	for _, key := range c.keys.keys {
+		if err := c.ping(context.WithValue(ctx, pinnedKeyKey{}, key), key.creds); err != nil {
+			c.logger.Warn("TMDB API key validation failed", zap.String("api_key", key.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", key.name, err))
+		}
+	}
+	if len(errs) == len(c.keys.keys) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// ping calls the /configuration endpoint with creds
+func (c *Client) ping(ctx context.Context, creds Credentials) error {
 	// Rate limiting is handled by OnBeforeRequest middleware
 	resp, err := c.httpClient.R().
 		SetContext(ctx).
@@ -206,9 +510,13 @@ func (c *Client) Ping(ctx context.Context) error {
 
 	// 检查错误响应
 	if resp.IsError() {
-		return handleError(resp)
+		err := handleError(resp)
+		if resp.StatusCode() == http.StatusUnauthorized {
+			return fmt.Errorf("TMDB rejected the configured %s: %w", creds.Kind(), err)
+		}
+		return err
 	}
 
-	c.logger.Info("TMDB API Key validation successful")
+	c.logger.Info("TMDB credentials validation successful", zap.String("credentials", creds.Kind()))
 	return nil
 }
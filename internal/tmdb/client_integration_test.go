@@ -176,3 +176,72 @@ func TestClient_RateLimiter_ContextCancellation(t *testing.T) {
 	require.Error(t, err, "Request should fail due to context timeout")
 	assert.Contains(t, err.Error(), "rate limit wait failed", "Error should mention rate limit wait failure")
 }
+
+// TestClient_RateLimiter_RetryAfter tests that a 429 with Retry-After pauses the rate limiter
+func TestClient_RateLimiter_RetryAfter(t *testing.T) {
+	var requestMutex sync.Mutex
+	var requestTimes []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestMutex.Lock()
+		requestTimes = append(requestTimes, time.Now())
+		first := len(requestTimes) == 1
+		requestMutex.Unlock()
+
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"status_code":25,"status_message":"Your request count is over the allowed limit"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"images":{"base_url":"http://image.tmdb.org/t/p/"}}`))
+	}))
+	defer server.Close()
+
+	cfg := config.TMDBConfig{
+		APIKey:    "test_api_key",
+		Language:  "en-US",
+		RateLimit: 40,
+		AdaptiveRateLimit: config.AdaptiveRateLimitConfig{
+			Enabled: true,
+			RampUp:  time.Second,
+		},
+	}
+	client := NewClient(cfg, zap.NewNop())
+	client.httpClient.SetBaseURL(server.URL)
+
+	require.NoError(t, client.Ping(context.Background()))
+
+	requestMutex.Lock()
+	defer requestMutex.Unlock()
+	require.Len(t, requestTimes, 2)
+	assert.GreaterOrEqual(t, requestTimes[1].Sub(requestTimes[0]), 900*time.Millisecond, "retry should wait for Retry-After")
+}
+
+// TestRateLimitReset tests reading X-RateLimit-Remaining and X-RateLimit-Reset
+func TestRateLimitReset(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	header := func(remaining, reset string) http.Header {
+		h := http.Header{}
+		h.Set("X-RateLimit-Remaining", remaining)
+		h.Set("X-RateLimit-Reset", reset)
+		return h
+	}
+
+	at, ok := rateLimitReset(header("0", "1700000005"), now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(5*time.Second), at)
+
+	// 异常的重置时间被限制在 maxRateLimitPause 内
+	at, ok = rateLimitReset(header("0", "1800000000"), now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(maxRateLimitPause), at)
+
+	for _, h := range []http.Header{header("3", "1700000005"), header("0", "1699999999"), header("0", "soon"), {}} {
+		_, ok = rateLimitReset(h, now)
+		assert.False(t, ok, h)
+	}
+
+	assert.Equal(t, defaultRetryAfter, retryAfter(http.Header{}, now))
+	assert.Equal(t, maxRateLimitPause, retryAfter(http.Header{"Retry-After": {"86400"}}, now))
+}
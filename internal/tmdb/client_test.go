@@ -14,6 +14,7 @@ import (
 	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/faketmdb"
 )
 
 // TestNewClient tests the Client constructor
@@ -29,7 +30,7 @@ func TestNewClient(t *testing.T) {
 
 	assert.NotNil(t, client)
 	assert.NotNil(t, client.httpClient)
-	assert.Equal(t, "test_api_key", client.apiKey)
+	assert.Equal(t, "test_api_key", client.creds.APIKey)
 	assert.Equal(t, "en-US", client.language)
 	assert.NotNil(t, client.logger)
 	assert.NotNil(t, client.rateLimiter)
@@ -193,6 +194,35 @@ func TestClient_Resty_Configuration(t *testing.T) {
 	assert.Contains(t, userAgent, "tmdb-mcp")
 }
 
+// TestClient_BaseURL tests the configurable API and image base URLs against the fake TMDB server
+func TestClient_BaseURL(t *testing.T) {
+	fake := faketmdb.New("fake-key")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := config.TMDBConfig{
+		APIKey:       "fake-key",
+		Language:     "en-US",
+		RateLimit:    40,
+		BaseURL:      server.URL + "/3",
+		ImageBaseURL: "https://images.example.com/t/p",
+	}
+	client := NewClient(cfg, zap.NewNop())
+	ctx := context.Background()
+
+	require.NoError(t, client.Ping(ctx))
+
+	movie, err := client.GetMovieDetails(ctx, 27205, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://images.example.com/t/p/w500/oYuLEt3zVCKq57qu2F8dT7NIa6f.jpg", movie.PosterURL)
+
+	person, err := client.GetPersonDetails(ctx, 6193, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://images.example.com/t/p/w500/wo2hJpn04vbtmh0B9utCFdsQhxM.jpg", person.ProfileURL)
+
+	assert.Equal(t, 1, fake.Requests("/configuration"))
+}
+
 // TestClient_CallCounter tests the API call counter functionality
 func TestClient_CallCounter(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,132 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingServer returns a server that counts requests and blocks them until release is closed
+func blockingServer(t *testing.T, status int, release <-chan struct{}, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"status_code": 7, "status_message": "Invalid API key"})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// waitForRequests waits until the server has received n requests
+func waitForRequests(t *testing.T, requests *int32, n int32) {
+	require.Eventually(t, func() bool { return atomic.LoadInt32(requests) >= n }, time.Second, time.Millisecond)
+}
+
+// TestClient_Coalesce_ConcurrentRequests tests that identical concurrent requests share one HTTP call
+func TestClient_Coalesce_ConcurrentRequests(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := blockingServer(t, http.StatusOK, release, &requests)
+	client := createTestClient(t, server.URL, "test-api-key")
+
+	const callers = 5
+	results := make([]*MovieDetails, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = client.GetMovieDetails(context.Background(), 27205, nil)
+		}(i)
+	}
+
+	waitForRequests(t, &requests, 1)
+	time.Sleep(50 * time.Millisecond) // 等待其余调用加入进行中的请求
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "Inception", results[i].Title)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, uint64(1), client.GetCallCount())
+	assert.Equal(t, uint64(callers-1), client.GetCoalescedCount())
+}
+
+// TestClient_Coalesce_SharedError tests that waiting callers receive the same TMDB error
+func TestClient_Coalesce_SharedError(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := blockingServer(t, http.StatusUnauthorized, release, &requests)
+	client := createTestClient(t, server.URL, "test-api-key")
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.GetMovieDetails(context.Background(), 1, nil)
+			errs <- err
+		}()
+	}
+
+	waitForRequests(t, &requests, 1)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		err := <-errs
+		var tmdbErr *TMDBError
+		require.ErrorAs(t, err, &tmdbErr)
+		assert.Equal(t, ErrorTypeAuth, tmdbErr.ErrorType)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, uint64(1), client.GetCoalescedCount())
+}
+
+// TestClient_Coalesce_LeaderCancelled tests that a waiting caller retries when the leading caller gives up
+func TestClient_Coalesce_LeaderCancelled(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := blockingServer(t, http.StatusOK, release, &requests)
+	client := createTestClient(t, server.URL, "test-api-key")
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := client.GetMovieDetails(leaderCtx, 27205, nil)
+		leaderErr <- err
+	}()
+	waitForRequests(t, &requests, 1)
+
+	followerResult := make(chan *MovieDetails, 1)
+	go func() {
+		details, err := client.GetMovieDetails(context.Background(), 27205, nil)
+		assert.NoError(t, err)
+		followerResult <- details
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	require.Error(t, <-leaderErr)
+	close(release)
+
+	details := <-followerResult
+	require.NotNil(t, details)
+	assert.Equal(t, "Inception", details.Title)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
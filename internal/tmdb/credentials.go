@@ -0,0 +1,114 @@
+package tmdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// ErrInvalidCredentials is returned for values that are neither a v3 API key nor a v4 read access token
+var ErrInvalidCredentials = errors.New("expected a TMDB v3 API key (32 hexadecimal characters) or v4 read access token (JWT)")
+
+// Credentials authenticate TMDB requests with a v3 API key (api_key query
+// parameter) or a v4 read access token (Authorization: Bearer header)
+// They are added by the HTTP transport, so request URLs in logs never carry them
+type Credentials struct {
+	APIKey      string
+	AccessToken string
+}
+
+// ParseCredentials detects whether value is a v3 API key or a v4 read access token
+func ParseCredentials(value string) (Credentials, error) {
+	value = strings.TrimSpace(value)
+	if strings.Count(value, ".") == 2 && strings.HasPrefix(value, "eyJ") {
+		return Credentials{AccessToken: value}, nil
+	}
+	if len(value) == 32 {
+		if _, err := hex.DecodeString(value); err == nil {
+			return Credentials{APIKey: value}, nil
+		}
+	}
+	return Credentials{}, ErrInvalidCredentials
+}
+
+// configCredentials returns the server's credentials: tmdb.access_token or
+// tmdb.api_key, either of which may hold a v3 API key or a v4 read access token
+// Values of neither format (e.g. for faketmdb) are sent as configured
+func configCredentials(cfg config.TMDBConfig) Credentials {
+	value := cfg.APIKey
+	if cfg.AccessToken != "" {
+		value = cfg.AccessToken
+	}
+	if creds, err := ParseCredentials(value); err == nil {
+		return creds
+	}
+	if cfg.AccessToken != "" {
+		return Credentials{AccessToken: cfg.AccessToken}
+	}
+	return Credentials{APIKey: cfg.APIKey}
+}
+
+// Kind describes the credential type for logs and errors
+func (c Credentials) Kind() string {
+	if c.AccessToken != "" {
+		return "v4 read access token"
+	}
+	return "v3 API key"
+}
+
+// Fingerprint returns a short, non-reversible identifier of the credentials for logs
+func (c Credentials) Fingerprint() string {
+	return c.hash()[:12]
+}
+
+// String returns the fingerprint, so credentials printed by mistake do not leak
+func (c Credentials) String() string {
+	return "credentials:" + c.Fingerprint()
+}
+
+// hash identifies the credentials without keeping the secret as a map key
+func (c Credentials) hash() string {
+	sum := sha256.Sum256([]byte(c.APIKey + "\x00" + c.AccessToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// credentialsKey stores client-supplied credentials in a context
+type credentialsKey struct{}
+
+// WithCredentials returns ctx carrying credentials supplied by an HTTP client
+// Calls made with ctx use a per-key client (see ClientKeysConfig) instead of the server's key
+func WithCredentials(ctx context.Context, creds Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, creds)
+}
+
+// CredentialsFromContext returns the client-supplied credentials of ctx, if any
+func CredentialsFromContext(ctx context.Context) (Credentials, bool) {
+	creds, ok := ctx.Value(credentialsKey{}).(Credentials)
+	return creds, ok
+}
+
+// authTransport adds the credentials to every request sent to TMDB
+type authTransport struct {
+	creds Credentials
+	next  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper 不得修改原请求
+	req = req.Clone(req.Context())
+	if t.creds.APIKey != "" {
+		query := req.URL.Query()
+		query.Set("api_key", t.creds.APIKey)
+		req.URL.RawQuery = query.Encode()
+	}
+	if t.creds.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+t.creds.AccessToken)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -29,7 +29,7 @@ func (c *Client) GetMovieDetails(ctx context.Context, id int, language *string)
 		req.SetQueryParam("language", *language)
 	}
 
-	resp, err := req.Get(endpoint)
+	resp, err := c.get(req, endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("get movie details failed: %w", err)
@@ -54,6 +54,7 @@ func (c *Client) GetMovieDetails(ctx context.Context, id int, language *string)
 		return nil, fmt.Errorf("get movie details API error: %w", err)
 	}
 
+	details.PosterURL = c.imageURL(details.PosterPath)
 	return &details, nil
 }
 
@@ -79,7 +80,7 @@ func (c *Client) GetTVDetails(ctx context.Context, id int, language *string) (*T
 		req.SetQueryParam("language", *language)
 	}
 
-	resp, err := req.Get(endpoint)
+	resp, err := c.get(req, endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("get TV details failed: %w", err)
@@ -104,6 +105,7 @@ func (c *Client) GetTVDetails(ctx context.Context, id int, language *string) (*T
 		return nil, fmt.Errorf("get TV details API error: %w", err)
 	}
 
+	details.PosterURL = c.imageURL(details.PosterPath)
 	return &details, nil
 }
 
@@ -129,7 +131,7 @@ func (c *Client) GetPersonDetails(ctx context.Context, id int, language *string)
 		req.SetQueryParam("language", *language)
 	}
 
-	resp, err := req.Get(endpoint)
+	resp, err := c.get(req, endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("get person details failed: %w", err)
@@ -154,5 +156,6 @@ func (c *Client) GetPersonDetails(ctx context.Context, id int, language *string)
 		return nil, fmt.Errorf("get person details API error: %w", err)
 	}
 
+	details.ProfileURL = c.imageURL(details.ProfilePath)
 	return &details, nil
 }
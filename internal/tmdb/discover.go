@@ -82,7 +82,7 @@ func (c *Client) DiscoverMovies(ctx context.Context, params DiscoverMoviesParams
 
 	// 调用 TMDB API /discover/movie 端点
 	var response DiscoverMoviesResponse
-	resp, err := req.SetResult(&response).Get(endpoint)
+	resp, err := c.get(req.SetResult(&response), endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("discover movies failed: %w", err)
@@ -167,7 +167,7 @@ func (c *Client) DiscoverTV(ctx context.Context, params DiscoverTVParams) (*Disc
 
 	// 调用 TMDB API /discover/tv 端点
 	var response DiscoverTVResponse
-	resp, err := req.SetResult(&response).Get(endpoint)
+	resp, err := c.get(req.SetResult(&response), endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("discover TV shows failed: %w", err)
@@ -3,7 +3,9 @@ package tmdb
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -79,7 +81,7 @@ func handleError(resp *resty.Response) error {
 			Retryable:      true,
 		}
 
-	case 500, 502, 503:
+	case 500, 502, 503, 504:
 		// 服务器错误，可以重试
 		return &TMDBError{
 			StatusCode:     statusCode,
@@ -121,3 +123,53 @@ func handleError(resp *resty.Response) error {
 		Retryable:      false,
 	}
 }
+
+// parseRetryAfter returns the delay of a Retry-After header value, given in
+// seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// retryAfter returns how long to wait after a 429 response: its Retry-After
+// delay, defaultRetryAfter when it has none, at most maxRateLimitPause
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	delay, ok := parseRetryAfter(header.Get("Retry-After"), now)
+	if !ok {
+		delay = defaultRetryAfter
+	}
+	return min(delay, maxRateLimitPause)
+}
+
+// rateLimitReset returns when the rate-limit window of a response ends if its
+// X-RateLimit-Remaining header says the budget is used up
+func rateLimitReset(header http.Header, now time.Time) (time.Time, bool) {
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}, false
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	at := time.Unix(reset, 0)
+	if !at.After(now) {
+		return time.Time{}, false
+	}
+	return minTime(at, now.Add(maxRateLimitPause)), true
+}
+
+// minTime returns the earlier of a and b
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -79,12 +80,13 @@ func TestHandleError(t *testing.T) {
 			}))
 			defer server.Close()
 
-			// 创建客户端并发送请求
+			// 创建客户端并发送请求（不重试，只检查错误转换）
 			logger := zap.NewNop()
 			cfg := config.TMDBConfig{
 				APIKey:    "test_api_key",
 				Language:  "en-US",
 				RateLimit: 40,
+				Retry:     config.RetryConfig{MaxAttempts: 1},
 			}
 			client := NewClient(cfg, logger)
 			client.httpClient.SetBaseURL(server.URL)
@@ -114,3 +116,25 @@ func TestTMDBError_Error(t *testing.T) {
 	expected := "TMDB API Error 401: Invalid API key"
 	assert.Equal(t, expected, err.Error())
 }
+
+// TestParseRetryAfter tests Retry-After values in seconds and as HTTP dates
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	delay, ok := parseRetryAfter("7", now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, delay)
+
+	delay, ok = parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, delay)
+
+	delay, ok = parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Zero(t, delay)
+
+	for _, value := range []string{"", "soon", "-1"} {
+		_, ok = parseRetryAfter(value, now)
+		assert.False(t, ok, value)
+	}
+}
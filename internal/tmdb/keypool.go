@@ -0,0 +1,289 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+	"github.com/XDwanj/tmdb-mcp/internal/progress"
+	"github.com/XDwanj/tmdb-mcp/internal/ratelimit"
+	"github.com/XDwanj/tmdb-mcp/internal/sessionlog"
+)
+
+// unhealthyRetry is how long a key rejected with 401 is skipped before it is tried again
+const unhealthyRetry = 10 * time.Minute
+
+// ErrNoHealthyKey is returned when every key of the tmdb.api_keys pool was rejected by TMDB
+var ErrNoHealthyKey = errors.New("no healthy TMDB API key: all configured keys were rejected by TMDB")
+
+// KeyUsage is the usage of one key of the tmdb.api_keys pool
+type KeyUsage struct {
+	Name      string
+	Requests  uint64 // 发往 TMDB 的请求数（含重试）
+	Rejected  uint64 // 401 响应数
+	Throttled uint64 // 429 响应数
+	InFlight  int64  // 等待限流和进行中的请求数
+	Healthy   bool
+}
+
+// apiKey is one credential of the pool with its own rate limiter
+type apiKey struct {
+	name    string
+	creds   Credentials
+	limiter *ratelimit.Limiter
+
+	waiting   atomic.Int64 // 等待限流的请求数
+	inflight  atomic.Int64 // 进行中的 HTTP 请求数
+	requests  atomic.Uint64
+	rejected  atomic.Uint64
+	throttled atomic.Uint64
+
+	mu             sync.Mutex
+	unhealthyUntil time.Time // 401 后跳过该 key 直到此时间
+	backoffUntil   time.Time // 429 后跳过该 key 直到此时间
+}
+
+// load is the number of requests queued on or sent with the key
+func (k *apiKey) load() int64 {
+	return k.waiting.Load() + k.inflight.Load()
+}
+
+// state reports whether the key is healthy and how long it is still backing off
+func (k *apiKey) state(now time.Time) (healthy bool, backoff time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return !now.Before(k.unhealthyUntil), max(k.backoffUntil.Sub(now), 0)
+}
+
+// keyPool spreads TMDB requests over the keys of tmdb.api_keys
+// Each request attempt picks a key (round robin, or the least loaded key), waits
+// for that key's rate limiter and is sent with its credentials, so every key
+// keeps its own budget. A key rejected with 401 is skipped for unhealthyRetry,
+// a key answered with 429 for the Retry-After delay
+type keyPool struct {
+	keys        []*apiKey
+	leastLoaded bool
+	next        atomic.Uint64
+	logger      *zap.Logger
+}
+
+// newKeyPool creates the pool of cfg.APIKeys
+func newKeyPool(cfg config.TMDBConfig, logger *zap.Logger) *keyPool {
+	pool := &keyPool{
+		leastLoaded: cfg.KeySelection == config.KeySelectionLeastLoaded,
+		logger:      logger,
+	}
+	for _, kc := range cfg.APIKeys {
+		keyCfg := cfg
+		if kc.RateLimit > 0 {
+			keyCfg.RateLimit = kc.RateLimit
+		}
+		creds := configCredentials(config.TMDBConfig{APIKey: kc.Key})
+		pool.keys = append(pool.keys, &apiKey{
+			name:    kc.Name,
+			creds:   creds,
+			limiter: ratelimit.NewLimiter(keyCfg, logger.With(zap.String("api_key", kc.Name))),
+		})
+		metrics.TMDBKeyHealthy.WithLabelValues(kc.Name).Set(1)
+	}
+	return pool
+}
+
+// acquire picks the key for one request attempt and waits for its rate limiter
+// When every healthy key is backing off after a 429, it waits for the first one to recover
+func (p *keyPool) acquire(ctx context.Context) (*apiKey, error) {
+	key := pinnedKey(ctx)
+	for key == nil {
+		var wait time.Duration
+		var err error
+		if key, wait, err = p.pick(time.Now()); err != nil {
+			return nil, err
+		}
+		if key != nil {
+			break
+		}
+
+		sessionlog.Logger(ctx, p.logger).Warn("All TMDB API keys are rate limited, waiting",
+			zap.Duration("wait", wait),
+		)
+		progress.Notify(ctx, fmt.Sprintf("All TMDB API keys are rate limited, waiting %s", wait.Round(time.Second)))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	key.waiting.Add(1)
+	defer key.waiting.Add(-1)
+	if err := key.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// pick returns the next usable key, or how long until a key finishes its 429 backoff
+func (p *keyPool) pick(now time.Time) (*apiKey, time.Duration, error) {
+	start := p.next.Add(1) - 1
+	var best *apiKey
+	healthy := 0
+	soonest := time.Duration(0)
+	for i := range p.keys {
+		key := p.keys[(start+uint64(i))%uint64(len(p.keys))]
+		ok, backoff := key.state(now)
+		if !ok {
+			continue
+		}
+		healthy++
+		if backoff > 0 {
+			if soonest == 0 || backoff < soonest {
+				soonest = backoff
+			}
+			continue
+		}
+		if best == nil {
+			best = key
+			if !p.leastLoaded {
+				break
+			}
+		} else if key.load() < best.load() {
+			best = key
+		}
+	}
+	if healthy == 0 {
+		return nil, 0, ErrNoHealthyKey
+	}
+	return best, soonest, nil
+}
+
+// hasHealthy reports whether any key is currently healthy
+func (p *keyPool) hasHealthy() bool {
+	now := time.Now()
+	for _, key := range p.keys {
+		if ok, _ := key.state(now); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// usage returns the usage of every key in configuration order
+func (p *keyPool) usage() []KeyUsage {
+	now := time.Now()
+	usage := make([]KeyUsage, 0, len(p.keys))
+	for _, key := range p.keys {
+		healthy, _ := key.state(now)
+		usage = append(usage, KeyUsage{
+			Name:      key.name,
+			Requests:  key.requests.Load(),
+			Rejected:  key.rejected.Load(),
+			Throttled: key.throttled.Load(),
+			InFlight:  key.load(),
+			Healthy:   healthy,
+		})
+	}
+	return usage
+}
+
+// observe updates the key's health from the response status of a request sent with it
+func (p *keyPool) observe(ctx context.Context, key *apiKey, resp *http.Response) {
+	now := time.Now()
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		key.rejected.Add(1)
+		key.mu.Lock()
+		key.unhealthyUntil = now.Add(unhealthyRetry)
+		key.mu.Unlock()
+		metrics.TMDBKeyHealthy.WithLabelValues(key.name).Set(0)
+		sessionlog.Logger(ctx, p.logger).Error("TMDB rejected API key, marking it unhealthy",
+			zap.String("api_key", key.name),
+			zap.String("credentials", key.creds.Kind()),
+			zap.Duration("retry_in", unhealthyRetry),
+		)
+	case http.StatusTooManyRequests:
+		key.throttled.Add(1)
+		backoff := retryAfter(resp.Header, now)
+		key.mu.Lock()
+		key.backoffUntil = now.Add(backoff)
+		key.mu.Unlock()
+		sessionlog.Logger(ctx, p.logger).Warn("TMDB API key rate limited, backing off",
+			zap.String("api_key", key.name),
+			zap.Duration("backoff", backoff),
+		)
+	default:
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return
+		}
+		key.mu.Lock()
+		recovered := !key.unhealthyUntil.IsZero()
+		key.unhealthyUntil = time.Time{}
+		key.mu.Unlock()
+		if recovered {
+			metrics.TMDBKeyHealthy.WithLabelValues(key.name).Set(1)
+			p.logger.Info("TMDB API key accepted again", zap.String("api_key", key.name))
+		}
+	}
+}
+
+// keyTransport sends each request with the credentials of the key acquired for it
+type keyTransport struct {
+	pool *keyPool
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *keyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := attemptKey(req.Context())
+	if key == nil {
+		return nil, errors.New("no TMDB API key acquired for request")
+	}
+	key.requests.Add(1)
+	key.inflight.Add(1)
+	defer key.inflight.Add(-1)
+
+	resp, err := (&authTransport{creds: key.creds, next: t.next}).RoundTrip(req)
+	if err != nil {
+		metrics.TMDBKeyRequests.WithLabelValues(key.name, "error").Inc()
+		return nil, err
+	}
+	metrics.TMDBKeyRequests.WithLabelValues(key.name, strconv.Itoa(resp.StatusCode)).Inc()
+	t.pool.observe(req.Context(), key, resp)
+	return resp, nil
+}
+
+// attemptKeyKey stores the key acquired for the current request attempt
+type attemptKeyKey struct{}
+
+// withAttemptKey returns ctx carrying the key acquired for a request attempt
+func withAttemptKey(ctx context.Context, key *apiKey) context.Context {
+	if key == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, attemptKeyKey{}, key)
+}
+
+// attemptKey returns the key acquired for the request attempt of ctx
+func attemptKey(ctx context.Context) *apiKey {
+	key, _ := ctx.Value(attemptKeyKey{}).(*apiKey)
+	return key
+}
+
+// pinnedKeyKey stores a key that requests made with the context must use
+type pinnedKeyKey struct{}
+
+// pinnedKey returns the key requests made with ctx must use, if any (see Ping)
+func pinnedKey(ctx context.Context) *apiKey {
+	key, _ := ctx.Value(pinnedKeyKey{}).(*apiKey)
+	return key
+}
@@ -0,0 +1,154 @@
+package tmdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// keyServer answers with the status configured per api_key and records the keys used
+type keyServer struct {
+	mu     sync.Mutex
+	status map[string]int
+	used   []string
+}
+
+func (s *keyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("api_key")
+	s.mu.Lock()
+	s.used = append(s.used, key)
+	status := s.status[key]
+	s.status[key] = 0 // 仅第一次返回配置的状态
+	if status == http.StatusUnauthorized {
+		s.status[key] = status
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "60")
+	}
+	if status != 0 {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"status_code":7,"status_message":"error"}`))
+		return
+	}
+	w.Write([]byte(`{"page":1,"results":[],"total_pages":0,"total_results":0}`))
+}
+
+// usedKeys returns the keys used so far
+func (s *keyServer) usedKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.used...)
+}
+
+// keyPoolConfig returns a TMDB configuration with a pool of keys
+func keyPoolConfig(baseURL string, keys ...string) config.TMDBConfig {
+	cfg := config.TMDBConfig{BaseURL: baseURL, RateLimit: 40}
+	for i, key := range keys {
+		cfg.APIKeys = append(cfg.APIKeys, config.APIKeyConfig{Name: fmt.Sprintf("key%d", i+1), Key: key})
+	}
+	return cfg
+}
+
+// TestKeyPool_RoundRobin tests spreading requests over the keys with per-key usage
+func TestKeyPool_RoundRobin(t *testing.T) {
+	backend := &keyServer{status: map[string]int{}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client := NewClient(keyPoolConfig(server.URL, "a", "b"), zap.NewNop())
+	assert.Nil(t, client.rateLimiter)
+	for i := range 4 {
+		_, err := client.Search(context.Background(), fmt.Sprintf("q%d", i), 1, nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"a", "b", "a", "b"}, backend.usedKeys())
+	usage := client.KeyUsage()
+	require.Len(t, usage, 2)
+	assert.Equal(t, KeyUsage{Name: "key1", Requests: 2, Healthy: true}, usage[0])
+	assert.Equal(t, KeyUsage{Name: "key2", Requests: 2, Healthy: true}, usage[1])
+}
+
+// TestKeyPool_Failover tests retrying with another key after a 401 or 429
+func TestKeyPool_Failover(t *testing.T) {
+	backend := &keyServer{status: map[string]int{"bad": http.StatusUnauthorized, "busy": http.StatusTooManyRequests}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client := NewClient(keyPoolConfig(server.URL, "bad", "busy", "good"), zap.NewNop())
+	ctx := context.Background()
+
+	// bad 被拒绝 → busy 返回 429 → good 成功
+	_, err := client.Search(ctx, "first", 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bad", "busy", "good"}, backend.usedKeys())
+
+	// bad 已不可用，busy 仍在退避：后续请求都使用 good
+	_, err = client.Search(ctx, "second", 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "good", backend.usedKeys()[3])
+
+	usage := client.KeyUsage()
+	assert.False(t, usage[0].Healthy)
+	assert.Equal(t, uint64(1), usage[0].Rejected)
+	assert.True(t, usage[1].Healthy)
+	assert.Equal(t, uint64(1), usage[1].Throttled)
+	assert.Equal(t, uint64(2), usage[2].Requests)
+}
+
+// TestKeyPool_Pick tests least-loaded selection, backoff waits and unhealthy keys
+func TestKeyPool_Pick(t *testing.T) {
+	pool := newKeyPool(keyPoolConfig("", "a", "b", "c"), zap.NewNop())
+	pool.leastLoaded = true
+	now := time.Now()
+
+	pool.keys[0].inflight.Add(2)
+	pool.keys[1].waiting.Add(1)
+	key, _, err := pool.pick(now)
+	require.NoError(t, err)
+	assert.Equal(t, "key3", key.name)
+
+	// 可用的 key 都在退避：返回最短的等待时间
+	pool.keys[0].unhealthyUntil = now.Add(time.Minute)
+	pool.keys[1].backoffUntil = now.Add(5 * time.Second)
+	pool.keys[2].backoffUntil = now.Add(2 * time.Second)
+	key, wait, err := pool.pick(now)
+	require.NoError(t, err)
+	assert.Nil(t, key)
+	assert.Equal(t, 2*time.Second, wait)
+
+	for _, k := range pool.keys {
+		k.unhealthyUntil = now.Add(time.Minute)
+	}
+	_, _, err = pool.pick(now)
+	assert.ErrorIs(t, err, ErrNoHealthyKey)
+}
+
+// TestKeyPool_Ping tests that Ping checks every key and fails only when all are rejected
+func TestKeyPool_Ping(t *testing.T) {
+	backend := &keyServer{status: map[string]int{"bad": http.StatusUnauthorized, "worse": http.StatusUnauthorized}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	client := NewClient(keyPoolConfig(server.URL, "bad", "good"), zap.NewNop())
+	require.NoError(t, client.Ping(context.Background()))
+	assert.False(t, client.KeyUsage()[0].Healthy)
+
+	client = NewClient(keyPoolConfig(server.URL, "bad", "worse"), zap.NewNop())
+	err := client.Ping(context.Background())
+	assert.ErrorContains(t, err, "key1: TMDB rejected")
+	assert.ErrorContains(t, err, "key2: TMDB rejected")
+}
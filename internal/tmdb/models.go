@@ -2,14 +2,28 @@ package tmdb
 
 // SearchResult represents a single result from TMDB multi search
 type SearchResult struct {
-	ID           int     `json:"id"`
-	MediaType    string  `json:"media_type"`     // "movie", "tv", "person"
-	Title        string  `json:"title"`          // 电影标题
-	Name         string  `json:"name"`           // 电视剧/人物名称
-	ReleaseDate  string  `json:"release_date"`   // 上映日期
-	FirstAirDate string  `json:"first_air_date"` // 首播日期
-	VoteAverage  float64 `json:"vote_average"`   // 评分
-	Overview     string  `json:"overview"`       // 简介
+	ID            int     `json:"id"`
+	MediaType     string  `json:"media_type"`               // "movie", "tv", "person"
+	Title         string  `json:"title"`                    // 电影标题
+	Name          string  `json:"name"`                     // 电视剧/人物名称
+	OriginalTitle string  `json:"original_title,omitempty"` // 电影原始标题（如中文/日文原名）
+	OriginalName  string  `json:"original_name,omitempty"`  // 电视剧原始名称
+	ReleaseDate   string  `json:"release_date"`             // 上映日期
+	FirstAirDate  string  `json:"first_air_date"`           // 首播日期
+	VoteAverage   float64 `json:"vote_average"`             // 评分
+	Overview      string  `json:"overview"`                 // 简介
+	Popularity    float64 `json:"popularity"`               // 热度
+
+	KnownForDepartment string         `json:"known_for_department,omitempty"` // 人物主要领域（仅 person）
+	KnownFor           []KnownForItem `json:"known_for,omitempty"`            // 人物代表作品（仅 person）
+}
+
+// KnownForItem represents a notable work listed for a person in search results
+type KnownForItem struct {
+	ID        int    `json:"id"`
+	MediaType string `json:"media_type"` // "movie" or "tv"
+	Title     string `json:"title"`      // 电影标题
+	Name      string `json:"name"`       // 电视剧名称
 }
 
 // SearchResponse represents the response from TMDB multi search API
@@ -70,8 +84,10 @@ type MovieDetails struct {
 	VoteAverage float64 `json:"vote_average"`
 	Overview    string  `json:"overview"`
 	Genres      []Genre `json:"genres"`
-	Credits     Credits `json:"credits"` // 通过 append_to_response 获取
-	Videos      Videos  `json:"videos"`  // 通过 append_to_response 获取
+	PosterPath  string  `json:"poster_path,omitempty"`
+	PosterURL   string  `json:"poster_url,omitempty"` // 由 client 根据 image_base_url 生成
+	Credits     Credits `json:"credits"`              // 通过 append_to_response 获取
+	Videos      Videos  `json:"videos"`               // 通过 append_to_response 获取
 }
 
 // TVDetails represents detailed information about a TV show
@@ -85,8 +101,10 @@ type TVDetails struct {
 	VoteAverage      float64 `json:"vote_average"`
 	Overview         string  `json:"overview"`
 	Genres           []Genre `json:"genres"`
-	Credits          Credits `json:"credits"` // 通过 append_to_response 获取
-	Videos           Videos  `json:"videos"`  // 通过 append_to_response 获取
+	PosterPath       string  `json:"poster_path,omitempty"`
+	PosterURL        string  `json:"poster_url,omitempty"` // 由 client 根据 image_base_url 生成
+	Credits          Credits `json:"credits"`              // 通过 append_to_response 获取
+	Videos           Videos  `json:"videos"`               // 通过 append_to_response 获取
 }
 
 // CombinedCastCredit represents a cast credit in combined credits
@@ -127,7 +145,9 @@ type PersonDetails struct {
 	Biography          string          `json:"biography"`
 	PlaceOfBirth       string          `json:"place_of_birth"`
 	KnownForDepartment string          `json:"known_for_department"`
-	CombinedCredits    CombinedCredits `json:"combined_credits"` // 通过 append_to_response 获取
+	ProfilePath        string          `json:"profile_path,omitempty"`
+	ProfileURL         string          `json:"profile_url,omitempty"` // 由 client 根据 image_base_url 生成
+	CombinedCredits    CombinedCredits `json:"combined_credits"`      // 通过 append_to_response 获取
 }
 
 // DiscoverMovieResult represents a single result from TMDB discover movies
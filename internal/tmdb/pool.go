@@ -0,0 +1,138 @@
+package tmdb
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/metrics"
+)
+
+// sweepInterval is how often lookups check for idle clients
+const sweepInterval = time.Minute
+
+// clientPool keeps a Client per client-supplied credential, each with its own
+// rate limiter and memory cache, so one team's traffic neither spends nor is
+// throttled by another's TMDB quota
+// Clients are created on first use and evicted after idleTimeout, or least
+// recently used first when maxClients is reached
+type clientPool struct {
+	cfg         config.TMDBConfig // 每个 key 的客户端配置
+	idleTimeout time.Duration
+	maxClients  int
+	logger      *zap.Logger
+
+	mu        sync.Mutex
+	transport http.RoundTripper // SetTransport 设置的 transport（nil 表示默认）
+	clients   map[string]*pooledClient
+	swept     time.Time
+
+	breaker *breaker // 服务器客户端的熔断器，TMDB 故障与凭据无关
+}
+
+// pooledClient is a per-key client and when it was last used
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+}
+
+// newClientPool creates the pool for the client_keys settings of cfg
+func newClientPool(cfg config.TMDBConfig, logger *zap.Logger) *clientPool {
+	keyCfg := cfg
+	keyCfg.APIKey, keyCfg.AccessToken, keyCfg.APIKeys = "", "", nil
+	keyCfg.ClientKeys = config.ClientKeysConfig{Mode: "disabled"}
+	// 磁盘缓存文件由服务器的客户端独占
+	keyCfg.Cache.Disk.Enabled = false
+	// 熔断器由所有客户端共用（见 get）
+	keyCfg.CircuitBreaker.Enabled = false
+	if cfg.ClientKeys.RateLimit > 0 {
+		keyCfg.RateLimit = cfg.ClientKeys.RateLimit
+	}
+	return &clientPool{
+		cfg:         keyCfg,
+		idleTimeout: cfg.ClientKeys.IdleTimeout,
+		maxClients:  cfg.ClientKeys.MaxClients,
+		logger:      logger,
+		clients:     make(map[string]*pooledClient),
+	}
+}
+
+// get returns the client for creds, creating it on first use
+func (p *clientPool) get(creds Credentials) *Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.swept) >= sweepInterval {
+		p.swept = now
+		for id, pc := range p.clients {
+			if now.Sub(pc.lastUsed) >= p.idleTimeout {
+				p.evictLocked(id, "idle")
+			}
+		}
+	}
+
+	id := creds.hash()
+	if pc, ok := p.clients[id]; ok {
+		pc.lastUsed = now
+		return pc.client
+	}
+
+	if len(p.clients) >= p.maxClients {
+		oldest := ""
+		for id, pc := range p.clients {
+			if oldest == "" || pc.lastUsed.Before(p.clients[oldest].lastUsed) {
+				oldest = id
+			}
+		}
+		p.evictLocked(oldest, "max_clients reached")
+	}
+
+	logger := p.logger.With(zap.String("tmdb_key", creds.Fingerprint()))
+	client := newClient(p.cfg, creds, logger)
+	if p.breaker != nil {
+		client.breaker = p.breaker
+		if client.cache != nil {
+			client.cache.KeepStale(p.breaker.maxStale)
+		}
+	}
+	if p.transport != nil {
+		client.SetTransport(p.transport)
+	}
+	p.clients[id] = &pooledClient{client: client, lastUsed: now}
+	metrics.ClientKeyClients.Set(float64(len(p.clients)))
+	logger.Info("Created TMDB client for client-supplied key", zap.Int("clients", len(p.clients)))
+	return client
+}
+
+// evictLocked removes a client; requests already using it complete normally
+func (p *clientPool) evictLocked(id, reason string) {
+	pc, ok := p.clients[id]
+	if !ok {
+		return
+	}
+	delete(p.clients, id)
+	pc.client.httpClient.GetClient().CloseIdleConnections()
+	metrics.ClientKeyClients.Set(float64(len(p.clients)))
+	pc.client.logger.Info("Evicted TMDB client for client-supplied key", zap.String("reason", reason))
+}
+
+// setTransport applies transport to existing and future clients
+func (p *clientPool) setTransport(transport http.RoundTripper) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transport = transport
+	for _, pc := range p.clients {
+		pc.client.SetTransport(transport)
+	}
+}
+
+// len returns the number of clients
+func (p *clientPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.clients)
+}
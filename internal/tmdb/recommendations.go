@@ -48,11 +48,11 @@ func (c *Client) getRecommendations(ctx context.Context, endpoint, mediaType str
 	// Rate limiting is handled by OnBeforeRequest middleware
 	// 调用 TMDB API /movie/{id}/recommendations 或 /tv/{id}/recommendations 端点
 	var recommendationsResp RecommendationsResponse
-	resp, err := c.httpClient.R().
+	req := c.httpClient.R().
 		SetContext(ctx).
 		SetQueryParam("page", fmt.Sprintf("%d", page)).
-		SetResult(&recommendationsResp).
-		Get(endpoint)
+		SetResult(&recommendationsResp)
+	resp, err := c.get(req, endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("get recommendations failed: %w", err)
@@ -0,0 +1,173 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/XDwanj/tmdb-mcp/internal/cassette"
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// Retry defaults, used for fields of tmdb.retry left at their zero value
+const (
+	defaultMaxAttempts = 4
+	defaultBaseDelay   = 1 * time.Second
+	defaultMaxDelay    = 10 * time.Second
+)
+
+// defaultRetryStatuses are the statuses retried when tmdb.retry.statuses is empty
+var defaultRetryStatuses = []int{429, 500, 502, 503, 504}
+
+// errCallTimeout is the cause of a call's context expiring after tmdb.retry.timeout
+var errCallTimeout = errors.New("TMDB call timeout")
+
+// retryPolicy decides which failed attempts are retried and how long to wait (tmdb.retry)
+type retryPolicy struct {
+	maxAttempts   int
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+	jitter        float64
+	statuses      map[int]bool
+	networkErrors bool
+	timeout       time.Duration
+	random        func() float64 // [0,1) 随机数，便于测试替换
+}
+
+// newRetryPolicy creates the retry policy of cfg
+func newRetryPolicy(cfg config.RetryConfig) *retryPolicy {
+	p := &retryPolicy{
+		maxAttempts:   cfg.MaxAttempts,
+		baseDelay:     cfg.BaseDelay,
+		maxDelay:      cfg.MaxDelay,
+		jitter:        cfg.Jitter,
+		statuses:      make(map[int]bool),
+		networkErrors: cfg.NetworkErrors,
+		timeout:       cfg.Timeout,
+		random:        rand.Float64,
+	}
+	if p.maxAttempts <= 0 {
+		p.maxAttempts = defaultMaxAttempts
+	}
+	if p.baseDelay <= 0 {
+		p.baseDelay = defaultBaseDelay
+	}
+	if p.maxDelay <= 0 {
+		p.maxDelay = max(defaultMaxDelay, p.baseDelay)
+	}
+	statuses := cfg.Statuses
+	if len(statuses) == 0 {
+		statuses = defaultRetryStatuses
+	}
+	for _, status := range statuses {
+		p.statuses[status] = true
+	}
+	return p
+}
+
+// retries returns the number of retries after the first attempt
+func (p *retryPolicy) retries() int {
+	return p.maxAttempts - 1
+}
+
+// shouldRetry reports whether an attempt that ended with resp and err is retried
+// A retry that could not start before the deadline of the call is not attempted,
+// so the caller gets the last response right away instead of a timeout
+func (p *retryPolicy) shouldRetry(resp *resty.Response, err error, honorRetryAfter bool) bool {
+	// 没有 response 说明请求未发出（如限流等待失败），不重试
+	if resp == nil || resp.Request == nil {
+		return false
+	}
+	if err != nil {
+		if !p.networkErrors || !isNetworkError(err) {
+			return false
+		}
+	} else if !p.statuses[resp.StatusCode()] {
+		return false
+	}
+
+	if deadline, ok := resp.Request.Context().Deadline(); ok && time.Until(deadline) < p.minWait(resp, honorRetryAfter) {
+		return false
+	}
+	return true
+}
+
+// wait returns the jittered wait before retrying the attempt that ended with resp
+// Jitter shortens the backoff by up to jitter of it; an honored Retry-After is exact
+// When the jittered wait would end past the call deadline, the shortest wait is
+// used instead, which shouldRetry has checked to fit
+func (p *retryPolicy) wait(resp *resty.Response, honorRetryAfter bool) time.Duration {
+	wait := p.delay(resp, honorRetryAfter)
+	if !p.honorsRetryAfter(resp, honorRetryAfter) {
+		wait -= time.Duration(p.jitter * p.random() * float64(wait))
+	}
+	if resp != nil && resp.Request != nil {
+		if deadline, ok := resp.Request.Context().Deadline(); ok && time.Until(deadline) < wait {
+			wait = p.minWait(resp, honorRetryAfter)
+		}
+	}
+	return wait
+}
+
+// minWait returns the shortest wait that wait can return for resp
+func (p *retryPolicy) minWait(resp *resty.Response, honorRetryAfter bool) time.Duration {
+	wait := p.delay(resp, honorRetryAfter)
+	if !p.honorsRetryAfter(resp, honorRetryAfter) {
+		wait -= time.Duration(p.jitter * float64(wait))
+	}
+	return wait
+}
+
+// delay returns the wait before retrying the attempt that ended with resp, before jitter:
+// the Retry-After of a 429 when honored, otherwise the exponential backoff for the attempt
+func (p *retryPolicy) delay(resp *resty.Response, honorRetryAfter bool) time.Duration {
+	if p.honorsRetryAfter(resp, honorRetryAfter) {
+		return retryAfter(resp.Header(), time.Now())
+	}
+	attempt := 1
+	if resp != nil && resp.Request != nil && resp.Request.Attempt > 0 {
+		attempt = resp.Request.Attempt
+	}
+	backoff := p.baseDelay
+	for i := 1; i < attempt && backoff < p.maxDelay; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.maxDelay)
+}
+
+// honorsRetryAfter reports whether resp is a 429 whose Retry-After is waited for exactly
+// With a key pool the retry uses another key instead, so the backoff applies
+func (p *retryPolicy) honorsRetryAfter(resp *resty.Response, honorRetryAfter bool) bool {
+	return honorRetryAfter && resp != nil && resp.RawResponse != nil &&
+		resp.StatusCode() == http.StatusTooManyRequests && resp.Header().Get("Retry-After") != ""
+}
+
+// isNetworkError reports whether err is a transport failure worth retrying
+// (connection errors, attempt timeouts); cassette misses and cancellations are not
+func isNetworkError(err error) bool {
+	if errors.Is(err, cassette.ErrNoRecording) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+// withCallTimeout returns ctx bounded by the per-call timeout of tmdb.retry
+func (p *retryPolicy) withCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, p.timeout, errCallTimeout)
+}
+
+// callTimedOut reports whether ctx expired because of the per-call timeout
+func callTimedOut(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errCallTimeout)
+}
@@ -0,0 +1,209 @@
+package tmdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/cassette"
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// attemptResponse builds the response of attempt with status and headers
+func attemptResponse(ctx context.Context, attempt, status int, header http.Header) *resty.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	req := resty.New().R().SetContext(ctx)
+	req.Attempt = attempt
+	return &resty.Response{Request: req, RawResponse: &http.Response{StatusCode: status, Header: header}}
+}
+
+// TestRetryPolicy_Defaults tests that zero values take the default policy
+func TestRetryPolicy_Defaults(t *testing.T) {
+	p := newRetryPolicy(config.RetryConfig{})
+	assert.Equal(t, 4, p.maxAttempts)
+	assert.Equal(t, 3, p.retries())
+	assert.Equal(t, time.Second, p.baseDelay)
+	assert.Equal(t, 10*time.Second, p.maxDelay)
+	for _, status := range []int{429, 500, 502, 503, 504} {
+		assert.True(t, p.statuses[status], "status %d", status)
+	}
+	assert.False(t, p.statuses[404])
+}
+
+// TestRetryPolicy_Backoff tests exponential backoff, its cap and jitter
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := newRetryPolicy(config.RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond})
+	ctx := context.Background()
+	assert.Equal(t, 100*time.Millisecond, p.wait(attemptResponse(ctx, 1, 503, nil), true))
+	assert.Equal(t, 200*time.Millisecond, p.wait(attemptResponse(ctx, 2, 503, nil), true))
+	assert.Equal(t, 300*time.Millisecond, p.wait(attemptResponse(ctx, 3, 503, nil), true))
+	assert.Equal(t, 300*time.Millisecond, p.wait(attemptResponse(ctx, 30, 503, nil), true))
+
+	// 抖动最多缩短 jitter 比例的等待
+	p.jitter = 0.5
+	p.random = func() float64 { return 0.999999 }
+	wait := p.wait(attemptResponse(ctx, 2, 503, nil), true)
+	assert.InDelta(t, float64(100*time.Millisecond), float64(wait), float64(time.Millisecond))
+	assert.Equal(t, 100*time.Millisecond, p.minWait(attemptResponse(ctx, 2, 503, nil), true))
+	p.random = func() float64 { return 0 }
+	assert.Equal(t, 200*time.Millisecond, p.wait(attemptResponse(ctx, 2, 503, nil), true))
+}
+
+// TestRetryPolicy_RetryAfter tests that a 429 waits exactly for Retry-After unless a key pool retries with another key
+func TestRetryPolicy_RetryAfter(t *testing.T) {
+	p := newRetryPolicy(config.RetryConfig{BaseDelay: 100 * time.Millisecond, Jitter: 0.5})
+	p.random = func() float64 { return 0.5 }
+	resp := attemptResponse(context.Background(), 1, 429, http.Header{"Retry-After": {"3"}})
+
+	assert.Equal(t, 3*time.Second, p.wait(resp, true))
+	assert.Equal(t, 3*time.Second, p.minWait(resp, true))
+	assert.Equal(t, 75*time.Millisecond, p.wait(resp, false))
+
+	// 没有 Retry-After 时使用退避
+	resp = attemptResponse(context.Background(), 1, 429, nil)
+	assert.Equal(t, 75*time.Millisecond, p.wait(resp, true))
+}
+
+// TestRetryPolicy_ShouldRetry tests which statuses and errors are retried
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	ctx := context.Background()
+	p := newRetryPolicy(config.RetryConfig{BaseDelay: 100 * time.Millisecond, NetworkErrors: true})
+	networkErr := &url.Error{Op: "Get", URL: "https://api.themoviedb.org/3/movie/1", Err: errors.New("connection refused")}
+
+	assert.True(t, p.shouldRetry(attemptResponse(ctx, 1, 504, nil), nil, true))
+	assert.True(t, p.shouldRetry(attemptResponse(ctx, 1, 429, nil), nil, true))
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 404, nil), nil, true))
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 200, nil), nil, true))
+	assert.True(t, p.shouldRetry(attemptResponse(ctx, 1, 0, nil), networkErr, true))
+
+	// 回放缺失和取消不是网络故障；请求未发出时不重试
+	missing := &url.Error{Op: "Get", URL: networkErr.URL, Err: fmt.Errorf("%w: GET /movie/1", cassette.ErrNoRecording)}
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 0, nil), missing, true))
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 0, nil), &url.Error{Op: "Get", URL: networkErr.URL, Err: context.Canceled}, true))
+	assert.False(t, p.shouldRetry(nil, networkErr, true))
+
+	// 自定义状态码与关闭网络错误重试
+	p = newRetryPolicy(config.RetryConfig{Statuses: []int{502}})
+	assert.True(t, p.shouldRetry(attemptResponse(ctx, 1, 502, nil), nil, true))
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 503, nil), nil, true))
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 0, nil), networkErr, true))
+}
+
+// TestRetryPolicy_Deadline tests that no retry is started that the call deadline would cut short
+func TestRetryPolicy_Deadline(t *testing.T) {
+	p := newRetryPolicy(config.RetryConfig{BaseDelay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 503, nil), nil, true))
+	assert.False(t, p.shouldRetry(attemptResponse(ctx, 1, 429, http.Header{"Retry-After": {"2"}}), nil, true))
+
+	p.baseDelay = 10 * time.Millisecond
+	assert.True(t, p.shouldRetry(attemptResponse(ctx, 1, 503, nil), nil, true))
+}
+
+// TestRetryPolicy_JitterWithinDeadline tests that a jittered wait never runs past the call deadline
+func TestRetryPolicy_JitterWithinDeadline(t *testing.T) {
+	p := newRetryPolicy(config.RetryConfig{BaseDelay: 400 * time.Millisecond, Jitter: 0.5})
+	p.random = func() float64 { return 0 } // 不缩短等待
+
+	// 没有时限：完整退避
+	assert.Equal(t, 400*time.Millisecond, p.wait(attemptResponse(context.Background(), 1, 503, nil), true))
+
+	// 最短等待（200ms）在时限内：重试，且等待不超过时限
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	resp := attemptResponse(ctx, 1, 503, nil)
+	require.True(t, p.shouldRetry(resp, nil, true))
+	assert.Equal(t, 200*time.Millisecond, p.wait(resp, true))
+
+	// 抖动后的等待在时限内时保持不变
+	p.random = func() float64 { return 0.8 }
+	assert.Equal(t, 240*time.Millisecond, p.wait(resp, true))
+
+	// 最短等待也超出时限：不重试
+	short, cancelShort := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShort()
+	assert.False(t, p.shouldRetry(attemptResponse(short, 1, 503, nil), nil, true))
+}
+
+// TestClient_Retry tests that the client retries 504 responses and network errors per tmdb.retry
+func TestClient_Retry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":27205,"title":"Inception"}`))
+	}))
+	defer server.Close()
+
+	cfg := config.TMDBConfig{
+		APIKey:    "test-api-key",
+		Language:  "en-US",
+		RateLimit: 40,
+		BaseURL:   server.URL,
+		Retry:     config.RetryConfig{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, Timeout: 5 * time.Second},
+	}
+	client := NewClient(cfg, zap.NewNop())
+	details, err := client.GetMovieDetails(context.Background(), 27205, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Inception", details.Title)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// 达到最大尝试次数后返回最后的响应
+	atomic.StoreInt32(&requests, -10)
+	_, err = client.GetMovieDetails(context.Background(), 27205, nil)
+	var tmdbErr *TMDBError
+	require.ErrorAs(t, err, &tmdbErr)
+	assert.Equal(t, ErrorTypeServer, tmdbErr.ErrorType)
+	assert.Equal(t, int32(-7), atomic.LoadInt32(&requests))
+}
+
+// TestClient_Retry_Timeout tests that a call, retries included, ends within tmdb.retry.timeout
+func TestClient_Retry_Timeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.TMDBConfig{
+		APIKey:    "test-api-key",
+		Language:  "en-US",
+		RateLimit: 40,
+		BaseURL:   server.URL,
+		Retry:     config.RetryConfig{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, NetworkErrors: true, Timeout: 100 * time.Millisecond},
+	}
+	client := NewClient(cfg, zap.NewNop())
+
+	start := time.Now()
+	_, err := client.GetMovieDetails(context.Background(), 27205, nil)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	var tmdbErr *TMDBError
+	require.ErrorAs(t, err, &tmdbErr)
+	assert.Equal(t, ErrorTypeNetwork, tmdbErr.ErrorType)
+	assert.Contains(t, tmdbErr.StatusMessage, "within 100ms")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
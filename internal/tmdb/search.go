@@ -46,7 +46,7 @@ func (c *Client) Search(ctx context.Context, query string, page int, language *s
 		req.SetQueryParam("language", *language)
 	}
 
-	resp, err := req.Get(endpoint)
+	resp, err := c.get(req, endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
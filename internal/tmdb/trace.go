@@ -0,0 +1,65 @@
+package tmdb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/XDwanj/tmdb-mcp/internal/tracing"
+)
+
+// attemptParentKey stores the context of the TMDB call a request attempt belongs to
+type attemptParentKey struct{}
+
+// attemptSpanKey stores the span of the current HTTP attempt
+type attemptSpanKey struct{}
+
+// attemptParent returns the context of the TMDB call that ctx's attempt belongs to
+// resty reuses the request context across retries, so without this every retry
+// span would become a child of the previous attempt
+func attemptParent(ctx context.Context) context.Context {
+	if parent, ok := ctx.Value(attemptParentKey{}).(context.Context); ok {
+		return parent
+	}
+	return ctx
+}
+
+// startAttempt starts the client span of one HTTP attempt of req and returns its context
+func startAttempt(req *resty.Request, basePath string) context.Context {
+	parent := attemptParent(req.Context())
+	endpoint := endpointLabel(req.URL, basePath)
+	ctx, span := tracing.Tracer().Start(parent, req.Method+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			tracing.AttrHTTPMethod.String(req.Method),
+			tracing.AttrTMDBEndpoint.String(endpoint),
+		),
+	)
+	if req.Attempt > 1 {
+		span.SetAttributes(tracing.AttrHTTPAttempt.Int(req.Attempt - 1))
+	}
+	ctx = context.WithValue(ctx, attemptSpanKey{}, span)
+	return context.WithValue(ctx, attemptParentKey{}, parent)
+}
+
+// endAttempt ends the attempt span stored in ctx with the response status or error
+func endAttempt(ctx context.Context, status int, err error) {
+	span, ok := ctx.Value(attemptSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if status > 0 {
+		span.SetAttributes(tracing.AttrHTTPStatus.Int(status))
+		if status >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,63 @@
+package tmdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/XDwanj/tmdb-mcp/internal/tracing"
+)
+
+// TestClient_Tracing_RetryAttempts tests that each retry is a sibling span under the TMDB call
+func TestClient_Tracing_RetryAttempts(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(MovieDetails{ID: 27205, Title: "Inception"})
+	}))
+	defer server.Close()
+
+	client := createCachedTestClient(t, server.URL)
+	_, err := client.GetMovieDetails(context.Background(), 27205, nil)
+	require.NoError(t, err)
+
+	var call sdktrace.ReadOnlySpan
+	var attempts []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "TMDB /movie/{id}":
+			call = span
+		case "GET /movie/{id}":
+			attempts = append(attempts, span)
+		}
+	}
+	require.NotNil(t, call)
+	require.Len(t, attempts, 2)
+
+	assert.Contains(t, call.Attributes(), tracing.AttrTMDBCache.String("miss"))
+	for _, attempt := range attempts {
+		assert.Equal(t, call.SpanContext().SpanID(), attempt.Parent().SpanID())
+	}
+	assert.Contains(t, attempts[0].Attributes(), tracing.AttrHTTPStatus.Int(503))
+	assert.Contains(t, attempts[1].Attributes(), tracing.AttrHTTPStatus.Int(200))
+	assert.Contains(t, attempts[1].Attributes(), tracing.AttrHTTPAttempt.Int(1))
+}
@@ -30,11 +30,11 @@ func (c *Client) GetTrending(ctx context.Context, mediaType, timeWindow string,
 	// Rate limiting is handled by OnBeforeRequest middleware
 	// 调用 TMDB API /trending/{media_type}/{time_window} 端点
 	var trendingResp TrendingResponse
-	resp, err := c.httpClient.R().
+	req := c.httpClient.R().
 		SetContext(ctx).
 		SetQueryParam("page", fmt.Sprintf("%d", page)).
-		SetResult(&trendingResp).
-		Get(endpoint)
+		SetResult(&trendingResp)
+	resp, err := c.get(req, endpoint)
 
 	if err != nil {
 		return nil, fmt.Errorf("get trending failed: %w", err)
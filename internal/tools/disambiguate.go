@@ -0,0 +1,292 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxCandidates is the maximum number of candidates offered for disambiguation
+	maxCandidates = 5
+
+	// candidateCastSize is the number of top cast members shown per candidate
+	candidateCastSize = 3
+)
+
+// Candidate is a possible match for a title-based lookup
+type Candidate struct {
+	MediaType string   `json:"media_type"`
+	ID        int      `json:"id"`
+	Title     string   `json:"title"`
+	Original  string   `json:"original_title,omitempty"` // 原始标题（与 title 相同时省略）
+	Year      string   `json:"year,omitempty"`
+	TopCast   []string `json:"top_cast,omitempty"`  // 电影/电视剧主演
+	KnownFor  []string `json:"known_for,omitempty"` // 人物代表作品
+	Label     string   `json:"label"`               // 向用户展示的选项文本
+
+	details any // 已获取的详情，选中后直接复用，避免重复请求
+}
+
+// DisambiguationResponse is returned when a lookup matches several entries and the
+// user could not be asked to choose (client without elicitation, or the user declined)
+type DisambiguationResponse struct {
+	Query      string      `json:"query"`
+	Message    string      `json:"message"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// lookupCandidates searches for a title and returns the plausible matches in TMDB ranking order
+// Results whose title equals the query (ignoring case and punctuation) are plausible;
+// when none match exactly, only the top result is kept
+func lookupCandidates(ctx context.Context, tmdbClient *tmdb.Client, query, mediaType string, year *int, language *string) ([]Candidate, error) {
+	searchResp, err := tmdbClient.Search(ctx, query, 1, language)
+	if err != nil {
+		return nil, convertTMDBError(err, "search results")
+	}
+
+	var all, exact []Candidate
+	normalizedQuery := normalizeTitle(query)
+	for _, result := range searchResp.Results {
+		if mediaType != "" && result.MediaType != mediaType {
+			continue
+		}
+		candidate := newCandidate(result)
+		if year != nil && candidate.MediaType != "person" && candidate.Year != fmt.Sprintf("%d", *year) {
+			continue
+		}
+		all = append(all, candidate)
+		if normalizeTitle(candidate.Title) == normalizedQuery || (candidate.Original != "" && normalizeTitle(candidate.Original) == normalizedQuery) {
+			exact = append(exact, candidate)
+		}
+	}
+
+	switch {
+	case len(exact) > 0:
+		if len(exact) > maxCandidates {
+			exact = exact[:maxCandidates]
+		}
+		return exact, nil
+	case len(all) > 0:
+		return all[:1], nil
+	}
+	return nil, nil
+}
+
+// newCandidate converts a search result to a candidate
+func newCandidate(result tmdb.SearchResult) Candidate {
+	candidate := Candidate{MediaType: result.MediaType, ID: result.ID, Title: result.Title, Original: result.OriginalTitle}
+	date := result.ReleaseDate
+	if result.MediaType != "movie" {
+		candidate.Title = result.Name
+		candidate.Original = result.OriginalName
+		date = result.FirstAirDate
+	}
+	if candidate.Original == candidate.Title {
+		candidate.Original = ""
+	}
+	if len(date) >= 4 {
+		candidate.Year = date[:4]
+	}
+	for _, work := range result.KnownFor {
+		title := work.Title
+		if title == "" {
+			title = work.Name
+		}
+		if title != "" && len(candidate.KnownFor) < candidateCastSize {
+			candidate.KnownFor = append(candidate.KnownFor, title)
+		}
+	}
+	return candidate
+}
+
+// normalizeTitle lowercases a title and drops punctuation and spaces for comparison
+// CJK characters are letters in Unicode and are kept as-is
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// enrichCandidates fetches details for each candidate to show its top cast
+// Details are kept so that the chosen candidate does not need another request
+func enrichCandidates(ctx context.Context, tmdbClient *tmdb.Client, logger *zap.Logger, candidates []Candidate, language *string) {
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.MediaType == "movie" || candidate.MediaType == "tv" {
+			details, err := fetchDetails(ctx, tmdbClient, logger, candidate.MediaType, candidate.ID, language)
+			if err != nil {
+				// 获取失败时仍然提供候选项，只是没有演员信息
+				logger.Debug("Failed to fetch candidate details",
+					zap.String("media_type", candidate.MediaType),
+					zap.Int("id", candidate.ID),
+					zap.Error(err),
+				)
+			} else {
+				candidate.details = details
+				candidate.TopCast = topCast(details)
+			}
+		}
+		candidate.Label = candidateLabel(*candidate)
+	}
+}
+
+// topCast returns the names of the first cast members in movie or TV details
+func topCast(details any) []string {
+	var cast []tmdb.CastMember
+	switch d := details.(type) {
+	case *tmdb.MovieDetails:
+		cast = d.Credits.Cast
+	case *tmdb.TVDetails:
+		cast = d.Credits.Cast
+	}
+	if len(cast) > candidateCastSize {
+		cast = cast[:candidateCastSize]
+	}
+	names := make([]string, 0, len(cast))
+	for _, member := range cast {
+		names = append(names, member.Name)
+	}
+	return names
+}
+
+// candidateLabel builds the option text shown to the user,
+// e.g. "The Office (2005) · TV show · Steve Carell, Rainn Wilson [tv:2316]"
+// The ID suffix keeps labels unique for same-name entries
+func candidateLabel(c Candidate) string {
+	parts := []string{c.Title}
+	if c.Year != "" {
+		parts[0] = fmt.Sprintf("%s (%s)", c.Title, c.Year)
+	}
+
+	switch c.MediaType {
+	case "movie":
+		parts = append(parts, "Movie")
+	case "tv":
+		parts = append(parts, "TV show")
+	case "person":
+		parts = append(parts, "Person")
+	}
+	if len(c.TopCast) > 0 {
+		parts = append(parts, strings.Join(c.TopCast, ", "))
+	}
+	if len(c.KnownFor) > 0 {
+		parts = append(parts, "known for "+strings.Join(c.KnownFor, ", "))
+	}
+	return fmt.Sprintf("%s [%s:%d]", strings.Join(parts, " · "), c.MediaType, c.ID)
+}
+
+// supportsElicitation reports whether the client declared the elicitation capability
+func supportsElicitation(session *mcp.ServerSession) bool {
+	if session == nil {
+		return false
+	}
+	params := session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
+
+// elicitChoice asks the user to pick one of the candidates
+// It returns nil when the user declines or cancels
+func elicitChoice(ctx context.Context, session *mcp.ServerSession, query string, candidates []Candidate) (*Candidate, error) {
+	labels := make([]any, len(candidates))
+	for i, candidate := range candidates {
+		labels[i] = candidate.Label
+	}
+
+	result, err := session.Elicit(ctx, &mcp.ElicitParams{
+		Message: fmt.Sprintf("Several entries match %q. Which one did you mean?", query),
+		RequestedSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"choice": map[string]any{
+					"type":        "string",
+					"title":       "Match",
+					"description": "Title (year) · type · top cast",
+					"enum":        labels,
+				},
+			},
+			"required": []string{"choice"},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to ask user to choose a match: %w", err)
+	}
+	if result.Action != "accept" {
+		return nil, nil
+	}
+
+	choice, _ := result.Content["choice"].(string)
+	for i := range candidates {
+		if candidates[i].Label == choice {
+			return &candidates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("invalid choice: %q is not one of the offered matches", choice)
+}
+
+// resolveByTitle resolves a title-based lookup to a single entry
+// When several entries match, the user is asked to choose via elicitation; if that is
+// not possible, the ranked candidates are returned instead of a guess
+// Exactly one of resolved and ambiguous is non-nil on success
+func resolveByTitle(ctx context.Context, req *mcp.CallToolRequest, tmdbClient *tmdb.Client, logger *zap.Logger, query, mediaType string, year *int, language *string) (resolved *Candidate, ambiguous *DisambiguationResponse, err error) {
+	// media_type 在按标题查询时可选，但如果指定必须有效
+	if mediaType != "" && mediaType != "movie" && mediaType != "tv" && mediaType != "person" {
+		return nil, nil, fmt.Errorf("invalid media_type: must be 'movie', 'tv', or 'person'")
+	}
+
+	candidates, err := lookupCandidates(ctx, tmdbClient, query, mediaType, year, language)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no matches found for %q", query)
+	}
+	if len(candidates) == 1 {
+		return &candidates[0], nil, nil
+	}
+
+	enrichCandidates(ctx, tmdbClient, logger, candidates, language)
+
+	var session *mcp.ServerSession
+	if req != nil {
+		session = req.Session
+	}
+	if supportsElicitation(session) {
+		chosen, err := elicitChoice(ctx, session, query, candidates)
+		if err != nil {
+			return nil, nil, err
+		}
+		if chosen != nil {
+			logger.Info("Ambiguous lookup resolved by user",
+				zap.String("query", query),
+				zap.String("media_type", chosen.MediaType),
+				zap.Int("id", chosen.ID),
+			)
+			return chosen, nil, nil
+		}
+		return nil, &DisambiguationResponse{
+			Query:      query,
+			Message:    "The user did not choose a match. Ask which entry they meant, then call get_details with its media_type and id",
+			Candidates: candidates,
+		}, nil
+	}
+
+	logger.Info("Ambiguous lookup, returning candidates",
+		zap.String("query", query),
+		zap.Int("candidates", len(candidates)),
+	)
+	return nil, &DisambiguationResponse{
+		Query:      query,
+		Message:    "Multiple entries match. Ask the user which one they meant, then call get_details with its media_type and id",
+		Candidates: candidates,
+	}, nil
+}
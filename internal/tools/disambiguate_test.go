@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+)
+
+// TestNormalizeTitle tests title normalization for exact-match comparison
+func TestNormalizeTitle(t *testing.T) {
+	assert.Equal(t, "theoffice", normalizeTitle("The Office"))
+	assert.Equal(t, "theoffice", normalizeTitle("the office!"))
+	assert.Equal(t, "千与千寻", normalizeTitle("千与千寻"))
+	assert.Equal(t, "spiderman2", normalizeTitle("Spider-Man 2"))
+}
+
+// TestNewCandidate tests conversion of search results to candidates
+func TestNewCandidate(t *testing.T) {
+	movie := newCandidate(tmdb.SearchResult{ID: 1, MediaType: "movie", Title: "Dune", ReleaseDate: "2021-09-15"})
+	assert.Equal(t, Candidate{MediaType: "movie", ID: 1, Title: "Dune", Year: "2021"}, movie)
+
+	tv := newCandidate(tmdb.SearchResult{ID: 2316, MediaType: "tv", Name: "The Office", FirstAirDate: "2005-03-24"})
+	assert.Equal(t, "The Office", tv.Title)
+	assert.Equal(t, "2005", tv.Year)
+
+	person := newCandidate(tmdb.SearchResult{
+		ID:        1,
+		MediaType: "person",
+		Name:      "Chris Evans",
+		KnownFor: []tmdb.KnownForItem{
+			{MediaType: "movie", Title: "The Avengers"},
+			{MediaType: "tv", Name: "Defending Jacob"},
+			{MediaType: "movie", Title: "Knives Out"},
+			{MediaType: "movie", Title: "Snowpiercer"},
+		},
+	})
+	assert.Equal(t, []string{"The Avengers", "Defending Jacob", "Knives Out"}, person.KnownFor)
+}
+
+// TestCandidateLabel tests the option text shown to the user
+func TestCandidateLabel(t *testing.T) {
+	label := candidateLabel(Candidate{
+		MediaType: "tv",
+		ID:        2316,
+		Title:     "The Office",
+		Year:      "2005",
+		TopCast:   []string{"Steve Carell", "Rainn Wilson"},
+	})
+	assert.Equal(t, "The Office (2005) · TV show · Steve Carell, Rainn Wilson [tv:2316]", label)
+
+	label = candidateLabel(Candidate{MediaType: "person", ID: 7, Title: "Chris Evans", KnownFor: []string{"Knives Out"}})
+	assert.Equal(t, "Chris Evans · Person · known for Knives Out [person:7]", label)
+}
+
+// TestTopCast tests extraction of top cast names from details
+func TestTopCast(t *testing.T) {
+	details := &tmdb.TVDetails{Credits: tmdb.Credits{Cast: []tmdb.CastMember{
+		{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"},
+	}}}
+	assert.Equal(t, []string{"A", "B", "C"}, topCast(details))
+	assert.Empty(t, topCast(&tmdb.PersonDetails{}))
+}
+
+// TestResolveByTitle_InvalidMediaType tests media_type validation for title lookups
+func TestResolveByTitle_InvalidMediaType(t *testing.T) {
+	tool := newTestBatchTool(1)
+
+	_, _, err := resolveByTitle(context.Background(), nil, tool.tmdbClient, tool.logger, "The Office", "film", nil, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid media_type")
+}
+
+// TestSupportsElicitation_NoSession tests that elicitation is skipped without a session
+func TestSupportsElicitation_NoSession(t *testing.T) {
+	assert.False(t, supportsElicitation(nil))
+}
+
+// TestElicitChoice tests that the user's choice is mapped back to a candidate
+func TestElicitChoice(t *testing.T) {
+	candidates := []Candidate{
+		{MediaType: "tv", ID: 2996, Title: "The Office", Year: "2001"},
+		{MediaType: "tv", ID: 2316, Title: "The Office", Year: "2005"},
+	}
+	for i := range candidates {
+		candidates[i].Label = candidateLabel(candidates[i])
+	}
+
+	tests := []struct {
+		name     string
+		result   *mcp.ElicitResult
+		expected *Candidate
+	}{
+		{
+			name:     "accept",
+			result:   &mcp.ElicitResult{Action: "accept", Content: map[string]any{"choice": candidates[1].Label}},
+			expected: &candidates[1],
+		},
+		{
+			name:     "decline",
+			result:   &mcp.ElicitResult{Action: "decline"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+			client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, &mcp.ClientOptions{
+				ElicitationHandler: func(context.Context, *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+					return tt.result, nil
+				},
+			})
+
+			clientTransport, serverTransport := mcp.NewInMemoryTransports()
+			serverSession, err := server.Connect(ctx, serverTransport, nil)
+			require.NoError(t, err)
+			clientSession, err := client.Connect(ctx, clientTransport, nil)
+			require.NoError(t, err)
+			defer clientSession.Close()
+			defer serverSession.Close()
+
+			require.True(t, supportsElicitation(serverSession))
+			chosen, err := elicitChoice(ctx, serverSession, "The Office", candidates)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, chosen)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 
+	"github.com/XDwanj/tmdb-mcp/internal/config"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
@@ -10,15 +11,17 @@ import (
 
 // DiscoverMoviesTool implements the MCP discover_movies tool
 type DiscoverMoviesTool struct {
-	tmdbClient *tmdb.Client
-	logger     *zap.Logger
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	logger      *zap.Logger
 }
 
 // NewDiscoverMoviesTool creates a new DiscoverMoviesTool instance
-func NewDiscoverMoviesTool(tmdbClient *tmdb.Client, logger *zap.Logger) *DiscoverMoviesTool {
+func NewDiscoverMoviesTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, logger *zap.Logger) *DiscoverMoviesTool {
 	return &DiscoverMoviesTool{
-		tmdbClient: tmdbClient,
-		logger:     logger,
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		logger:      logger,
 	}
 }
 
@@ -27,6 +30,11 @@ func (t *DiscoverMoviesTool) Name() string {
 	return "discover_movies"
 }
 
+// Title returns the human-readable tool title
+func (t *DiscoverMoviesTool) Title() string {
+	return "Discover Movies"
+}
+
 // Description returns the tool description
 func (t *DiscoverMoviesTool) Description() string {
 	return "Discover movies using filters like genre, year, rating, and language. " +
@@ -77,19 +85,24 @@ func (t *DiscoverMoviesTool) Handler() func(context.Context, *mcp.CallToolReques
 		if result == nil || len(result.Results) == 0 {
 			t.logger.Info("No movies found matching criteria")
 			// 返回完整的响应对象，而不是空数组
-			if result != nil {
-				return &mcp.CallToolResult{}, result, nil
+			if result == nil {
+				// 如果 result 为 nil，返回一个空的响应对象
+				result = &tmdb.DiscoverMoviesResponse{
+					Page:         1,
+					Results:      []tmdb.DiscoverMovieResult{},
+					TotalPages:   0,
+					TotalResults: 0,
+				}
 			}
-			// 如果 result 为 nil，返回一个空的响应对象
-			return &mcp.CallToolResult{}, &tmdb.DiscoverMoviesResponse{
-				Page:         1,
-				Results:      []tmdb.DiscoverMovieResult{},
-				TotalPages:   0,
-				TotalResults: 0,
-			}, nil
+		}
+
+		// 应用字段投影和响应预算
+		shaped, err := shapeResponse(result, params.ResponseParams, t.responseCfg)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		// 返回空的 CallToolResult 和结构化响应
-		return &mcp.CallToolResult{}, result, nil
+		return &mcp.CallToolResult{}, shaped, nil
 	}
 }
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 
+	"github.com/XDwanj/tmdb-mcp/internal/config"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
@@ -10,15 +11,17 @@ import (
 
 // DiscoverTVTool implements the MCP discover_tv tool
 type DiscoverTVTool struct {
-	tmdbClient *tmdb.Client
-	logger     *zap.Logger
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	logger      *zap.Logger
 }
 
 // NewDiscoverTVTool creates a new DiscoverTVTool instance
-func NewDiscoverTVTool(tmdbClient *tmdb.Client, logger *zap.Logger) *DiscoverTVTool {
+func NewDiscoverTVTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, logger *zap.Logger) *DiscoverTVTool {
 	return &DiscoverTVTool{
-		tmdbClient: tmdbClient,
-		logger:     logger,
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		logger:      logger,
 	}
 }
 
@@ -27,6 +30,11 @@ func (t *DiscoverTVTool) Name() string {
 	return "discover_tv"
 }
 
+// Title returns the human-readable tool title
+func (t *DiscoverTVTool) Title() string {
+	return "Discover TV Shows"
+}
+
 // Description returns the tool description
 func (t *DiscoverTVTool) Description() string {
 	return "Discover TV shows using filters like genre, year, rating, and status. " +
@@ -80,19 +88,24 @@ func (t *DiscoverTVTool) Handler() func(context.Context, *mcp.CallToolRequest, D
 		if result == nil || len(result.Results) == 0 {
 			t.logger.Info("No TV shows found matching criteria")
 			// 返回完整的响应对象，而不是空数组
-			if result != nil {
-				return &mcp.CallToolResult{}, result, nil
+			if result == nil {
+				// 如果 result 为 nil，返回一个空的响应对象
+				result = &tmdb.DiscoverTVResponse{
+					Page:         1,
+					Results:      []tmdb.DiscoverTVResult{},
+					TotalPages:   0,
+					TotalResults: 0,
+				}
 			}
-			// 如果 result 为 nil，返回一个空的响应对象
-			return &mcp.CallToolResult{}, &tmdb.DiscoverTVResponse{
-				Page:         1,
-				Results:      []tmdb.DiscoverTVResult{},
-				TotalPages:   0,
-				TotalResults: 0,
-			}, nil
+		}
+
+		// 应用字段投影和响应预算
+		shaped, err := shapeResponse(result, params.ResponseParams, t.responseCfg)
+		if err != nil {
+			return nil, nil, err
 		}
 
 		// 返回空的 CallToolResult 和结构化响应
-		return &mcp.CallToolResult{}, result, nil
+		return &mcp.CallToolResult{}, shaped, nil
 	}
 }
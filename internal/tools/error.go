@@ -13,6 +13,10 @@ func convertTMDBError(err error, resourceType string) error {
 		return nil
 	}
 
+	if errors.Is(err, tmdb.ErrCircuitOpen) {
+		return fmt.Errorf("TMDB service is temporarily unavailable and no cached data is available. Please try again later")
+	}
+
 	var tmdbErr *tmdb.TMDBError
 	if errors.As(err, &tmdbErr) {
 		switch tmdbErr.ErrorType {
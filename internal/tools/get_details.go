@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/XDwanj/tmdb-mcp/internal/config"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
@@ -11,15 +12,17 @@ import (
 
 // GetDetailsTool implements the MCP get_details tool
 type GetDetailsTool struct {
-	tmdbClient *tmdb.Client
-	logger     *zap.Logger
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	logger      *zap.Logger
 }
 
 // NewGetDetailsTool creates a new GetDetailsTool instance
-func NewGetDetailsTool(tmdbClient *tmdb.Client, logger *zap.Logger) *GetDetailsTool {
+func NewGetDetailsTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, logger *zap.Logger) *GetDetailsTool {
 	return &GetDetailsTool{
-		tmdbClient: tmdbClient,
-		logger:     logger,
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		logger:      logger,
 	}
 }
 
@@ -28,9 +31,18 @@ func (t *GetDetailsTool) Name() string {
 	return "get_details"
 }
 
+// Title returns the human-readable tool title
+func (t *GetDetailsTool) Title() string {
+	return "Get Details"
+}
+
 // Description returns the tool description
 func (t *GetDetailsTool) Description() string {
-	return "Get detailed information about a movie, TV show, or person using their TMDB ID"
+	return `Get detailed information about a movie, TV show, or person using their TMDB ID.
+
+If the ID is unknown, pass the title as query instead (optionally with media_type and year).
+When several entries match (remakes, same-name shows, namesake actors), the user is asked to pick one;
+if the client cannot ask, the ranked candidates are returned so you can ask the user and call again with the chosen id.`
 }
 
 // Handler returns a handler function compatible with mcp.AddTool
@@ -38,65 +50,101 @@ func (t *GetDetailsTool) Description() string {
 // business logic encapsulated in the GetDetailsTool struct
 func (t *GetDetailsTool) Handler() func(context.Context, *mcp.CallToolRequest, GetDetailsParams) (*mcp.CallToolResult, any, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, params GetDetailsParams) (*mcp.CallToolResult, any, error) {
-		// 验证 media_type 参数
-		validMediaTypes := map[string]bool{
-			"movie":  true,
-			"tv":     true,
-			"person": true,
-		}
-		if !validMediaTypes[params.MediaType] {
-			return nil, nil, fmt.Errorf("invalid media_type: must be 'movie', 'tv', or 'person'")
-		}
-
-		// 根据 media_type 调用相应的 TMDB Client 方法
-		switch params.MediaType {
-		case "movie":
-			movieDetails, err := t.tmdbClient.GetMovieDetails(ctx, params.ID, params.Language)
+		var details any
+		if params.Query != nil && *params.Query != "" {
+			// 按标题查询：存在多个候选时请用户选择，而不是猜测
+			resolved, ambiguous, err := resolveByTitle(ctx, req, t.tmdbClient, t.logger, *params.Query, params.MediaType, params.Year, params.Language)
 			if err != nil {
-				return nil, nil, convertTMDBError(err, "movie")
-			}
-			// 检查资源是否存在（404 情况）
-			if movieDetails == nil {
-				t.logger.Warn("Resource not found",
-					zap.String("media_type", params.MediaType),
-					zap.Int("id", params.ID),
-				)
-				return nil, nil, fmt.Errorf("the requested movie was not found")
+				return nil, nil, err
 			}
-			return &mcp.CallToolResult{}, movieDetails, nil
-
-		case "tv":
-			tvDetails, err := t.tmdbClient.GetTVDetails(ctx, params.ID, params.Language)
-			if err != nil {
-				return nil, nil, convertTMDBError(err, "TV show")
+			if ambiguous != nil {
+				return &mcp.CallToolResult{}, ambiguous, nil
 			}
-			// 检查资源是否存在（404 情况）
-			if tvDetails == nil {
-				t.logger.Warn("Resource not found",
-					zap.String("media_type", params.MediaType),
-					zap.Int("id", params.ID),
-				)
-				return nil, nil, fmt.Errorf("the requested TV show was not found")
+			details = resolved.details
+			if details == nil {
+				details, err = fetchDetails(ctx, t.tmdbClient, t.logger, resolved.MediaType, resolved.ID, params.Language)
+				if err != nil {
+					return nil, nil, err
+				}
 			}
-			return &mcp.CallToolResult{}, tvDetails, nil
-
-		case "person":
-			personDetails, err := t.tmdbClient.GetPersonDetails(ctx, params.ID, params.Language)
+		} else {
+			var err error
+			details, err = fetchDetails(ctx, t.tmdbClient, t.logger, params.MediaType, params.ID, params.Language)
 			if err != nil {
-				return nil, nil, convertTMDBError(err, "person")
-			}
-			// 检查资源是否存在（404 情况）
-			if personDetails == nil {
-				t.logger.Warn("Resource not found",
-					zap.String("media_type", params.MediaType),
-					zap.Int("id", params.ID),
-				)
-				return nil, nil, fmt.Errorf("the requested person was not found")
+				return nil, nil, err
 			}
-			return &mcp.CallToolResult{}, personDetails, nil
 		}
 
-		// 不应该到达这里（已经验证了 media_type）
-		return nil, nil, fmt.Errorf("invalid media_type: %s", params.MediaType)
+		// 应用字段投影和响应预算（例如限制 cast 列表长度、截断 overview）
+		shaped, err := shapeResponse(details, params.ResponseParams, t.responseCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &mcp.CallToolResult{}, shaped, nil
 	}
 }
+
+// fetchDetails fetches details for a single movie, TV show, or person
+// Errors are converted to user-friendly messages; 404 is reported as an error
+func fetchDetails(ctx context.Context, tmdbClient *tmdb.Client, logger *zap.Logger, mediaType string, id int, language *string) (any, error) {
+	// 验证 media_type 参数
+	validMediaTypes := map[string]bool{
+		"movie":  true,
+		"tv":     true,
+		"person": true,
+	}
+	if !validMediaTypes[mediaType] {
+		return nil, fmt.Errorf("invalid media_type: must be 'movie', 'tv', or 'person'")
+	}
+
+	// 根据 media_type 调用相应的 TMDB Client 方法
+	switch mediaType {
+	case "movie":
+		movieDetails, err := tmdbClient.GetMovieDetails(ctx, id, language)
+		if err != nil {
+			return nil, convertTMDBError(err, "movie")
+		}
+		// 检查资源是否存在（404 情况）
+		if movieDetails == nil {
+			logger.Warn("Resource not found",
+				zap.String("media_type", mediaType),
+				zap.Int("id", id),
+			)
+			return nil, fmt.Errorf("the requested movie was not found")
+		}
+		return movieDetails, nil
+
+	case "tv":
+		tvDetails, err := tmdbClient.GetTVDetails(ctx, id, language)
+		if err != nil {
+			return nil, convertTMDBError(err, "TV show")
+		}
+		// 检查资源是否存在（404 情况）
+		if tvDetails == nil {
+			logger.Warn("Resource not found",
+				zap.String("media_type", mediaType),
+				zap.Int("id", id),
+			)
+			return nil, fmt.Errorf("the requested TV show was not found")
+		}
+		return tvDetails, nil
+
+	case "person":
+		personDetails, err := tmdbClient.GetPersonDetails(ctx, id, language)
+		if err != nil {
+			return nil, convertTMDBError(err, "person")
+		}
+		// 检查资源是否存在（404 情况）
+		if personDetails == nil {
+			logger.Warn("Resource not found",
+				zap.String("media_type", mediaType),
+				zap.Int("id", id),
+			)
+			return nil, fmt.Errorf("the requested person was not found")
+		}
+		return personDetails, nil
+	}
+
+	// 不应该到达这里（已经验证了 media_type）
+	return nil, fmt.Errorf("invalid media_type: %s", mediaType)
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/progress"
+	"github.com/XDwanj/tmdb-mcp/internal/ratelimit"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// GetDetailsBatchTool implements the MCP get_details_batch tool
+type GetDetailsBatchTool struct {
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	batchCfg    config.BatchConfig
+	logger      *zap.Logger
+}
+
+// NewGetDetailsBatchTool creates a new GetDetailsBatchTool instance
+func NewGetDetailsBatchTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, batchCfg config.BatchConfig, logger *zap.Logger) *GetDetailsBatchTool {
+	return &GetDetailsBatchTool{
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		batchCfg:    batchCfg,
+		logger:      logger,
+	}
+}
+
+// Name returns the tool name
+func (t *GetDetailsBatchTool) Name() string {
+	return "get_details_batch"
+}
+
+// Title returns the human-readable tool title
+func (t *GetDetailsBatchTool) Title() string {
+	return "Get Details (Batch)"
+}
+
+// Description returns the tool description
+func (t *GetDetailsBatchTool) Description() string {
+	return fmt.Sprintf(`Get detailed information about up to %d movies, TV shows, or people in a single call.
+
+Items are fetched concurrently. Each item reports its own details or error, so one missing ID does not fail the whole call.
+
+Example:
+- Enrich a recommendation list: items=[{media_type: movie, id: 27205}, {media_type: tv, id: 1396}]
+
+Parameters:
+- items: List of {media_type, id} pairs (media_type: movie/tv/person)
+- language: ISO 639-1 language code (optional, uses config default if not specified)
+- fields / max_results / max_chars: Response shaping applied to each item's details (optional)`, t.batchCfg.MaxItems)
+}
+
+// Handler returns a handler function compatible with mcp.AddTool
+// This allows the tool to be registered with the MCP server while keeping
+// business logic encapsulated in the GetDetailsBatchTool struct
+func (t *GetDetailsBatchTool) Handler() func(context.Context, *mcp.CallToolRequest, GetDetailsBatchParams) (*mcp.CallToolResult, GetDetailsBatchResponse, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params GetDetailsBatchParams) (*mcp.CallToolResult, GetDetailsBatchResponse, error) {
+		// 验证条目数量
+		if len(params.Items) == 0 {
+			return nil, GetDetailsBatchResponse{}, fmt.Errorf("items parameter is required and must not be empty")
+		}
+		if len(params.Items) > t.batchCfg.MaxItems {
+			return nil, GetDetailsBatchResponse{}, fmt.Errorf("too many items: maximum is %d, got %d", t.batchCfg.MaxItems, len(params.Items))
+		}
+
+		total := len(params.Items)
+		results := make([]BatchItemResult, total)
+
+		// 进度以条目为单位；限流等待和重试会在条目之间报告
+		tracker := progress.FromContext(ctx)
+		tracker.SetTotal(total)
+
+		// 使用信号量限制并发数；所有请求共享 tmdb.Client 中的 rate limiter
+		// 批量条目以后台优先级排队，不阻塞其他会话和交互式调用
+		itemCtx := ratelimit.WithPriority(ctx, ratelimit.PriorityBackground)
+		sem := make(chan struct{}, t.batchCfg.Concurrency)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		completed := 0
+
+		for i, item := range params.Items {
+			wg.Add(1)
+			go func(i int, item BatchItem) {
+				defer wg.Done()
+
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+					results[i] = t.fetchItem(itemCtx, item, params)
+				case <-ctx.Done():
+					results[i] = BatchItemResult{MediaType: item.MediaType, ID: item.ID, Error: ctx.Err().Error()}
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				completed++
+				tracker.Advance(ctx, fmt.Sprintf("Fetched %d of %d items", completed, total))
+			}(i, item)
+		}
+		wg.Wait()
+
+		response := GetDetailsBatchResponse{Results: results}
+		for _, result := range results {
+			if result.Error != "" {
+				response.Failed++
+			} else {
+				response.Succeeded++
+			}
+		}
+
+		t.logger.Info("Batch details fetched",
+			zap.Int("total", total),
+			zap.Int("succeeded", response.Succeeded),
+			zap.Int("failed", response.Failed),
+		)
+
+		return &mcp.CallToolResult{}, response, nil
+	}
+}
+
+// fetchItem fetches and shapes a single batch item, capturing errors per item
+func (t *GetDetailsBatchTool) fetchItem(ctx context.Context, item BatchItem, params GetDetailsBatchParams) BatchItemResult {
+	result := BatchItemResult{MediaType: item.MediaType, ID: item.ID}
+
+	details, err := fetchDetails(ctx, t.tmdbClient, t.logger, item.MediaType, item.ID, params.Language)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	shaped, err := shapeResponse(details, params.ResponseParams, t.responseCfg)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Details = shaped
+	return result
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+)
+
+// newTestBatchTool creates a GetDetailsBatchTool with a client that is never reached
+func newTestBatchTool(maxItems int) *GetDetailsBatchTool {
+	logger := zap.NewNop()
+	client := tmdb.NewClient(config.TMDBConfig{APIKey: "test-api-key", Language: "en-US", RateLimit: 40}, logger)
+	return NewGetDetailsBatchTool(client, config.ResponseConfig{}, config.BatchConfig{MaxItems: maxItems, Concurrency: 2}, logger)
+}
+
+// TestGetDetailsBatch_Validation tests item count validation
+func TestGetDetailsBatch_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		items  []BatchItem
+		errMsg string
+	}{
+		{
+			name:   "empty items",
+			items:  nil,
+			errMsg: "must not be empty",
+		},
+		{
+			name:   "too many items",
+			items:  []BatchItem{{"movie", 1}, {"movie", 2}, {"movie", 3}},
+			errMsg: "too many items: maximum is 2, got 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestBatchTool(2).Handler()
+
+			_, _, err := handler(context.Background(), nil, GetDetailsBatchParams{Items: tt.items})
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+// TestGetDetailsBatch_PerItemErrors tests that item failures are reported per item
+func TestGetDetailsBatch_PerItemErrors(t *testing.T) {
+	handler := newTestBatchTool(10).Handler()
+
+	_, response, err := handler(context.Background(), nil, GetDetailsBatchParams{
+		Items: []BatchItem{
+			{MediaType: "book", ID: 1},
+			{MediaType: "movie", ID: -1},
+		},
+	})
+	require.NoError(t, err, "item failures should not fail the whole call")
+
+	assert.Equal(t, 0, response.Succeeded)
+	assert.Equal(t, 2, response.Failed)
+	require.Len(t, response.Results, 2)
+
+	// 结果保持请求顺序
+	assert.Equal(t, "book", response.Results[0].MediaType)
+	assert.Contains(t, response.Results[0].Error, "invalid media_type")
+	assert.Equal(t, -1, response.Results[1].ID)
+	assert.Contains(t, response.Results[1].Error, "invalid movie ID")
+}
@@ -4,22 +4,26 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/XDwanj/tmdb-mcp/internal/config"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 )
 
 // GetRecommendationsTool implements the MCP get_recommendations tool
 type GetRecommendationsTool struct {
-	tmdbClient *tmdb.Client
-	logger     *zap.Logger
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	logger      *zap.Logger
 }
 
 // NewGetRecommendationsTool creates a new GetRecommendationsTool instance
-func NewGetRecommendationsTool(tmdbClient *tmdb.Client, logger *zap.Logger) *GetRecommendationsTool {
+func NewGetRecommendationsTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, logger *zap.Logger) *GetRecommendationsTool {
 	return &GetRecommendationsTool{
-		tmdbClient: tmdbClient,
-		logger:     logger,
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		logger:      logger,
 	}
 }
 
@@ -28,6 +32,11 @@ func (t *GetRecommendationsTool) Name() string {
 	return "get_recommendations"
 }
 
+// Title returns the human-readable tool title
+func (t *GetRecommendationsTool) Title() string {
+	return "Get Recommendations"
+}
+
 // Description returns the tool description
 func (t *GetRecommendationsTool) Description() string {
 	return `Get movie or TV show recommendations based on a specific title you like.
@@ -40,7 +49,15 @@ Parameters:
 - media_type: Type of media to get recommendations for (movie/tv)
 - id: TMDB ID of the movie or TV show
 - page: Page number (optional, default: 1)
-- language: ISO 639-1 language code (optional, uses config default if not specified)`
+- language: ISO 639-1 language code (optional, uses config default if not specified)
+- fields: Comma-separated fields to return, e.g. id,title,vote_average (optional)
+- max_results / max_chars: Cap list sizes and overview length (optional, uses server default if not specified)`
+}
+
+// OutputSchema returns the tool output schema; fields of results are optional
+// because the fields parameter may leave them out
+func (t *GetRecommendationsTool) OutputSchema() *jsonschema.Schema {
+	return shapedOutputSchema[GetRecommendationsResponse]()
 }
 
 // Handler returns a handler function compatible with mcp.AddTool
@@ -71,7 +88,13 @@ func (t *GetRecommendationsTool) Handler() func(context.Context, *mcp.CallToolRe
 			return nil, GetRecommendationsResponse{}, convertTMDBError(err, "content")
 		}
 
+		// Apply field projection and response budget
+		shaped, err := shapeTypedResponse(GetRecommendationsResponse{Results: results.Results}, params.ResponseParams, t.responseCfg)
+		if err != nil {
+			return nil, GetRecommendationsResponse{}, err
+		}
+
 		// Return empty result metadata and structured response
-		return &mcp.CallToolResult{}, GetRecommendationsResponse{Results: results.Results}, nil
+		return &mcp.CallToolResult{}, shaped, nil
 	}
 }
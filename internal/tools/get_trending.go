@@ -3,22 +3,26 @@ package tools
 import (
 	"context"
 
+	"github.com/XDwanj/tmdb-mcp/internal/config"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 )
 
 // GetTrendingTool implements the MCP get_trending tool
 type GetTrendingTool struct {
-	tmdbClient *tmdb.Client
-	logger     *zap.Logger
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	logger      *zap.Logger
 }
 
 // NewGetTrendingTool creates a new GetTrendingTool instance
-func NewGetTrendingTool(tmdbClient *tmdb.Client, logger *zap.Logger) *GetTrendingTool {
+func NewGetTrendingTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, logger *zap.Logger) *GetTrendingTool {
 	return &GetTrendingTool{
-		tmdbClient: tmdbClient,
-		logger:     logger,
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		logger:      logger,
 	}
 }
 
@@ -27,6 +31,11 @@ func (t *GetTrendingTool) Name() string {
 	return "get_trending"
 }
 
+// Title returns the human-readable tool title
+func (t *GetTrendingTool) Title() string {
+	return "Get Trending"
+}
+
 // Description returns the tool description
 func (t *GetTrendingTool) Description() string {
 	return `Get trending movies, TV shows, or people for a specific time window (day or week).
@@ -40,7 +49,15 @@ Parameters:
 - media_type: Type of media (movie/tv/person)
 - time_window: Time window for trending items (day/week)
 - page: Page number (optional, default: 1)
-- language: ISO 639-1 language code (optional, uses config default if not specified)`
+- language: ISO 639-1 language code (optional, uses config default if not specified)
+- fields: Comma-separated fields to return, e.g. id,title,vote_average (optional)
+- max_results / max_chars: Cap list sizes and overview length (optional, uses server default if not specified)`
+}
+
+// OutputSchema returns the tool output schema; fields of results are optional
+// because the fields parameter may leave them out
+func (t *GetTrendingTool) OutputSchema() *jsonschema.Schema {
+	return shapedOutputSchema[GetTrendingResponse]()
 }
 
 // Handler returns a handler function compatible with mcp.AddTool
@@ -60,7 +77,13 @@ func (t *GetTrendingTool) Handler() func(context.Context, *mcp.CallToolRequest,
 			return nil, GetTrendingResponse{}, convertTMDBError(err, "content")
 		}
 
+		// Apply field projection and response budget
+		shaped, err := shapeTypedResponse(GetTrendingResponse{Results: results.Results}, params.ResponseParams, t.responseCfg)
+		if err != nil {
+			return nil, GetTrendingResponse{}, err
+		}
+
 		// Return empty result metadata and structured response
-		return &mcp.CallToolResult{}, GetTrendingResponse{Results: results.Results}, nil
+		return &mcp.CallToolResult{}, shaped, nil
 	}
 }
@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxRunnersUp is the maximum number of runners-up returned by the lookup tool
+	maxRunnersUp = 3
+
+	// 评分权重：标题相似度为主，年份和热度用于区分同名条目
+	titleWeight      = 0.6
+	yearWeight       = 0.2
+	popularityWeight = 0.2
+
+	// yearTolerance is the year difference at which the year score drops to 0
+	yearTolerance = 5
+
+	// confidenceMargin is the score gap to the runner-up at which confidence equals the score
+	confidenceMargin = 0.2
+)
+
+// LookupTool implements the MCP lookup tool
+type LookupTool struct {
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	logger      *zap.Logger
+}
+
+// NewLookupTool creates a new LookupTool instance
+func NewLookupTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, logger *zap.Logger) *LookupTool {
+	return &LookupTool{
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		logger:      logger,
+	}
+}
+
+// Name returns the tool name
+func (t *LookupTool) Name() string {
+	return "lookup"
+}
+
+// Title returns the human-readable tool title
+func (t *LookupTool) Title() string {
+	return "Look Up by Title"
+}
+
+// Description returns the tool description
+func (t *LookupTool) Description() string {
+	return `Resolve a free-text title to full details in one call (instead of search followed by get_details).
+
+Candidates are scored by fuzzy title similarity (including original titles such as Chinese or Japanese names),
+year proximity and popularity. Returns details of the best match, a confidence score (0-1) and runners-up.
+If confidence is low, check the runners-up or ask the user which entry they meant.
+
+Examples:
+- lookup(title="Inception")
+- lookup(title="The Office", media_type="tv", year=2005)
+- lookup(title="千与千寻")`
+}
+
+// Handler returns a handler function compatible with mcp.AddTool
+// This allows the tool to be registered with the MCP server while keeping
+// business logic encapsulated in the LookupTool struct
+func (t *LookupTool) Handler() func(context.Context, *mcp.CallToolRequest, LookupParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params LookupParams) (*mcp.CallToolResult, any, error) {
+		if params.Title == "" {
+			return nil, nil, fmt.Errorf("title parameter is required")
+		}
+
+		mediaType := ""
+		if params.MediaType != nil {
+			mediaType = *params.MediaType
+		}
+		if mediaType != "" && mediaType != "movie" && mediaType != "tv" && mediaType != "person" {
+			return nil, nil, fmt.Errorf("invalid media_type: must be 'movie', 'tv', or 'person'")
+		}
+
+		searchResp, err := t.tmdbClient.Search(ctx, params.Title, 1, params.Language)
+		if err != nil {
+			return nil, nil, convertTMDBError(err, "search results")
+		}
+
+		var results []tmdb.SearchResult
+		for _, result := range searchResp.Results {
+			if mediaType == "" || result.MediaType == mediaType {
+				results = append(results, result)
+			}
+		}
+		matches := scoreMatches(params.Title, params.Year, results)
+		if len(matches) == 0 {
+			return nil, nil, fmt.Errorf("no matches found for %q", params.Title)
+		}
+
+		best := matches[0]
+		details, err := fetchDetails(ctx, t.tmdbClient, t.logger, best.MediaType, best.ID, params.Language)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// 响应裁剪仅作用于最佳匹配的详情
+		shaped, err := shapeResponse(details, params.ResponseParams, t.responseCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		runnersUp := matches[1:]
+		if len(runnersUp) > maxRunnersUp {
+			runnersUp = runnersUp[:maxRunnersUp]
+		}
+		response := LookupResponse{
+			Match:      best,
+			Confidence: matchConfidence(matches),
+			Details:    shaped,
+			RunnersUp:  runnersUp,
+		}
+
+		t.logger.Info("Lookup resolved",
+			zap.String("title", params.Title),
+			zap.String("media_type", best.MediaType),
+			zap.Int("id", best.ID),
+			zap.Float64("score", best.Score),
+			zap.Float64("confidence", response.Confidence),
+			zap.Int("candidates", len(matches)),
+		)
+
+		return &mcp.CallToolResult{}, response, nil
+	}
+}
+
+// scoreMatches scores search results against the query and sorts them by score (highest first)
+// Ties keep the TMDB relevance order
+func scoreMatches(query string, year *int, results []tmdb.SearchResult) []LookupMatch {
+	maxPopularity := 0.0
+	for _, result := range results {
+		maxPopularity = math.Max(maxPopularity, result.Popularity)
+	}
+
+	normalizedQuery := normalizeTitle(query)
+	matches := make([]LookupMatch, 0, len(results))
+	for _, result := range results {
+		candidate := newCandidate(result)
+
+		titleScore := titleSimilarity(normalizedQuery, normalizeTitle(candidate.Title))
+		if candidate.Original != "" {
+			titleScore = math.Max(titleScore, titleSimilarity(normalizedQuery, normalizeTitle(candidate.Original)))
+		}
+
+		popularityScore := 0.0
+		if maxPopularity > 0 {
+			// 对数缩放，避免热门条目压倒标题相似度
+			popularityScore = math.Log1p(result.Popularity) / math.Log1p(maxPopularity)
+		}
+
+		var score float64
+		if year != nil && candidate.MediaType != "person" {
+			score = titleWeight*titleScore + yearWeight*yearScore(*year, candidate.Year) + popularityWeight*popularityScore
+		} else {
+			// 未指定年份（或人物）时，年份权重分配给标题相似度
+			score = (titleWeight+yearWeight)*titleScore + popularityWeight*popularityScore
+		}
+
+		matches = append(matches, LookupMatch{
+			MediaType:     candidate.MediaType,
+			ID:            candidate.ID,
+			Title:         candidate.Title,
+			OriginalTitle: candidate.Original,
+			Year:          candidate.Year,
+			Popularity:    result.Popularity,
+			Score:         math.Round(score*1000) / 1000,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// matchConfidence derives the confidence in the best match from its score and
+// its margin over the runner-up: a close runner-up halves the confidence
+func matchConfidence(matches []LookupMatch) float64 {
+	if len(matches) == 0 {
+		return 0
+	}
+	best := matches[0].Score
+	if len(matches) == 1 {
+		return best
+	}
+	margin := math.Min(1, (best-matches[1].Score)/confidenceMargin)
+	return math.Round(best*(0.5+0.5*margin)*1000) / 1000
+}
+
+// yearScore scores how close a candidate year is to the requested year
+// Unknown years score 0
+func yearScore(want int, got string) float64 {
+	year, err := strconv.Atoi(got)
+	if err != nil {
+		return 0
+	}
+	diff := math.Abs(float64(want - year))
+	return math.Max(0, 1-diff/yearTolerance)
+}
+
+// titleSimilarity returns a similarity between 0 and 1 based on the Levenshtein
+// distance of two normalized titles; it works on runes so CJK titles compare per character
+func titleSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein computes the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+)
+
+// TestTitleSimilarity tests fuzzy title similarity, including CJK titles
+func TestTitleSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, titleSimilarity("inception", "inception"))
+	assert.InDelta(t, 0.778, titleSimilarity("inceptoin", "inception"), 0.001)
+	assert.Equal(t, 1.0, titleSimilarity(normalizeTitle("千与千寻"), normalizeTitle("千与千寻")))
+	assert.InDelta(t, 0.75, titleSimilarity("千与千寻", "千与千尋"), 0.001)
+	assert.Equal(t, 0.0, titleSimilarity("", ""))
+}
+
+// TestLevenshtein tests edit distance calculation
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein([]rune("abc"), []rune("abc")))
+	assert.Equal(t, 3, levenshtein([]rune("kitten"), []rune("sitting")))
+	assert.Equal(t, 4, levenshtein([]rune(""), []rune("dune")))
+}
+
+// TestYearScore tests year proximity scoring
+func TestYearScore(t *testing.T) {
+	assert.Equal(t, 1.0, yearScore(2005, "2005"))
+	assert.InDelta(t, 0.8, yearScore(2005, "2004"), 0.001)
+	assert.Equal(t, 0.0, yearScore(2005, "1990"))
+	assert.Equal(t, 0.0, yearScore(2005, ""))
+}
+
+// TestScoreMatches_YearBreaksTie tests that the requested year selects between same-name shows
+func TestScoreMatches_YearBreaksTie(t *testing.T) {
+	results := []tmdb.SearchResult{
+		{ID: 2316, MediaType: "tv", Name: "The Office", FirstAirDate: "2005-03-24", Popularity: 300},
+		{ID: 2996, MediaType: "tv", Name: "The Office", FirstAirDate: "2001-07-09", Popularity: 40},
+	}
+	year := 2001
+
+	matches := scoreMatches("The Office", &year, results)
+
+	require.Len(t, matches, 2)
+	assert.Equal(t, 2996, matches[0].ID)
+	assert.Greater(t, matches[0].Score, matches[1].Score)
+}
+
+// TestScoreMatches_OriginalTitle tests matching on original (CJK) titles
+func TestScoreMatches_OriginalTitle(t *testing.T) {
+	results := []tmdb.SearchResult{
+		{ID: 1, MediaType: "movie", Title: "Spirited Away Documentary", ReleaseDate: "2002-01-01", Popularity: 5},
+		{ID: 129, MediaType: "movie", Title: "Spirited Away", OriginalTitle: "千と千尋の神隠し", ReleaseDate: "2001-07-20", Popularity: 100},
+	}
+
+	matches := scoreMatches("千と千尋の神隠し", nil, results)
+
+	require.Len(t, matches, 2)
+	assert.Equal(t, 129, matches[0].ID)
+	assert.Equal(t, "千と千尋の神隠し", matches[0].OriginalTitle)
+}
+
+// TestScoreMatches_PopularityPrefersWellKnown tests popularity as a tie breaker without a year
+func TestScoreMatches_PopularityPrefersWellKnown(t *testing.T) {
+	results := []tmdb.SearchResult{
+		{ID: 1, MediaType: "movie", Title: "Dune", ReleaseDate: "1984-12-14", Popularity: 20},
+		{ID: 438631, MediaType: "movie", Title: "Dune", ReleaseDate: "2021-09-15", Popularity: 200},
+	}
+
+	matches := scoreMatches("Dune", nil, results)
+
+	assert.Equal(t, 438631, matches[0].ID)
+}
+
+// TestMatchConfidence tests confidence from score and margin
+func TestMatchConfidence(t *testing.T) {
+	assert.Equal(t, 0.0, matchConfidence(nil))
+	assert.Equal(t, 0.9, matchConfidence([]LookupMatch{{Score: 0.9}}))
+	assert.Equal(t, 0.9, matchConfidence([]LookupMatch{{Score: 0.9}, {Score: 0.5}}))
+	assert.Equal(t, 0.45, matchConfidence([]LookupMatch{{Score: 0.9}, {Score: 0.9}}))
+}
+
+// TestLookup_Validation tests parameter validation
+func TestLookup_Validation(t *testing.T) {
+	logger := zap.NewNop()
+	client := tmdb.NewClient(config.TMDBConfig{APIKey: "test-api-key", Language: "en-US", RateLimit: 40}, logger)
+	handler := NewLookupTool(client, config.ResponseConfig{}, logger).Handler()
+
+	_, _, err := handler(context.Background(), nil, LookupParams{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "title parameter is required")
+
+	film := "film"
+	_, _, err = handler(context.Background(), nil, LookupParams{Title: "Dune", MediaType: &film})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid media_type")
+}
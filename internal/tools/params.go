@@ -7,18 +7,84 @@ type SearchParams struct {
 	Query    string  `json:"query" jsonschema:"Search query for movies, TV shows, and people"`                                                  // 搜索关键词（必需）
 	Page     int     `json:"page" jsonschema:"Page number (default: 1)"`                                                                        // 页码（可选，默认 1）
 	Language *string `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选）
 }
 
 // SearchResponse represents the response from the search tool
 type SearchResponse struct {
 	Results []tmdb.SearchResult `json:"results" jsonschema:"List of search results"`
+
+	shaped any // 按 fields/预算裁剪后的通用结构；非 nil 时代替 Results 序列化
 }
 
 // GetDetailsParams represents the parameters for the get_details tool
 type GetDetailsParams struct {
-	MediaType string  `json:"media_type" jsonschema:"Media type (movie/tv/person)"`                                                              // 媒体类型（必需）
-	ID        int     `json:"id" jsonschema:"TMDB ID of the content"`                                                                            // TMDB ID（必需）
+	MediaType string  `json:"media_type,omitempty" jsonschema:"Media type (movie/tv/person). Required when looking up by id"`                    // 媒体类型（按 ID 查询时必需）
+	ID        int     `json:"id,omitempty" jsonschema:"TMDB ID of the content. Either id or query is required"`                                  // TMDB ID
+	Query     *string `json:"query,omitempty" jsonschema:"Title or name to look up when the TMDB ID is unknown (e.g., 'The Office')"`            // 按标题查询（可选）
+	Year      *int    `json:"year,omitempty" jsonschema:"Release or first air year to narrow a query lookup (optional)"`                         // 年份（可选）
+	Language  *string `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选）
+}
+
+// BatchItem identifies a single item requested through the get_details_batch tool
+type BatchItem struct {
+	MediaType string `json:"media_type" jsonschema:"Media type (movie/tv/person)"` // 媒体类型（必需）
+	ID        int    `json:"id" jsonschema:"TMDB ID of the content"`               // TMDB ID（必需）
+}
+
+// GetDetailsBatchParams represents the parameters for the get_details_batch tool
+type GetDetailsBatchParams struct {
+	Items    []BatchItem `json:"items" jsonschema:"List of {media_type, id} pairs to fetch"`                                                        // 待获取条目（必需）
+	Language *string     `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选，作用于每个条目的详情）
+}
+
+// BatchItemResult represents the outcome of a single item in get_details_batch
+type BatchItemResult struct {
+	MediaType string `json:"media_type" jsonschema:"Media type of the item"`
+	ID        int    `json:"id" jsonschema:"TMDB ID of the item"`
+	Details   any    `json:"details,omitempty" jsonschema:"Item details, present when the fetch succeeded"`
+	Error     string `json:"error,omitempty" jsonschema:"Error message, present when the fetch failed"`
+}
+
+// GetDetailsBatchResponse represents the response from the get_details_batch tool
+type GetDetailsBatchResponse struct {
+	Results   []BatchItemResult `json:"results" jsonschema:"Per-item results in request order"`
+	Succeeded int               `json:"succeeded" jsonschema:"Number of items fetched successfully"`
+	Failed    int               `json:"failed" jsonschema:"Number of items that failed"`
+}
+
+// LookupParams represents the parameters for the lookup tool
+type LookupParams struct {
+	Title     string  `json:"title" jsonschema:"Free-text title or name to resolve (e.g., 'Inception', '千与千寻')"`                                 // 标题（必需）
+	Year      *int    `json:"year,omitempty" jsonschema:"Release or first air year, used to prefer the closest match (optional)"`                // 年份（可选）
+	MediaType *string `json:"media_type,omitempty" jsonschema:"Restrict matches to a media type (movie/tv/person, optional)"`                    // 媒体类型（可选）
 	Language  *string `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选，作用于最佳匹配的详情）
+}
+
+// LookupMatch represents a scored candidate of the lookup tool
+type LookupMatch struct {
+	MediaType     string  `json:"media_type" jsonschema:"Media type of the match"`
+	ID            int     `json:"id" jsonschema:"TMDB ID of the match"`
+	Title         string  `json:"title" jsonschema:"Title or name"`
+	OriginalTitle string  `json:"original_title,omitempty" jsonschema:"Original title, when different from title"`
+	Year          string  `json:"year,omitempty" jsonschema:"Release or first air year"`
+	Popularity    float64 `json:"popularity" jsonschema:"TMDB popularity"`
+	Score         float64 `json:"score" jsonschema:"Match score between 0 and 1"`
+}
+
+// LookupResponse represents the response from the lookup tool
+type LookupResponse struct {
+	Match      LookupMatch   `json:"match" jsonschema:"Best match"`
+	Confidence float64       `json:"confidence" jsonschema:"Confidence in the best match between 0 and 1; low values mean the runners-up are close"`
+	Details    any           `json:"details" jsonschema:"Full details of the best match"`
+	RunnersUp  []LookupMatch `json:"runners_up,omitempty" jsonschema:"Next best matches, highest score first"`
 }
 
 // DiscoverMoviesParams represents the parameters for the discover_movies tool
@@ -31,6 +97,8 @@ type DiscoverMoviesParams struct {
 	SortBy               *string  `json:"sort_by,omitempty" jsonschema:"Sort results by (e.g., 'popularity.desc', 'vote_average.desc', 'release_date.desc')"`
 	Page                 *int     `json:"page,omitempty" jsonschema:"Page number (default: 1)"`
 	Language             *string  `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选）
 }
 
 // DiscoverTVParams represents the parameters for the discover_tv tool
@@ -44,6 +112,8 @@ type DiscoverTVParams struct {
 	SortBy               *string  `json:"sort_by,omitempty" jsonschema:"Sort results by (e.g., 'popularity.desc', 'vote_average.desc', 'first_air_date.desc')"`
 	Page                 *int     `json:"page,omitempty" jsonschema:"Page number (default: 1)"`
 	Language             *string  `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选）
 }
 
 // GetTrendingParams represents the parameters for the get_trending tool
@@ -52,11 +122,15 @@ type GetTrendingParams struct {
 	TimeWindow string  `json:"time_window" jsonschema:"Time window for trending items (day/week)"`                                                // 时间窗口（必需）
 	Page       *int    `json:"page,omitempty" jsonschema:"Page number (default: 1)"`                                                              // 页码（可选，默认 1）
 	Language   *string `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选）
 }
 
 // GetTrendingResponse represents the response from the get_trending tool
 type GetTrendingResponse struct {
 	Results []tmdb.TrendingResult `json:"results" jsonschema:"List of trending items"`
+
+	shaped any // 按 fields/预算裁剪后的通用结构；非 nil 时代替 Results 序列化
 }
 
 // GetRecommendationsParams represents the parameters for the get_recommendations tool
@@ -65,9 +139,13 @@ type GetRecommendationsParams struct {
 	ID        int     `json:"id" jsonschema:"TMDB ID of the movie or TV show"`                                                                   // TMDB ID（必需）
 	Page      *int    `json:"page,omitempty" jsonschema:"Page number (default: 1)"`                                                              // 页码（可选，默认 1）
 	Language  *string `json:"language,omitempty" jsonschema:"ISO 639-1 language code (e.g., 'en', 'zh'). If not specified, uses config default"` // 语言参数（可选）
+
+	ResponseParams // 响应裁剪参数（可选）
 }
 
 // GetRecommendationsResponse represents the response from the get_recommendations tool
 type GetRecommendationsResponse struct {
 	Results []tmdb.RecommendationResult `json:"results" jsonschema:"List of recommended movies or TV shows"`
+
+	shaped any // 按 fields/预算裁剪后的通用结构；非 nil 时代替 Results 序列化
 }
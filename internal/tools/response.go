@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// ResponseParams holds the response shaping controls shared by all tools
+type ResponseParams struct {
+	Fields     *string `json:"fields,omitempty" jsonschema:"Comma-separated list of fields to return (e.g., 'id,title,release_date,vote_average'). For list results the fields apply to each item. Use dot notation for nested fields (e.g., 'credits.cast.name')"`
+	MaxResults *int    `json:"max_results,omitempty" jsonschema:"Maximum number of items in each list (results, cast, crew, videos). 0 means unlimited. If not specified, uses server default"`
+	MaxChars   *int    `json:"max_chars,omitempty" jsonschema:"Maximum number of characters for long text fields (overview, biography). 0 means unlimited. If not specified, uses server default"`
+}
+
+// longTextFields lists the text fields truncated by max_chars
+var longTextFields = map[string]bool{
+	"overview":  true,
+	"biography": true,
+}
+
+// truncationSuffix is appended to truncated text fields
+const truncationSuffix = "…"
+
+// shapeResponse applies field projection and the response budget to a tool response
+// 请求参数优先于服务器默认值；未做任何裁剪时原样返回
+func shapeResponse(v any, params ResponseParams, defaults config.ResponseConfig) (any, error) {
+	maxResults := defaults.MaxResults
+	if params.MaxResults != nil {
+		maxResults = *params.MaxResults
+	}
+	maxChars := defaults.MaxChars
+	if params.MaxChars != nil {
+		maxChars = *params.MaxChars
+	}
+
+	if maxResults < 0 {
+		return nil, fmt.Errorf("invalid max_results: must not be negative")
+	}
+	if maxChars < 0 {
+		return nil, fmt.Errorf("invalid max_chars: must not be negative")
+	}
+
+	var fields fieldTree
+	if params.Fields != nil {
+		fields = parseFields(*params.Fields)
+	}
+
+	if maxResults == 0 && maxChars == 0 && len(fields) == 0 {
+		return v, nil
+	}
+
+	// 转换为通用 JSON 结构，以便按字段名裁剪
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(fields) > 0 {
+		generic = projectFields(generic, fields)
+	}
+	return applyBudget(generic, "", maxResults, maxChars), nil
+}
+
+// shapedResponse is a typed tool response that can carry its shaped generic form
+type shapedResponse[T any] interface {
+	withShaped(shaped any) T
+}
+
+// shapeTypedResponse applies shapeResponse to a typed tool response
+// The response keeps its type, and with it the tool's output schema and typed
+// structured content; when shaping changed it, the shaped generic form is what
+// gets serialized, so selected fields keep their zero values and others are absent
+func shapeTypedResponse[T shapedResponse[T]](v T, params ResponseParams, defaults config.ResponseConfig) (T, error) {
+	shaped, err := shapeResponse(v, params, defaults)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if _, unchanged := shaped.(T); unchanged {
+		return v, nil
+	}
+	return v.withShaped(shaped), nil
+}
+
+// withShaped returns r serialized as shaped
+func (r SearchResponse) withShaped(shaped any) SearchResponse {
+	r.shaped = shaped
+	return r
+}
+
+// MarshalJSON encodes the shaped form of the response if any
+func (r SearchResponse) MarshalJSON() ([]byte, error) {
+	if r.shaped != nil {
+		return json.Marshal(r.shaped)
+	}
+	type plain SearchResponse
+	return json.Marshal(plain(r))
+}
+
+// withShaped returns r serialized as shaped
+func (r GetTrendingResponse) withShaped(shaped any) GetTrendingResponse {
+	r.shaped = shaped
+	return r
+}
+
+// MarshalJSON encodes the shaped form of the response if any
+func (r GetTrendingResponse) MarshalJSON() ([]byte, error) {
+	if r.shaped != nil {
+		return json.Marshal(r.shaped)
+	}
+	type plain GetTrendingResponse
+	return json.Marshal(plain(r))
+}
+
+// withShaped returns r serialized as shaped
+func (r GetRecommendationsResponse) withShaped(shaped any) GetRecommendationsResponse {
+	r.shaped = shaped
+	return r
+}
+
+// MarshalJSON encodes the shaped form of the response if any
+func (r GetRecommendationsResponse) MarshalJSON() ([]byte, error) {
+	if r.shaped != nil {
+		return json.Marshal(r.shaped)
+	}
+	type plain GetRecommendationsResponse
+	return json.Marshal(plain(r))
+}
+
+// shapedOutputSchema returns the output schema of the typed response T with the
+// fields of nested objects (e.g. list items) made optional, since the fields
+// parameter may leave any of them out
+func shapedOutputSchema[T any]() *jsonschema.Schema {
+	schema, err := jsonschema.For[T](&jsonschema.ForOptions{})
+	if err != nil {
+		panic(fmt.Sprintf("output schema of %T: %v", *new(T), err))
+	}
+	for _, prop := range schema.Properties {
+		optionalFields(prop)
+	}
+	return schema
+}
+
+// optionalFields clears the required fields of s and of the schemas nested in it
+func optionalFields(s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	s.Required = nil
+	optionalFields(s.Items)
+	for _, prop := range s.Properties {
+		optionalFields(prop)
+	}
+}
+
+// fieldTree is a parsed set of dotted field paths
+// A nil subtree selects the whole value
+type fieldTree map[string]fieldTree
+
+// parseFields parses a comma-separated list of dotted field paths
+func parseFields(raw string) fieldTree {
+	tree := fieldTree{}
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		node := tree
+		parts := strings.Split(field, ".")
+		for i, part := range parts {
+			sub, exists := node[part]
+			if exists && sub == nil {
+				// 已选择整个字段，忽略更细粒度的路径
+				break
+			}
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+			if sub == nil {
+				sub = fieldTree{}
+				node[part] = sub
+			}
+			node = sub
+		}
+	}
+	return tree
+}
+
+// apply keeps only the selected fields of v; lists are projected item by item
+func (t fieldTree) apply(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for key, sub := range t {
+			child, ok := val[key]
+			if !ok {
+				continue
+			}
+			if sub == nil {
+				out[key] = child
+			} else {
+				out[key] = sub.apply(child)
+			}
+		}
+		return out
+	case []any:
+		for i := range val {
+			val[i] = t.apply(val[i])
+		}
+		return val
+	}
+	return v
+}
+
+// projectFields applies the field selection to a response
+// For list responses the selection applies to each entry of "results" and
+// pagination fields (page, total_pages, total_results) are kept
+func projectFields(v any, fields fieldTree) any {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	if results, ok := obj["results"].([]any); ok {
+		obj["results"] = fields.apply(results)
+		return obj
+	}
+	return fields.apply(obj)
+}
+
+// applyBudget caps lists of objects to maxResults and truncates long text fields to maxChars
+func applyBudget(v any, key string, maxResults, maxChars int) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			val[k] = applyBudget(child, k, maxResults, maxChars)
+		}
+		return val
+	case []any:
+		if maxResults > 0 && len(val) > maxResults && isObjectList(val) {
+			val = val[:maxResults]
+		}
+		for i := range val {
+			val[i] = applyBudget(val[i], "", maxResults, maxChars)
+		}
+		return val
+	case string:
+		if maxChars > 0 && longTextFields[key] {
+			return truncateText(val, maxChars)
+		}
+	}
+	return v
+}
+
+// isObjectList reports whether a list holds entities (e.g. cast members) rather than scalars (e.g. genre_ids)
+func isObjectList(list []any) bool {
+	if len(list) == 0 {
+		return false
+	}
+	_, ok := list[0].(map[string]any)
+	return ok
+}
+
+// truncateText truncates text to at most maxChars characters (rune-aware)
+func truncateText(text string, maxChars int) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	return string(runes[:maxChars]) + truncationSuffix
+}
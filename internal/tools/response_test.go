@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+)
+
+// toJSONMap converts a shaped response to a generic map for assertions
+func toJSONMap(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(data, &m))
+	return m
+}
+
+// TestShapeResponse_NoLimits tests that responses are returned unchanged without limits
+func TestShapeResponse_NoLimits(t *testing.T) {
+	resp := SearchResponse{Results: []tmdb.SearchResult{{ID: 1, Title: "Inception"}}}
+
+	shaped, err := shapeResponse(resp, ResponseParams{}, config.ResponseConfig{})
+
+	require.NoError(t, err)
+	assert.Equal(t, resp, shaped)
+}
+
+// TestShapeTypedResponse tests that projected typed responses serialize only the selected
+// fields, zero values included
+func TestShapeTypedResponse(t *testing.T) {
+	resp := GetTrendingResponse{Results: []tmdb.TrendingResult{
+		{ID: 27205, MediaType: "movie", Title: "Inception", VoteAverage: 0, Overview: ""},
+	}}
+	fields := "id,title,vote_average,overview"
+
+	shaped, err := shapeTypedResponse(resp, ResponseParams{Fields: &fields}, config.ResponseConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, resp.Results, shaped.Results)
+
+	m := toJSONMap(t, shaped)
+	assert.Equal(t, map[string]any{"id": float64(27205), "title": "Inception", "vote_average": float64(0), "overview": ""}, m["results"].([]any)[0])
+
+	// 未选择 fields 时零值字段照常返回
+	m = toJSONMap(t, resp)
+	assert.Contains(t, m["results"].([]any)[0], "vote_average")
+
+	// 未裁剪时原样返回
+	unshaped, err := shapeTypedResponse(resp, ResponseParams{}, config.ResponseConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, resp, unshaped)
+}
+
+// TestShapeResponse_FieldsOnListResults tests field projection on list responses
+func TestShapeResponse_FieldsOnListResults(t *testing.T) {
+	resp := &tmdb.DiscoverMoviesResponse{
+		Page: 1,
+		Results: []tmdb.DiscoverMovieResult{
+			{ID: 27205, Title: "Inception", ReleaseDate: "2010-07-16", VoteAverage: 8.4, Overview: "Cobb..."},
+		},
+		TotalPages:   1,
+		TotalResults: 1,
+	}
+	fields := "id, title,vote_average"
+
+	shaped, err := shapeResponse(resp, ResponseParams{Fields: &fields}, config.ResponseConfig{})
+	require.NoError(t, err)
+
+	m := toJSONMap(t, shaped)
+	assert.Equal(t, float64(1), m["page"], "pagination fields should be kept")
+	results := m["results"].([]any)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]any{"id": float64(27205), "title": "Inception", "vote_average": 8.4}, results[0])
+}
+
+// TestShapeResponse_NestedFields tests dotted field paths on detail responses
+func TestShapeResponse_NestedFields(t *testing.T) {
+	resp := &tmdb.MovieDetails{
+		ID:    27205,
+		Title: "Inception",
+		Credits: tmdb.Credits{
+			Cast: []tmdb.CastMember{{ID: 6193, Name: "Leonardo DiCaprio", Character: "Cobb"}},
+			Crew: []tmdb.CrewMember{{ID: 525, Name: "Christopher Nolan", Job: "Director"}},
+		},
+	}
+	fields := "title,credits.cast.name"
+
+	shaped, err := shapeResponse(resp, ResponseParams{Fields: &fields}, config.ResponseConfig{})
+	require.NoError(t, err)
+
+	m := toJSONMap(t, shaped)
+	assert.Equal(t, map[string]any{
+		"title": "Inception",
+		"credits": map[string]any{
+			"cast": []any{map[string]any{"name": "Leonardo DiCaprio"}},
+		},
+	}, m)
+}
+
+// TestShapeResponse_Budget tests list capping and text truncation
+func TestShapeResponse_Budget(t *testing.T) {
+	resp := &tmdb.TVDetails{
+		ID:       1399,
+		Name:     "Game of Thrones",
+		Overview: strings.Repeat("长", 30),
+		Credits: tmdb.Credits{
+			Cast: []tmdb.CastMember{{ID: 1}, {ID: 2}, {ID: 3}},
+		},
+	}
+
+	shaped, err := shapeResponse(resp, ResponseParams{}, config.ResponseConfig{MaxResults: 2, MaxChars: 10})
+	require.NoError(t, err)
+
+	m := toJSONMap(t, shaped)
+	assert.Equal(t, strings.Repeat("长", 10)+truncationSuffix, m["overview"])
+	assert.Equal(t, "Game of Thrones", m["name"], "short non-text fields should be untouched")
+	cast := m["credits"].(map[string]any)["cast"].([]any)
+	assert.Len(t, cast, 2)
+}
+
+// TestShapeResponse_ParamsOverrideDefaults tests that per-call limits override server defaults
+func TestShapeResponse_ParamsOverrideDefaults(t *testing.T) {
+	resp := GetTrendingResponse{Results: []tmdb.TrendingResult{{ID: 1}, {ID: 2}, {ID: 3}}}
+	unlimited := 0
+
+	shaped, err := shapeResponse(resp, ResponseParams{MaxResults: &unlimited}, config.ResponseConfig{MaxResults: 1})
+	require.NoError(t, err)
+
+	m := toJSONMap(t, shaped)
+	assert.Len(t, m["results"], 3)
+}
+
+// TestShapeResponse_InvalidLimits tests validation of negative limits
+func TestShapeResponse_InvalidLimits(t *testing.T) {
+	negative := -1
+
+	_, err := shapeResponse(SearchResponse{}, ResponseParams{MaxResults: &negative}, config.ResponseConfig{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid max_results")
+
+	_, err = shapeResponse(SearchResponse{}, ResponseParams{MaxChars: &negative}, config.ResponseConfig{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid max_chars")
+}
+
+// TestParseFields tests parsing of dotted field paths
+func TestParseFields(t *testing.T) {
+	tree := parseFields("id, credits, credits.cast.name,,videos.results.key")
+
+	assert.Equal(t, fieldTree{
+		"id":      nil,
+		"credits": nil,
+		"videos":  fieldTree{"results": fieldTree{"key": nil}},
+	}, tree)
+}
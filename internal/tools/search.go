@@ -3,22 +3,26 @@ package tools
 import (
 	"context"
 
+	"github.com/XDwanj/tmdb-mcp/internal/config"
 	"github.com/XDwanj/tmdb-mcp/internal/tmdb"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
 )
 
 // SearchTool implements the MCP search tool
 type SearchTool struct {
-	tmdbClient *tmdb.Client
-	logger     *zap.Logger
+	tmdbClient  *tmdb.Client
+	responseCfg config.ResponseConfig
+	logger      *zap.Logger
 }
 
 // NewSearchTool creates a new SearchTool instance
-func NewSearchTool(tmdbClient *tmdb.Client, logger *zap.Logger) *SearchTool {
+func NewSearchTool(tmdbClient *tmdb.Client, responseCfg config.ResponseConfig, logger *zap.Logger) *SearchTool {
 	return &SearchTool{
-		tmdbClient: tmdbClient,
-		logger:     logger,
+		tmdbClient:  tmdbClient,
+		responseCfg: responseCfg,
+		logger:      logger,
 	}
 }
 
@@ -27,11 +31,22 @@ func (t *SearchTool) Name() string {
 	return "search"
 }
 
+// Title returns the human-readable tool title
+func (t *SearchTool) Title() string {
+	return "Search Movies, TV Shows and People"
+}
+
 // Description returns the tool description
 func (t *SearchTool) Description() string {
 	return "Search for movies, TV shows, and people on TMDB using a query string"
 }
 
+// OutputSchema returns the tool output schema; fields of results are optional
+// because the fields parameter may leave them out
+func (t *SearchTool) OutputSchema() *jsonschema.Schema {
+	return shapedOutputSchema[SearchResponse]()
+}
+
 // Handler returns a handler function compatible with mcp.AddTool
 // This allows the tool to be registered with the MCP server while keeping
 // business logic encapsulated in the SearchTool struct
@@ -48,7 +63,13 @@ func (t *SearchTool) Handler() func(context.Context, *mcp.CallToolRequest, Searc
 			return nil, SearchResponse{}, convertTMDBError(err, "content")
 		}
 
+		// Apply field projection and response budget
+		shaped, err := shapeTypedResponse(SearchResponse{Results: results.Results}, params.ResponseParams, t.responseCfg)
+		if err != nil {
+			return nil, SearchResponse{}, err
+		}
+
 		// Return empty result metadata and structured response
-		return &mcp.CallToolResult{}, SearchResponse{Results: results.Results}, nil
+		return &mcp.CallToolResult{}, shaped, nil
 	}
 }
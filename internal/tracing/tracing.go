@@ -0,0 +1,139 @@
+// Package tracing sets up OpenTelemetry tracing for the server.
+// Spans follow a request from the MCP method call (see mcp.LoggingMiddleware)
+// through the tool handler into tmdb.Client, where cache lookups, rate-limit
+// waits and every HTTP attempt (including retries) are child spans.
+// When tracing is disabled the global no-op tracer provider is used, so the
+// instrumentation costs next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// instrumentationName identifies the tracer of this module
+const instrumentationName = "github.com/XDwanj/tmdb-mcp"
+
+// Attribute keys used on spans
+const (
+	AttrMCPMethod    = attribute.Key("mcp.method.name")
+	AttrMCPSession   = attribute.Key("mcp.session.id")
+	AttrMCPTool      = attribute.Key("mcp.tool.name")
+	AttrMCPToolError = attribute.Key("mcp.tool.is_error")
+
+	AttrTMDBEndpoint  = attribute.Key("tmdb.endpoint")  // endpoint 模板，如 /movie/{id}
+	AttrTMDBCache     = attribute.Key("tmdb.cache")     // memory_hit, disk_hit, revalidated, miss, disabled
+	AttrTMDBCoalesced = attribute.Key("tmdb.coalesced") // 复用了进行中的相同请求
+	AttrHTTPMethod    = attribute.Key("http.request.method")
+	AttrHTTPStatus    = attribute.Key("http.response.status_code")
+	AttrHTTPAttempt   = attribute.Key("http.request.resend_count")
+
+	AttrRateLimitQueued = attribute.Key("ratelimit.queued")
+)
+
+// Propagator extracts and injects W3C trace context and baggage
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// Tracer returns the tracer used for all spans of this server
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the global tracer provider exporting spans over OTLP
+// It returns a shutdown function that flushes pending spans; when tracing is
+// disabled nothing is installed and the shutdown function is a no-op
+func Setup(ctx context.Context, cfg config.TracingConfig, serviceVersion string) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("service.version", serviceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// 客户端传入的 trace context 决定是否采样，否则按比例采样
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(Propagator)
+
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the OTLP exporter for the configured protocol
+// An empty endpoint falls back to OTEL_EXPORTER_OTLP_* environment variables
+// and then to the exporter default (localhost:4318 for http, localhost:4317 for grpc)
+func newExporter(ctx context.Context, cfg config.TracingConfig) (*otlptrace.Exporter, error) {
+	switch cfg.Protocol {
+	case "grpc":
+		opts := []otlptracegrpc.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+}
+
+// ExtractMeta returns ctx carrying the remote span context found in an MCP
+// request's _meta (traceparent, tracestate and baggage keys, as in HTTP headers)
+// ctx is returned unchanged when meta carries no trace context
+func ExtractMeta(ctx context.Context, meta map[string]any) context.Context {
+	if len(meta) == 0 {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{}
+	for _, key := range Propagator.Fields() {
+		if value, ok := meta[key].(string); ok {
+			carrier[key] = value
+		}
+	}
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return Propagator.Extract(ctx, carrier)
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/XDwanj/tmdb-mcp/internal/config"
+)
+
+// TestExtractMeta tests reading W3C trace context from MCP _meta
+func TestExtractMeta(t *testing.T) {
+	meta := map[string]any{
+		"traceparent":   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"progressToken": "abc",
+	}
+
+	ctx := ExtractMeta(context.Background(), meta)
+
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsValid())
+	assert.True(t, sc.IsRemote())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID().String())
+	assert.True(t, sc.IsSampled())
+}
+
+// TestExtractMeta_NoTraceContext tests that ctx is unchanged without trace context
+func TestExtractMeta_NoTraceContext(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, ctx, ExtractMeta(ctx, nil))
+	assert.Equal(t, ctx, ExtractMeta(ctx, map[string]any{"progressToken": 1}))
+	assert.False(t, trace.SpanContextFromContext(ExtractMeta(ctx, map[string]any{"traceparent": "garbage"})).IsValid())
+}
+
+// TestSetup_Disabled tests that disabled tracing installs nothing
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Enabled: false}, "test")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}